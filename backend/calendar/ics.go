@@ -0,0 +1,156 @@
+// Package calendar renders bookings as iCalendar (RFC 5545) data
+package calendar
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+// ContentType is the MIME type of iCalendar data
+const ContentType = "text/calendar; charset=utf-8"
+
+// prodID identifies this application in generated calendars
+const prodID = "-//Massage Booking//Bookings//EN"
+
+// Salon is the business the events take place at
+type Salon struct {
+	Name    string
+	Address string // optional
+}
+
+// Event is a single VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	Stamp       time.Time
+	Cancelled   bool // tells calendars that already hold the event to drop it
+	Sequence    int  // revision number; calendars apply the highest one
+}
+
+// Calendar is a VCALENDAR holding zero or more events
+type Calendar struct {
+	Name   string // optional display name for subscription feeds
+	Method string // optional iTIP method, e.g. PUBLISH
+	Events []Event
+}
+
+// BookingEvent builds the calendar event for a booking in the booking's locale.
+// The booking reference is the UID, so re-importing an updated event replaces
+// the earlier one; a cancelled booking becomes a cancelled later revision.
+func BookingEvent(booking *models.BookingDetail, salon Salon) Event {
+	location := salon.Name
+	if salon.Address != "" {
+		location = salon.Name + ", " + salon.Address
+	}
+
+	locale := i18n.Parse(booking.Locale)
+	description := locale.T("calendar.description",
+		booking.Reference, booking.ServiceName, booking.Duration, booking.ClientName)
+
+	event := Event{
+		UID:         booking.Reference,
+		Summary:     locale.T("calendar.summary", booking.ServiceName, salon.Name),
+		Description: description,
+		Location:    location,
+		Start:       booking.StartsAt,
+		End:         booking.StartsAt.Add(time.Duration(booking.Duration) * time.Minute),
+		Stamp:       booking.CreatedAt,
+	}
+	if booking.Status == models.BookingStatusCancelled {
+		event.Cancelled = true
+		event.Sequence = 1
+		if booking.CancelledAt != nil {
+			event.Stamp = *booking.CancelledAt
+		}
+	}
+	return event
+}
+
+// Bytes renders the calendar with CRLF line endings and folded long lines
+func (c *Calendar) Bytes() []byte {
+	var buf bytes.Buffer
+	w := &lineWriter{buf: &buf}
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + prodID)
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	for _, e := range c.Events {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + escapeText(e.UID))
+		w.line("DTSTAMP:" + formatTime(e.Stamp))
+		w.line("DTSTART:" + formatTime(e.Start))
+		w.line("DTEND:" + formatTime(e.End))
+		w.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			w.line("LOCATION:" + escapeText(e.Location))
+		}
+		w.line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+		if e.Cancelled {
+			w.line("STATUS:CANCELLED")
+		} else {
+			w.line("STATUS:CONFIRMED")
+		}
+		w.line("END:VEVENT")
+	}
+	w.line("END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// formatTime renders an instant in UTC form, e.g. 20250310T080000Z
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText escapes a TEXT property value
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	s = strings.ReplaceAll(s, "\r", `\n`)
+	return s
+}
+
+// lineWriter writes content lines folded at 75 octets without splitting UTF-8 characters
+type lineWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w *lineWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.buf.WriteString(s[:cut])
+		w.buf.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards the limit
+		limit = 74
+	}
+	w.buf.WriteString(s)
+	w.buf.WriteString("\r\n")
+}
@@ -0,0 +1,90 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+func TestBookingEvent(t *testing.T) {
+	booking := &models.BookingDetail{
+		Reference:   "BK-20250310-001",
+		ClientName:  "Jane Doe",
+		ServiceName: "Swedish Massage",
+		Duration:    90,
+		StartsAt:    time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		CreatedAt:   time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+	}
+
+	cal := Calendar{Method: "PUBLISH", Events: []Event{BookingEvent(booking, Salon{Name: "Serenity", Address: "Main St 1"})}}
+	got := string(cal.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"METHOD:PUBLISH\r\n",
+		"UID:BK-20250310-001\r\n",
+		"DTSTAMP:20250301T093000Z\r\n",
+		"DTSTART:20250310T120000Z\r\n",
+		"DTEND:20250310T133000Z\r\n",
+		"SUMMARY:Swedish Massage at Serenity\r\n",
+		"SEQUENCE:0\r\n",
+		"STATUS:CONFIRMED\r\n",
+		`LOCATION:Serenity\, Main St 1` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCancelledBookingEvent(t *testing.T) {
+	cancelledAt := time.Date(2025, 3, 5, 14, 0, 0, 0, time.UTC)
+	booking := &models.BookingDetail{
+		Reference:   "BK-20250310-001",
+		ServiceName: "Swedish Massage",
+		Duration:    60,
+		Status:      models.BookingStatusCancelled,
+		StartsAt:    time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC),
+		CreatedAt:   time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+		CancelledAt: &cancelledAt,
+	}
+
+	cal := Calendar{Events: []Event{BookingEvent(booking, Salon{Name: "Serenity"})}}
+	got := string(cal.Bytes())
+	for _, want := range []string{"UID:BK-20250310-001\r\n", "DTSTAMP:20250305T140000Z\r\n", "SEQUENCE:1\r\n", "STATUS:CANCELLED\r\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "STATUS:CONFIRMED") {
+		t.Errorf("cancelled event marked confirmed:\n%s", got)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	got := escapeText("a,b;c\\d\ne")
+	if want := `a\,b\;c\\d\ne`; got != want {
+		t.Errorf("escapeText = %q, want %q", got, want)
+	}
+}
+
+func TestLinesAreFolded(t *testing.T) {
+	cal := Calendar{Name: strings.Repeat("ä", 100)}
+	for _, line := range strings.Split(strings.TrimSuffix(string(cal.Bytes()), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+		if !strings.HasPrefix(line, " ") && !strings.Contains(line, ":") {
+			t.Errorf("unexpected unfolded line %q", line)
+		}
+	}
+
+	// Unfolding restores the original value
+	unfolded := strings.ReplaceAll(string(cal.Bytes()), "\r\n ", "")
+	if !strings.Contains(unfolded, "X-WR-CALNAME:"+strings.Repeat("ä", 100)+"\r\n") {
+		t.Errorf("unfolded calendar lost data:\n%s", unfolded)
+	}
+}
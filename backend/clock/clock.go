@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time; it lets tests control time-dependent behaviour
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is a Clock backed by the system time
+type Real struct{}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a Clock that only moves when told to
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a fake clock set to the given time
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the fake clock's current time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the fake clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the fake clock to the given time
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// DefaultTimezone is the business timezone used when none is configured
+const DefaultTimezone = "Europe/Tallinn"
+
+// LoadLocation loads the named IANA timezone, falling back to DefaultTimezone when name is empty
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %v", name, err)
+	}
+	return loc, nil
+}
+
+// LocalTime converts a wall-clock date (YYYY-MM-DD) and time (HH:MM) in loc
+// to an instant. Times skipped by a DST transition are normalised forward.
+func LocalTime(date, hhmm string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", date+" "+hhmm, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date/time %q %q: %v", date, hhmm, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"massage-booking/backend/clock"
+	"massage-booking/backend/models"
+)
+
+// newTestStore opens a seeded in-memory store whose clock starts at now
+func newTestStore(t *testing.T, now time.Time) *Store {
+	t.Helper()
+
+	ctx := context.Background()
+	store, err := Open(ctx, ":memory:", clock.NewFake(now), now.Location())
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Seed(ctx); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+	return store
+}
+
+// book reserves and books the first available slot of the service on date
+func (s *Store) book(t *testing.T, serviceID int, date string) *models.Booking {
+	t.Helper()
+
+	ctx := context.Background()
+	slots, err := s.GetTimeSlots(ctx, date, serviceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slot := range slots {
+		if !slot.Available {
+			continue
+		}
+		reservation, err := s.CreateReservation(ctx, slot.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		booking, err := s.CreateBooking(ctx, models.BookingRequest{
+			ReservationID: reservation.ID,
+			ClientName:    "Jane Doe",
+			Email:         "jane@example.com",
+			Phone:         "+37251234567",
+			ServiceID:     serviceID,
+			Date:          slot.Date,
+			TimeSlot:      slot.Time,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return booking
+	}
+	t.Fatalf("no available slot for service %d on %s", serviceID, date)
+	return nil
+}
+
+func TestBookingKeepsServiceAsBooked(t *testing.T) {
+	now := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	store := newTestStore(t, now)
+	ctx := context.Background()
+
+	booking := store.book(t, 1, "2025-03-10")
+
+	// The catalog changes after the booking was made
+	if _, err := store.db.ExecContext(ctx,
+		"UPDATE massage_types SET name = 'Swedish Massage Deluxe', duration = 75, price_cents = 6200 WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	detail, err := store.GetBookingByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detail.ServiceName != "Swedish Massage" || detail.Duration != 60 || detail.Price != models.NewMoney(5000, "EUR") {
+		t.Errorf("expected the service as booked, got %q, %d min, %v", detail.ServiceName, detail.Duration, detail.Price)
+	}
+}
@@ -1,121 +1,92 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
+	"massage-booking/backend/clock"
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/metrics"
 	"massage-booking/backend/models"
 
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
+// Errors returned by Store methods; handlers map them to HTTP status codes
+var (
+	ErrSlotNotFound        = errors.New("slot not found")
+	ErrSlotUnavailable     = errors.New("slot is not available")
+	ErrSlotReserved        = errors.New("slot is already reserved")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationMismatch = errors.New("reservation is for another service or time")
+	ErrServiceNotFound     = errors.New("service not found")
+	ErrBookingNotFound     = errors.New("booking not found")
+	ErrBookingCancelled    = errors.New("booking is already cancelled")
+	ErrBookingStarted      = errors.New("booking has already started")
+	ErrBookingUnpaid       = errors.New("booking is awaiting payment")
+	ErrEmailNotFound       = errors.New("email not found")
+)
+
+// timestampLayout is the format used for DATETIME columns written by the application
+const timestampLayout = "2006-01-02 15:04:05"
+
+// reservationTTL is how long a temporary reservation holds a slot
+const reservationTTL = 10 * time.Minute
+
+// Store provides access to the booking database.
+// Instants are stored in UTC; slot dates and times are wall-clock values in
+// the business timezone loc.
+type Store struct {
+	db    *sql.DB
+	clock clock.Clock
+	loc   *time.Location
+
+	staffEmail string         // receives new booking and cancellation notices; empty disables them
+	currency   string         // currency of seeded prices
+	taxRate    models.TaxRate // tax rate of seeded services
+	invoicing  InvoiceSettings
+}
 
-// InitDB initializes the SQLite database connection and creates tables
-func InitDB() error {
-	var err error
-	DB, err = sql.Open("sqlite", "./massage_booking.db")
+// Open opens the SQLite database at dsn and applies pending migrations.
+// Use ":memory:" for a throwaway in-memory database.
+func Open(ctx context.Context, dsn string, clk clock.Clock, loc *time.Location) (*Store, error) {
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
-	if err = DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %v", err)
+	// Every connection to ":memory:" gets its own empty database
+	if dsn == ":memory:" {
+		db.SetMaxOpenConns(1)
 	}
 
-	if err = createTables(); err != nil {
-		return fmt.Errorf("failed to create tables: %v", err)
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	if err = seedData(); err != nil {
-		return fmt.Errorf("failed to seed data: %v", err)
+	s := &Store{db: db, clock: clk, loc: loc, currency: models.DefaultCurrency}
+	if err = s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply migrations: %v", err)
 	}
 
 	log.Println("Database initialized successfully")
-	return nil
+	return s, nil
 }
 
-// createTables creates the necessary database tables
-func createTables() error {
-	// Create massage_types table
-	massageTypesTable := `
-	CREATE TABLE IF NOT EXISTS massage_types (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		duration INTEGER NOT NULL,
-		price REAL NOT NULL
-	);`
-
-	if _, err := DB.Exec(massageTypesTable); err != nil {
-		return fmt.Errorf("failed to create massage_types table: %v", err)
-	}
-
-	// Create time_slots table
-	timeSlotsTable := `
-	CREATE TABLE IF NOT EXISTS time_slots (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		date TEXT NOT NULL,
-		time TEXT NOT NULL,
-		service_id INTEGER NOT NULL,
-		available INTEGER NOT NULL DEFAULT 1,
-		FOREIGN KEY (service_id) REFERENCES massage_types (id)
-	);`
-
-	if _, err := DB.Exec(timeSlotsTable); err != nil {
-		return fmt.Errorf("failed to create time_slots table: %v", err)
-	}
-
-	// Create bookings table for Story #2 & #3
-	bookingsTable := `
-	CREATE TABLE IF NOT EXISTS bookings (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		reference TEXT UNIQUE NOT NULL,
-		client_name TEXT NOT NULL,
-		email TEXT NOT NULL,
-		phone TEXT NOT NULL,
-		service_id INTEGER NOT NULL,
-		date TEXT NOT NULL,
-		time_slot TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (service_id) REFERENCES massage_types (id)
-	);`
-
-	if _, err := DB.Exec(bookingsTable); err != nil {
-		return fmt.Errorf("failed to create bookings table: %v", err)
-	}
-
-	// Create temporary_reservations table for Story #2
-	reservationsTable := `
-	CREATE TABLE IF NOT EXISTS temporary_reservations (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		slot_id INTEGER NOT NULL,
-		reserved_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		expires_at DATETIME NOT NULL,
-		FOREIGN KEY (slot_id) REFERENCES time_slots (id)
-	);`
-
-	if _, err := DB.Exec(reservationsTable); err != nil {
-		return fmt.Errorf("failed to create temporary_reservations table: %v", err)
-	}
-
-	// Create index for cleanup queries
-	indexQuery := `CREATE INDEX IF NOT EXISTS idx_expires_at ON temporary_reservations(expires_at);`
-	if _, err := DB.Exec(indexQuery); err != nil {
-		return fmt.Errorf("failed to create index on temporary_reservations: %v", err)
-	}
-
-	return nil
-}
-
-// seedData populates the database with initial sample data
-func seedData() error {
+// Seed populates the database with initial sample data
+func (s *Store) Seed(ctx context.Context) error {
 	// Check if data already exists
 	var count int
-	err := DB.QueryRow("SELECT COUNT(*) FROM massage_types").Scan(&count)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM massage_types").Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check existing data: %v", err)
 	}
@@ -127,22 +98,22 @@ func seedData() error {
 
 	// Insert massage types
 	massageTypes := []models.MassageType{
-		{Name: "Swedish Massage", Duration: 60, Price: 50.0},
-		{Name: "Deep Tissue", Duration: 90, Price: 70.0},
-		{Name: "Hot Stone", Duration: 60, Price: 65.0},
-		{Name: "Sports Massage", Duration: 45, Price: 45.0},
+		{Name: "Swedish Massage", Duration: 60, Price: models.NewMoney(5000, s.currency)},
+		{Name: "Deep Tissue", Duration: 90, Price: models.NewMoney(7000, s.currency)},
+		{Name: "Hot Stone", Duration: 60, Price: models.NewMoney(6500, s.currency)},
+		{Name: "Sports Massage", Duration: 45, Price: models.NewMoney(4500, s.currency)},
 	}
 
 	for _, mt := range massageTypes {
-		_, err := DB.Exec("INSERT INTO massage_types (name, duration, price) VALUES (?, ?, ?)",
-			mt.Name, mt.Duration, mt.Price)
+		_, err := s.db.ExecContext(ctx, "INSERT INTO massage_types (name, duration, price_cents, currency, tax_rate, tax_inclusive) VALUES (?, ?, ?, ?, ?, 1)",
+			mt.Name, mt.Duration, mt.Price.Amount, mt.Price.Currency, s.taxRate)
 		if err != nil {
 			return fmt.Errorf("failed to insert massage type: %v", err)
 		}
 	}
 
 	// Generate time slots for the next 30 days
-	if err := generateTimeSlots(); err != nil {
+	if err := s.generateTimeSlots(ctx); err != nil {
 		return fmt.Errorf("failed to generate time slots: %v", err)
 	}
 
@@ -151,13 +122,13 @@ func seedData() error {
 }
 
 // generateTimeSlots creates time slots for the next 30 days based on service duration
-func generateTimeSlots() error {
+func (s *Store) generateTimeSlots(ctx context.Context) error {
 	// Create a new random source for Go 1.20+
 	source := rand.NewSource(time.Now().UnixNano())
 	rng := rand.New(source)
 
 	// Get all massage types with their durations
-	rows, err := DB.Query("SELECT id, duration FROM massage_types")
+	rows, err := s.db.QueryContext(ctx, "SELECT id, duration FROM massage_types")
 	if err != nil {
 		return fmt.Errorf("failed to get massage types: %v", err)
 	}
@@ -178,8 +149,8 @@ func generateTimeSlots() error {
 		services = append(services, service)
 	}
 
-	// Generate slots for next 30 days
-	startDate := time.Now()
+	// Generate slots for next 30 days, counting days in the business timezone
+	startDate := s.clock.Now().In(s.loc)
 	for day := 0; day < 30; day++ {
 		currentDate := startDate.AddDate(0, 0, day)
 		dateStr := currentDate.Format("2006-01-02")
@@ -202,12 +173,13 @@ func generateTimeSlots() error {
 				hour := startMinutes / 60
 				minute := startMinutes % 60
 				timeStr := fmt.Sprintf("%02d:%02d", hour, minute)
+				startsAt := time.Date(currentDate.Year(), currentDate.Month(), currentDate.Day(), hour, minute, 0, 0, s.loc)
 
 				// Randomly make some slots unavailable (about 30% booked)
 				available := rng.Float32() > 0.3
 
-				_, err := DB.Exec("INSERT INTO time_slots (date, time, service_id, available) VALUES (?, ?, ?, ?)",
-					dateStr, timeStr, service.ID, available)
+				_, err := s.db.ExecContext(ctx, "INSERT INTO time_slots (date, time, starts_at, service_id, available) VALUES (?, ?, ?, ?, ?)",
+					dateStr, timeStr, formatTimestamp(startsAt), service.ID, available)
 				if err != nil {
 					return fmt.Errorf("failed to insert time slot: %v", err)
 				}
@@ -218,9 +190,46 @@ func generateTimeSlots() error {
 	return nil
 }
 
+// formatTimestamp formats an instant as UTC for storage in a DATETIME column
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// now returns the current time formatted for comparison with DATETIME columns
+func (s *Store) now() string {
+	return formatTimestamp(s.clock.Now())
+}
+
+// today returns the current business-timezone date as YYYY-MM-DD
+func (s *Store) today() string {
+	return s.clock.Now().In(s.loc).Format("2006-01-02")
+}
+
+// SetStaffEmail sets the address notified of every new booking and
+// cancellation. An empty address disables staff notifications.
+func (s *Store) SetStaffEmail(address string) {
+	s.staffEmail = address
+}
+
+// SetCurrency sets the currency of the prices created by Seed
+func (s *Store) SetCurrency(currency string) {
+	s.currency = currency
+}
+
+// SetTaxRate sets the tax rate, included in the price, of the services
+// created when seeding an empty database
+func (s *Store) SetTaxRate(rate models.TaxRate) {
+	s.taxRate = rate
+}
+
+// Location returns the business timezone used for slot dates and times
+func (s *Store) Location() *time.Location {
+	return s.loc
+}
+
 // GetMassageTypes retrieves all massage types from the database
-func GetMassageTypes() ([]models.MassageType, error) {
-	rows, err := DB.Query("SELECT id, name, duration, price FROM massage_types ORDER BY id")
+func (s *Store) GetMassageTypes(ctx context.Context) ([]models.MassageType, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, duration, price_cents, currency, tax_rate, tax_inclusive FROM massage_types ORDER BY id")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query massage types: %v", err)
 	}
@@ -229,7 +238,7 @@ func GetMassageTypes() ([]models.MassageType, error) {
 	var massageTypes []models.MassageType
 	for rows.Next() {
 		var mt models.MassageType
-		if err := rows.Scan(&mt.ID, &mt.Name, &mt.Duration, &mt.Price); err != nil {
+		if err := rows.Scan(&mt.ID, &mt.Name, &mt.Duration, &mt.Price.Amount, &mt.Price.Currency, &mt.TaxRate, &mt.TaxInclusive); err != nil {
 			return nil, fmt.Errorf("failed to scan massage type: %v", err)
 		}
 		massageTypes = append(massageTypes, mt)
@@ -238,16 +247,54 @@ func GetMassageTypes() ([]models.MassageType, error) {
 	return massageTypes, nil
 }
 
+// SetServiceTax sets the tax rate of a service and whether its price
+// includes the tax. Bookings already made keep the tax they were booked with.
+func (s *Store) SetServiceTax(ctx context.Context, serviceID int, rate models.TaxRate, inclusive bool) (*models.MassageType, error) {
+	result, err := s.db.ExecContext(ctx, "UPDATE massage_types SET tax_rate = ?, tax_inclusive = ? WHERE id = ?", rate, inclusive, serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tax of service %d: %v", serviceID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check affected rows: %v", err)
+	}
+	if n == 0 {
+		return nil, ErrServiceNotFound
+	}
+
+	var mt models.MassageType
+	err = s.db.QueryRowContext(ctx, "SELECT id, name, duration, price_cents, currency, tax_rate, tax_inclusive FROM massage_types WHERE id = ?", serviceID).
+		Scan(&mt.ID, &mt.Name, &mt.Duration, &mt.Price.Amount, &mt.Price.Currency, &mt.TaxRate, &mt.TaxInclusive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service %d: %v", serviceID, err)
+	}
+	return &mt, nil
+}
+
 // GetTimeSlots retrieves time slots for a specific date and service, excluding reserved slots
-func GetTimeSlots(date string, serviceID int) ([]models.TimeSlot, error) {
+func (s *Store) GetTimeSlots(ctx context.Context, date string, serviceID int) ([]models.TimeSlot, error) {
 	query := `
-		SELECT ts.id, ts.date, ts.time, ts.service_id, ts.available
+		SELECT ts.id, ts.date, ts.time, ts.starts_at, ts.service_id, ts.available
 		FROM time_slots ts
-		LEFT JOIN temporary_reservations tr ON ts.id = tr.slot_id AND tr.expires_at > datetime('now')
+		LEFT JOIN temporary_reservations tr ON ts.id = tr.slot_id AND tr.expires_at > ?
 		WHERE ts.date = ? AND ts.service_id = ? AND tr.id IS NULL
 		ORDER BY ts.time
 	`
-	rows, err := DB.Query(query, date, serviceID)
+	// Prices are worked out before the slots are read, as an in-memory
+	// database has only one connection and cannot query while rows are open
+	base, err := servicePrice(ctx, s.db, serviceID)
+	if errors.Is(err, ErrServiceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.ListPricingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, s.now(), date, serviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query time slots: %v", err)
 	}
@@ -256,9 +303,10 @@ func GetTimeSlots(date string, serviceID int) ([]models.TimeSlot, error) {
 	var timeSlots []models.TimeSlot
 	for rows.Next() {
 		var ts models.TimeSlot
-		if err := rows.Scan(&ts.ID, &ts.Date, &ts.Time, &ts.ServiceID, &ts.Available); err != nil {
+		if err := rows.Scan(&ts.ID, &ts.Date, &ts.Time, &ts.StartsAt, &ts.ServiceID, &ts.Available); err != nil {
 			return nil, fmt.Errorf("failed to scan time slot: %v", err)
 		}
+		ts.Price, ts.Adjustment = s.slotPrice(base, rules, ts.ServiceID, ts.StartsAt)
 		timeSlots = append(timeSlots, ts)
 	}
 
@@ -266,15 +314,16 @@ func GetTimeSlots(date string, serviceID int) ([]models.TimeSlot, error) {
 }
 
 // CleanupExpiredReservations removes expired reservations
-func CleanupExpiredReservations() error {
-	query := "DELETE FROM temporary_reservations WHERE expires_at < datetime('now')"
-	result, err := DB.Exec(query)
+func (s *Store) CleanupExpiredReservations(ctx context.Context) error {
+	query := "DELETE FROM temporary_reservations WHERE expires_at < ?"
+	result, err := s.db.ExecContext(ctx, query, s.now())
 	if err != nil {
 		return fmt.Errorf("failed to cleanup expired reservations: %v", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err == nil && rowsAffected > 0 {
+		metrics.ReservationsExpired.Add(float64(rowsAffected))
 		log.Printf("Cleaned up %d expired reservations", rowsAffected)
 	}
 
@@ -282,65 +331,100 @@ func CleanupExpiredReservations() error {
 }
 
 // StartCleanupJob runs cleanup every minute to remove expired reservations
-func StartCleanupJob() {
+// and release bookings whose payment hold has run out.
+// The job stops when ctx is cancelled and marks wg done once it has exited.
+func (s *Store) StartCleanupJob(ctx context.Context, wg *sync.WaitGroup) {
 	ticker := time.NewTicker(1 * time.Minute)
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		defer ticker.Stop()
-		for range ticker.C {
-			if err := CleanupExpiredReservations(); err != nil {
-				log.Printf("Error during cleanup: %v", err)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("Stopped cleanup job for expired reservations")
+				return
+			case <-ticker.C:
+				if err := s.CleanupExpiredReservations(ctx); err != nil {
+					log.Printf("Error during cleanup: %v", err)
+				}
+				if _, err := s.ReleaseExpiredHolds(ctx); err != nil {
+					log.Printf("Error releasing expired payment holds: %v", err)
+				}
 			}
 		}
 	}()
 	log.Println("Started cleanup job for expired reservations")
 }
 
-// CreateReservation creates a temporary reservation for a slot
-func CreateReservation(slotID int) (int, time.Time, error) {
+// CreateReservation creates a temporary reservation for a slot, locking in
+// the slot's price with the pricing rules applied
+func (s *Store) CreateReservation(ctx context.Context, slotID int) (*models.Reservation, error) {
 	// Check if slot exists and is available
 	var available bool
-	err := DB.QueryRow("SELECT available FROM time_slots WHERE id = ?", slotID).Scan(&available)
+	var serviceID int
+	var startsAt time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT available, service_id, starts_at FROM time_slots WHERE id = ?", slotID).
+		Scan(&available, &serviceID, &startsAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, time.Time{}, fmt.Errorf("slot not found")
+			return nil, ErrSlotNotFound
 		}
-		return 0, time.Time{}, fmt.Errorf("failed to check slot availability: %v", err)
+		return nil, fmt.Errorf("failed to check slot availability: %v", err)
 	}
 
 	if !available {
-		return 0, time.Time{}, fmt.Errorf("slot is not available")
+		return nil, ErrSlotUnavailable
 	}
 
 	// Check if slot is already reserved
-	var count int
-	err = DB.QueryRow("SELECT COUNT(*) FROM temporary_reservations WHERE slot_id = ? AND expires_at > datetime('now')", slotID).Scan(&count)
+	reserved, err := s.IsSlotReserved(ctx, slotID)
 	if err != nil {
-		return 0, time.Time{}, fmt.Errorf("failed to check existing reservations: %v", err)
+		return nil, err
 	}
 
-	if count > 0 {
-		return 0, time.Time{}, fmt.Errorf("slot is already reserved")
+	if reserved {
+		return nil, ErrSlotReserved
 	}
 
+	base, err := servicePrice(ctx, s.db, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.ListPricingRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	price, _ := s.slotPrice(base, rules, serviceID, startsAt)
+
 	// Create reservation with 10-minute expiration
-	expiresAt := time.Now().Add(10 * time.Minute)
-	result, err := DB.Exec("INSERT INTO temporary_reservations (slot_id, expires_at) VALUES (?, ?)",
-		slotID, expiresAt.Format("2006-01-02 15:04:05"))
+	now := s.clock.Now().UTC()
+	expiresAt := now.Add(reservationTTL)
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO temporary_reservations (slot_id, reserved_at, expires_at, price_cents, currency)
+		VALUES (?, ?, ?, ?, ?)
+	`, slotID, formatTimestamp(now), formatTimestamp(expiresAt), price.Amount, price.Currency)
 	if err != nil {
-		return 0, time.Time{}, fmt.Errorf("failed to create reservation: %v", err)
+		return nil, fmt.Errorf("failed to create reservation: %v", err)
 	}
 
 	reservationID, err := result.LastInsertId()
 	if err != nil {
-		return 0, time.Time{}, fmt.Errorf("failed to get reservation ID: %v", err)
+		return nil, fmt.Errorf("failed to get reservation ID: %v", err)
 	}
 
-	return int(reservationID), expiresAt, nil
+	return &models.Reservation{
+		ID:         int(reservationID),
+		SlotID:     slotID,
+		ReservedAt: now,
+		ExpiresAt:  expiresAt,
+		Price:      price,
+	}, nil
 }
 
 // DeleteReservation removes a temporary reservation
-func DeleteReservation(reservationID int) error {
-	result, err := DB.Exec("DELETE FROM temporary_reservations WHERE id = ?", reservationID)
+func (s *Store) DeleteReservation(ctx context.Context, reservationID int) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM temporary_reservations WHERE id = ?", reservationID)
 	if err != nil {
 		return fmt.Errorf("failed to delete reservation: %v", err)
 	}
@@ -351,16 +435,16 @@ func DeleteReservation(reservationID int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("reservation not found")
+		return ErrReservationNotFound
 	}
 
 	return nil
 }
 
 // IsSlotReserved checks if a slot is temporarily reserved
-func IsSlotReserved(slotID int) (bool, error) {
+func (s *Store) IsSlotReserved(ctx context.Context, slotID int) (bool, error) {
 	var count int
-	err := DB.QueryRow("SELECT COUNT(*) FROM temporary_reservations WHERE slot_id = ? AND expires_at > datetime('now')", slotID).Scan(&count)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM temporary_reservations WHERE slot_id = ? AND expires_at > ?", slotID, s.now()).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("failed to check slot reservation: %v", err)
 	}
@@ -368,12 +452,17 @@ func IsSlotReserved(slotID int) (bool, error) {
 	return count > 0, nil
 }
 
-// GenerateBookingReference generates a unique booking reference
-func GenerateBookingReference(date string) (string, error) {
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// generateBookingReference generates a unique booking reference
+func generateBookingReference(ctx context.Context, q queryRower, date string) (string, error) {
 	// Get count of bookings for this date
 	var count int
 	dateOnly := strings.Split(date, " ")[0] // Extract date part if datetime
-	err := DB.QueryRow("SELECT COUNT(*) FROM bookings WHERE date = ?", dateOnly).Scan(&count)
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM bookings WHERE date = ?", dateOnly).Scan(&count)
 	if err != nil {
 		return "", fmt.Errorf("failed to get booking count: %v", err)
 	}
@@ -385,27 +474,72 @@ func GenerateBookingReference(date string) (string, error) {
 	return reference, nil
 }
 
-// GetBookingByID retrieves a booking by ID with service details
-func GetBookingByID(bookingID int) (*models.BookingDetail, error) {
-	query := `
-		SELECT b.id, b.reference, b.client_name, b.email, b.phone,
-		       b.service_id, b.date, b.time_slot, b.created_at,
-		       mt.name as service_name, mt.duration, mt.price
-		FROM bookings b
-		JOIN massage_types mt ON b.service_id = mt.id
-		WHERE b.id = ?
-	`
+// bookingDetailQuery selects bookings for scanBookingDetail. The service
+// name, duration and price are those at booking time, not the current catalog.
+const bookingDetailQuery = `
+	SELECT b.id, b.reference, b.client_name, b.email, b.phone,
+	       b.service_id, b.date, b.time_slot, b.starts_at, b.status, b.sms_opt_in, b.locale, b.created_at, b.cancelled_at, b.hold_expires_at,
+	       b.service_name, b.duration, b.price_cents, b.currency, b.promo_code, b.discount_cents, b.voucher_code, b.voucher_cents, COALESCE(b.package_id, 0),
+	       b.tax_rate, b.tax_inclusive, b.net_cents, b.tax_cents, b.gross_cents,
+	       (SELECT COALESCE(SUM(r.amount_cents), 0) FROM refunds r WHERE r.booking_id = b.id AND r.status = 'succeeded')
+	FROM bookings b
+`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+// scanBookingDetail reads a row selected with bookingDetailQuery
+func scanBookingDetail(row rowScanner) (models.BookingDetail, error) {
 	var booking models.BookingDetail
-	err := DB.QueryRow(query, bookingID).Scan(
+	var cancelledAt, holdExpiresAt sql.NullTime
+	err := row.Scan(
 		&booking.ID, &booking.Reference, &booking.ClientName, &booking.Email, &booking.Phone,
-		&booking.ServiceID, &booking.Date, &booking.TimeSlot, &booking.CreatedAt,
-		&booking.ServiceName, &booking.Duration, &booking.Price,
+		&booking.ServiceID, &booking.Date, &booking.TimeSlot, &booking.StartsAt, &booking.Status, &booking.SMSOptIn, &booking.Locale, &booking.CreatedAt, &cancelledAt, &holdExpiresAt,
+		&booking.ServiceName, &booking.Duration, &booking.Price.Amount, &booking.Price.Currency, &booking.PromoCode, &booking.Discount.Amount,
+		&booking.VoucherCode, &booking.VoucherAmount.Amount, &booking.PackageID,
+		&booking.TaxRate, &booking.TaxInclusive, &booking.Net.Amount, &booking.Tax.Amount, &booking.Gross.Amount,
+		&booking.Refunded.Amount,
 	)
+	currency := booking.Price.Currency
+	booking.Discount.Currency, booking.VoucherAmount.Currency = currency, currency
+	booking.Net.Currency, booking.Tax.Currency, booking.Gross.Currency, booking.Refunded.Currency = currency, currency, currency, currency
+	booking.Total = models.NewMoney(booking.Gross.Amount-booking.VoucherAmount.Amount, currency)
+	if cancelledAt.Valid {
+		booking.CancelledAt = &cancelledAt.Time
+	}
+	if holdExpiresAt.Valid {
+		booking.HoldExpiresAt = &holdExpiresAt.Time
+	}
+	return booking, err
+}
 
+// queryBookingDetails runs bookingDetailQuery with the given conditions
+func (s *Store) queryBookingDetails(ctx context.Context, where string, args ...any) ([]models.BookingDetail, error) {
+	rows, err := s.db.QueryContext(ctx, bookingDetailQuery+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bookings: %v", err)
+	}
+	defer rows.Close()
+
+	var bookings []models.BookingDetail
+	for rows.Next() {
+		booking, err := scanBookingDetail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, rows.Err()
+}
+
+// GetBookingByID retrieves a booking by ID with service details
+func (s *Store) GetBookingByID(ctx context.Context, bookingID int) (*models.BookingDetail, error) {
+	booking, err := scanBookingDetail(s.db.QueryRowContext(ctx, bookingDetailQuery+"WHERE b.id = ?", bookingID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("booking not found")
+			return nil, ErrBookingNotFound
 		}
 		return nil, fmt.Errorf("failed to get booking: %v", err)
 	}
@@ -413,20 +547,176 @@ func GetBookingByID(bookingID int) (*models.BookingDetail, error) {
 	return &booking, nil
 }
 
-// CreateBookingWithReference creates a booking with generated reference
-func CreateBookingWithReference(clientName, email, phone string, serviceID int, date, timeSlot string) (*models.BookingDetail, error) {
-	// Generate reference
-	reference, err := GenerateBookingReference(date)
+// ListUpcomingBookings returns confirmed and cancelled bookings starting at
+// or after from, earliest first. Cancellations are included so calendar
+// feeds can tell subscribers to drop them.
+func (s *Store) ListUpcomingBookings(ctx context.Context, from time.Time) ([]models.BookingDetail, error) {
+	return s.queryBookingDetails(ctx, "WHERE b.status IN (?, ?) AND b.starts_at >= ? ORDER BY b.starts_at, b.id",
+		models.BookingStatusConfirmed, models.BookingStatusCancelled, formatTimestamp(from))
+}
+
+// ListAllBookingsOn returns the bookings on a business-timezone date in any status, earliest first
+func (s *Store) ListAllBookingsOn(ctx context.Context, date string) ([]models.BookingDetail, error) {
+	return s.queryBookingDetails(ctx, "WHERE b.date = ? ORDER BY b.starts_at, b.id", date)
+}
+
+// ListBookingsBetween returns the bookings from one business-timezone date
+// to another, both included, in any status, earliest first
+func (s *Store) ListBookingsBetween(ctx context.Context, from, to string) ([]models.BookingDetail, error) {
+	return s.queryBookingDetails(ctx, "WHERE b.date BETWEEN ? AND ? ORDER BY b.starts_at, b.id", from, to)
+}
+
+// ListBookingsOn returns confirmed bookings on a business-timezone date (YYYY-MM-DD), earliest first
+func (s *Store) ListBookingsOn(ctx context.Context, date string) ([]models.BookingDetail, error) {
+	return s.queryBookingDetails(ctx, "WHERE b.status = ? AND b.date = ? ORDER BY b.starts_at, b.id",
+		models.BookingStatusConfirmed, date)
+}
+
+// CreateBooking converts an unexpired reservation into a confirmed booking.
+// The booking insert, marking the slot unavailable and releasing the
+// reservation happen in a single transaction.
+func (s *Store) CreateBooking(ctx context.Context, req models.BookingRequest) (*models.Booking, error) {
+	return s.createBooking(ctx, req, time.Time{})
+}
+
+// CreatePendingBooking converts an unexpired reservation into a booking that
+// holds its slot until holdUntil while the client pays. Notifications are
+// queued once ConfirmPayment confirms it; ReleaseExpiredHolds frees the slot
+// if that does not happen in time.
+func (s *Store) CreatePendingBooking(ctx context.Context, req models.BookingRequest, holdUntil time.Time) (*models.Booking, error) {
+	return s.createBooking(ctx, req, holdUntil)
+}
+
+// createBooking creates a confirmed booking, or one pending payment when holdUntil is set
+func (s *Store) createBooking(ctx context.Context, req models.BookingRequest, holdUntil time.Time) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate reference: %v", err)
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Check if reservation exists and is not expired
+	var slot models.TimeSlot
+	var startsAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT tr.slot_id, ts.service_id, ts.date, ts.time, ts.starts_at
+		FROM temporary_reservations tr
+		JOIN time_slots ts ON ts.id = tr.slot_id
+		WHERE tr.id = ? AND tr.expires_at > ?
+	`, req.ReservationID, s.now()).Scan(&slot.ID, &slot.ServiceID, &slot.Date, &slot.Time, &startsAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("failed to check reservation %d: %v", req.ReservationID, err)
+	}
+	// The booking is made for the reserved slot, so the request must describe it
+	if slot.ServiceID != req.ServiceID || slot.Date != req.Date || slot.Time != req.TimeSlot {
+		return nil, ErrReservationMismatch
+	}
+	slotID := slot.ID
+
+	// The booking keeps the service as it is now, whatever the catalog says later
+	var service models.MassageType
+	err = tx.QueryRowContext(ctx, "SELECT name, duration, price_cents, currency, tax_rate, tax_inclusive FROM massage_types WHERE id = ?", req.ServiceID).
+		Scan(&service.Name, &service.Duration, &service.Price.Amount, &service.Price.Currency, &service.TaxRate, &service.TaxInclusive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrServiceNotFound
+		}
+		return nil, fmt.Errorf("failed to get service %d: %v", req.ServiceID, err)
+	}
+	locked, ok, err := s.lockedPrice(ctx, tx, req.ReservationID, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		service.Price = locked
+	}
+
+	// A session package for the service pays for the whole booking, so
+	// promo codes and vouchers are left unused
+	pkg, err := s.findPackage(ctx, tx, req.PackageCode, req.Email, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	var packageID any
+	if pkg != nil {
+		if err = usePackageSession(ctx, tx, pkg.ID); err != nil {
+			return nil, err
+		}
+		packageID = pkg.ID
+	}
+
+	// Promo code limits are checked in the transaction that uses the code up
+	var promoID any
+	var promoCode string
+	var discount int64
+	if pkg == nil && NormalizePromoCode(req.PromoCode) != "" {
+		promo, err := s.findPromo(ctx, tx, req.PromoCode, req.ServiceID, req.Email, service.Price.Currency)
+		if err != nil {
+			return nil, err
+		}
+		promoID, promoCode, discount = promo.ID, promo.Code, promo.Discount(service.Price).Amount
 	}
 
-	// Insert booking
-	result, err := DB.Exec(`
-		INSERT INTO bookings (reference, client_name, email, phone, service_id, date, time_slot)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, reference, clientName, email, phone, serviceID, date, timeSlot)
+	// Tax is charged on what is left after the discount; a package leaves nothing
+	charged := models.NewMoney(service.Price.Amount-discount, service.Price.Currency)
+	if pkg != nil {
+		charged.Amount = 0
+	}
+	tax := models.ApplyTax(charged, service.TaxRate, service.TaxInclusive)
 
+	// A gift voucher pays what is left after the discount, tax included
+	var voucherID any
+	var voucherCode string
+	var voucherAmount int64
+	if pkg == nil && NormalizeVoucherCode(req.VoucherCode) != "" {
+		voucher, err := s.findVoucher(ctx, tx, req.VoucherCode, req.ServiceID, service.Price.Currency)
+		if err != nil {
+			return nil, err
+		}
+		voucherAmount = voucher.Covers(tax.Gross).Amount
+		if voucherAmount > 0 {
+			if err = redeemVoucher(ctx, tx, voucher, voucherAmount); err != nil {
+				return nil, err
+			}
+			voucherID, voucherCode = voucher.ID, voucher.Code
+		}
+	}
+
+	// Generate booking reference
+	reference, err := generateBookingReference(ctx, tx, req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate reference: %v", err)
+	}
+
+	// Unsupported or missing locales fall back to the default language
+	locale := string(i18n.Parse(req.Locale))
+
+	status := models.BookingStatusConfirmed
+	var holdExpiresAt *time.Time
+	var holdColumn any
+	if !holdUntil.IsZero() {
+		status = models.BookingStatusPendingPayment
+		holdUntil = holdUntil.UTC()
+		holdExpiresAt = &holdUntil
+		holdColumn = formatTimestamp(holdUntil)
+	}
+
+	// Create booking with reference
+	createdAt := s.clock.Now().UTC()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO bookings (reference, client_name, email, phone, service_id, date, time_slot, starts_at, status, sms_opt_in, locale, created_at, hold_expires_at,
+		                      service_name, duration, price_cents, currency, promo_code_id, promo_code, discount_cents,
+		                      voucher_id, voucher_code, voucher_cents, package_id,
+		                      tax_rate, tax_inclusive, net_cents, tax_cents, gross_cents)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, reference, req.ClientName, req.Email, req.Phone, req.ServiceID, req.Date, req.TimeSlot,
+		formatTimestamp(startsAt), status, req.SMSOptIn, locale, formatTimestamp(createdAt), holdColumn,
+		service.Name, service.Duration, service.Price.Amount, service.Price.Currency, promoID, promoCode, discount,
+		voucherID, voucherCode, voucherAmount, packageID,
+		tax.TaxRate, tax.TaxInclusive, tax.Net.Amount, tax.Tax.Amount, tax.Gross.Amount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create booking: %v", err)
 	}
@@ -436,14 +726,158 @@ func CreateBookingWithReference(clientName, email, phone string, serviceID int,
 		return nil, fmt.Errorf("failed to get booking ID: %v", err)
 	}
 
-	// Get the created booking with details
-	return GetBookingByID(int(bookingID))
+	// Mark slot as unavailable
+	if _, err = tx.ExecContext(ctx, "UPDATE time_slots SET available = 0 WHERE id = ?", slotID); err != nil {
+		return nil, fmt.Errorf("failed to mark slot %d as unavailable: %v", slotID, err)
+	}
+
+	// Delete reservation
+	if _, err = tx.ExecContext(ctx, "DELETE FROM temporary_reservations WHERE id = ?", req.ReservationID); err != nil {
+		return nil, fmt.Errorf("failed to delete reservation %d: %v", req.ReservationID, err)
+	}
+
+	// Queue the confirmation so it is sent even if the process stops now
+	if status == models.BookingStatusConfirmed {
+		if err = s.enqueueConfirmation(ctx, tx, int(bookingID), req.Email, req.Phone, req.SMSOptIn, createdAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &models.Booking{
+		ID:         int(bookingID),
+		Reference:  reference,
+		ClientName: req.ClientName,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		ServiceID:  req.ServiceID,
+		Date:       req.Date,
+		TimeSlot:   req.TimeSlot,
+		StartsAt:   startsAt.UTC(),
+		Status:     status,
+		SMSOptIn:   req.SMSOptIn,
+		Locale:     locale,
+		CreatedAt:  createdAt,
+
+		HoldExpiresAt: holdExpiresAt,
+	}, nil
 }
 
-// CloseDB closes the database connection
-func CloseDB() error {
-	if DB != nil {
-		return DB.Close()
+// enqueueConfirmation queues the confirmation email, SMS if the client opted
+// in, and the staff notice for a newly confirmed booking
+func (s *Store) enqueueConfirmation(ctx context.Context, ex execer, bookingID int, email, phone string, smsOptIn bool, now time.Time) error {
+	if err := enqueueEmail(ctx, ex, models.EmailKindBookingConfirmation, bookingID, email, now); err != nil {
+		return err
+	}
+	if smsOptIn {
+		if err := enqueueSMS(ctx, ex, models.EmailKindBookingConfirmation, bookingID, phone, now); err != nil {
+			return err
+		}
+	}
+	if s.staffEmail != "" {
+		if err := enqueueEmail(ctx, ex, models.EmailKindStaffNewBooking, bookingID, s.staffEmail, now); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// releaseSlot makes the slot of a booking available again. Bookings do not
+// reference their slot, so it is found by service, date and time.
+func releaseSlot(ctx context.Context, ex execer, bookingID int) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE time_slots SET available = 1
+		WHERE id IN (
+			SELECT ts.id FROM time_slots ts
+			JOIN bookings b ON ts.service_id = b.service_id AND ts.date = b.date AND ts.time = b.time_slot
+			WHERE b.id = ?
+		)
+	`, bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to release slot for booking %d: %v", bookingID, err)
+	}
+	return nil
+}
+
+// CancelBooking cancels a confirmed booking that has not started yet and
+// makes its slot available again. The client and, if configured, the staff
+// are notified through the outbox in the same transaction; reminders still
+// queued for the booking are skipped by the outbox worker.
+func (s *Store) CancelBooking(ctx context.Context, bookingID int) (*models.BookingDetail, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	booking, err := scanBookingDetail(tx.QueryRowContext(ctx, bookingDetailQuery+"WHERE b.id = ?", bookingID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get booking %d: %v", bookingID, err)
+	}
+	switch booking.Status {
+	case models.BookingStatusCancelled:
+		return nil, ErrBookingCancelled
+	case models.BookingStatusPendingPayment:
+		return nil, ErrBookingUnpaid
+	}
+
+	now := s.clock.Now().UTC()
+	if !booking.StartsAt.After(now) {
+		return nil, ErrBookingStarted
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE bookings SET status = ?, cancelled_at = ? WHERE id = ?",
+		models.BookingStatusCancelled, formatTimestamp(now), bookingID); err != nil {
+		return nil, fmt.Errorf("failed to cancel booking %d: %v", bookingID, err)
+	}
+
+	if err = releaseSlot(ctx, tx, bookingID); err != nil {
+		return nil, err
+	}
+	if err = restoreVoucher(ctx, tx, bookingID); err != nil {
+		return nil, err
+	}
+	if err = restorePackageSession(ctx, tx, bookingID); err != nil {
+		return nil, err
+	}
+	// A payment the provider cannot be asked to return is left to staff
+	// rather than blocking the cancellation
+	if err = s.recordCancellationRefunds(ctx, tx, bookingID); errors.Is(err, ErrPaymentRefMissing) {
+		log.Printf("Cancelled booking %s needs a manual refund: %v", booking.Reference, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = enqueueEmail(ctx, tx, models.EmailKindBookingCancellation, bookingID, booking.Email, now); err != nil {
+		return nil, err
+	}
+	if s.staffEmail != "" {
+		if err = enqueueEmail(ctx, tx, models.EmailKindStaffCancellation, bookingID, s.staffEmail, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	booking.Status = models.BookingStatusCancelled
+	booking.CancelledAt = &now
+	return &booking, nil
+}
+
+// Ping verifies that the database connection is alive
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
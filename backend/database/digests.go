@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// DigestSent reports whether the staff digest for a date (YYYY-MM-DD) has been sent
+func (s *Store) DigestSent(ctx context.Context, date string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM staff_digests WHERE date = ?", date).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check digest for %s: %v", date, err)
+	}
+	return count > 0, nil
+}
+
+// RecordDigestSent records that the staff digest for a date has been sent
+func (s *Store) RecordDigestSent(ctx context.Context, date string) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR IGNORE INTO staff_digests (date, sent_at) VALUES (?, ?)", date, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to record digest for %s: %v", date, err)
+	}
+	return nil
+}
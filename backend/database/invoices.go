@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"massage-booking/backend/models"
+)
+
+// ErrInvoiceNotAvailable is returned when an invoice is requested for a
+// booking that is awaiting payment, or was cancelled before one was issued
+var ErrInvoiceNotAvailable = errors.New("invoice is not available for this booking")
+
+// DefaultInvoicePrefix starts invoice numbers when no prefix is configured
+const DefaultInvoicePrefix = "INV-"
+
+// InvoiceSettings are the details put on newly issued invoices
+type InvoiceSettings struct {
+	Seller models.Seller
+	Prefix string // put before the sequence number, e.g. "INV-" for INV-000042; empty for DefaultInvoicePrefix
+}
+
+// SetInvoiceSettings sets the seller and number prefix of invoices issued
+// from now on; invoices already issued keep theirs
+func (s *Store) SetInvoiceSettings(settings InvoiceSettings) {
+	s.invoicing = settings
+}
+
+// GetBookingByReference retrieves a booking by its reference
+func (s *Store) GetBookingByReference(ctx context.Context, reference string) (*models.BookingDetail, error) {
+	booking, err := scanBookingDetail(s.db.QueryRowContext(ctx, bookingDetailQuery+"WHERE b.reference = ?", reference))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get booking: %v", err)
+	}
+
+	return &booking, nil
+}
+
+// IssueInvoice returns the invoice of a booking, giving it the next number
+// the first time it is asked for. Numbers run on without gaps because they
+// are only taken by bookings that are confirmed.
+func (s *Store) IssueInvoice(ctx context.Context, bookingID int) (*models.Invoice, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	inv, err := scanInvoice(tx.QueryRowContext(ctx, invoiceQuery+"WHERE booking_id = ?", bookingID))
+	if err == sql.ErrNoRows {
+		inv, err = s.createInvoice(ctx, tx, bookingID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	booking, err := s.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.ListPayments(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	var paidOnline int64
+	for _, p := range payments {
+		if p.Status == models.PaymentStatusPaid {
+			paidOnline += p.Amount
+		}
+	}
+	inv.Itemize(booking, paidOnline)
+	return inv, nil
+}
+
+// createInvoice numbers and stores a new invoice for a confirmed booking
+func (s *Store) createInvoice(ctx context.Context, tx *sql.Tx, bookingID int) (*models.Invoice, error) {
+	var status string
+	var vatRate models.TaxRate
+	err := tx.QueryRowContext(ctx, "SELECT status, tax_rate FROM bookings WHERE id = ?", bookingID).Scan(&status, &vatRate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to get booking %d: %v", bookingID, err)
+	}
+	if status != models.BookingStatusConfirmed {
+		return nil, ErrInvoiceNotAvailable
+	}
+
+	var sequence int
+	if err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(sequence), 0) + 1 FROM invoices").Scan(&sequence); err != nil {
+		return nil, fmt.Errorf("failed to get next invoice number: %v", err)
+	}
+
+	settings := s.invoicing
+	if settings.Prefix == "" {
+		settings.Prefix = DefaultInvoicePrefix
+	}
+	inv := &models.Invoice{
+		Number:    fmt.Sprintf("%s%06d", settings.Prefix, sequence),
+		BookingID: bookingID,
+		IssuedOn:  s.today(),
+		Seller:    settings.Seller,
+		VATRate:   vatRate,
+		CreatedAt: s.clock.Now().UTC(),
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO invoices (number, sequence, booking_id, issued_on, vat_rate, seller_name, seller_address,
+		                      seller_registry_code, seller_vat_number, seller_email, seller_bank_account, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, inv.Number, sequence, bookingID, inv.IssuedOn, inv.VATRate, inv.Seller.Name, inv.Seller.Address,
+		inv.Seller.RegistryCode, inv.Seller.VATNumber, inv.Seller.Email, inv.Seller.BankAccount, formatTimestamp(inv.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invoice for booking %d: %v", bookingID, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice ID: %v", err)
+	}
+	inv.ID = int(id)
+	return inv, nil
+}
+
+// invoiceQuery selects invoices for scanInvoice
+const invoiceQuery = `
+	SELECT id, number, booking_id, issued_on, vat_rate, seller_name, seller_address,
+	       seller_registry_code, seller_vat_number, seller_email, seller_bank_account, created_at
+	FROM invoices
+`
+
+// scanInvoice reads a row selected with invoiceQuery
+func scanInvoice(row rowScanner) (*models.Invoice, error) {
+	var inv models.Invoice
+	err := row.Scan(&inv.ID, &inv.Number, &inv.BookingID, &inv.IssuedOn, &inv.VATRate, &inv.Seller.Name, &inv.Seller.Address,
+		&inv.Seller.RegistryCode, &inv.Seller.VATNumber, &inv.Seller.Email, &inv.Seller.BankAccount, &inv.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get invoice: %v", err)
+	}
+	return &inv, nil
+}
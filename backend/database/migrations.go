@@ -0,0 +1,474 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"massage-booking/backend/clock"
+)
+
+// migration is a numbered set of schema statements applied exactly once.
+// apply, if set, runs after the statements for data changes that need Go code.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	apply      func(ctx context.Context, tx *sql.Tx, loc *time.Location) error
+}
+
+// migrations lists every schema change in the order it must be applied.
+// Append new entries; never edit or reorder ones that have shipped.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "initial schema",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS massage_types (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				duration INTEGER NOT NULL,
+				price REAL NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS time_slots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				date TEXT NOT NULL,
+				time TEXT NOT NULL,
+				service_id INTEGER NOT NULL,
+				available INTEGER NOT NULL DEFAULT 1,
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS bookings (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				reference TEXT UNIQUE NOT NULL,
+				client_name TEXT NOT NULL,
+				email TEXT NOT NULL,
+				phone TEXT NOT NULL,
+				service_id INTEGER NOT NULL,
+				date TEXT NOT NULL,
+				time_slot TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`CREATE TABLE IF NOT EXISTS temporary_reservations (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				slot_id INTEGER NOT NULL,
+				reserved_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				expires_at DATETIME NOT NULL,
+				FOREIGN KEY (slot_id) REFERENCES time_slots (id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_expires_at ON temporary_reservations(expires_at);`,
+		},
+	},
+	{
+		version: 2,
+		name:    "slot and booking start timestamps",
+		statements: []string{
+			`ALTER TABLE time_slots ADD COLUMN starts_at DATETIME;`,
+			`ALTER TABLE bookings ADD COLUMN starts_at DATETIME;`,
+			`CREATE INDEX IF NOT EXISTS idx_bookings_starts_at ON bookings(starts_at);`,
+		},
+		apply: backfillStartsAt,
+	},
+	{
+		version: 3,
+		name:    "email outbox",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS email_outbox (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				kind TEXT NOT NULL,
+				booking_id INTEGER NOT NULL,
+				recipient TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				next_attempt_at DATETIME NOT NULL,
+				created_at DATETIME NOT NULL,
+				sent_at DATETIME,
+				FOREIGN KEY (booking_id) REFERENCES bookings (id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(status, next_attempt_at);`,
+		},
+	},
+	{
+		version: 4,
+		name:    "booking status and reminders",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN status TEXT NOT NULL DEFAULT 'confirmed';`,
+			`CREATE TABLE IF NOT EXISTS booking_reminders (
+				booking_id INTEGER NOT NULL,
+				offset_minutes INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (booking_id, offset_minutes),
+				FOREIGN KEY (booking_id) REFERENCES bookings (id)
+			);`,
+		},
+	},
+	{
+		version: 5,
+		name:    "sms notifications",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN sms_opt_in INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE email_outbox ADD COLUMN channel TEXT NOT NULL DEFAULT 'email';`,
+		},
+	},
+	{
+		version: 6,
+		name:    "booking locale",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN locale TEXT NOT NULL DEFAULT 'en';`,
+		},
+	},
+	{
+		version: 7,
+		name:    "booking cancellation and staff digests",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN cancelled_at DATETIME;`,
+			`CREATE TABLE IF NOT EXISTS staff_digests (
+				date TEXT PRIMARY KEY,
+				sent_at DATETIME NOT NULL
+			);`,
+		},
+	},
+	{
+		version: 8,
+		name:    "online payments",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN hold_expires_at DATETIME;`,
+			`CREATE TABLE IF NOT EXISTS payments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				booking_id INTEGER NOT NULL,
+				provider TEXT NOT NULL,
+				checkout_id TEXT UNIQUE NOT NULL,
+				payment_ref TEXT NOT NULL DEFAULT '',
+				amount INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'pending',
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				FOREIGN KEY (booking_id) REFERENCES bookings (id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_payments_booking ON payments(booking_id);`,
+			`CREATE INDEX IF NOT EXISTS idx_bookings_hold ON bookings(status, hold_expires_at);`,
+		},
+	},
+	{
+		version: 9,
+		name:    "integer prices with currency",
+		statements: []string{
+			// Prices so far were always euros
+			`ALTER TABLE massage_types ADD COLUMN price_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE massage_types ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';`,
+			`UPDATE massage_types SET price_cents = CAST(ROUND(price * 100) AS INTEGER);`,
+			`ALTER TABLE massage_types DROP COLUMN price;`,
+			`UPDATE payments SET currency = UPPER(currency);`,
+		},
+	},
+	{
+		version: 10,
+		name:    "booked service snapshot",
+		statements: []string{
+			`ALTER TABLE bookings ADD COLUMN service_name TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE bookings ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE bookings ADD COLUMN price_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE bookings ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';`,
+			// Existing bookings get the catalog as it is now, the best record there is
+			`UPDATE bookings SET service_name = mt.name, duration = mt.duration, price_cents = mt.price_cents, currency = mt.currency
+			FROM massage_types mt WHERE mt.id = bookings.service_id;`,
+		},
+	},
+	{
+		version: 11,
+		name:    "promo codes",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS promo_codes (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				code TEXT UNIQUE NOT NULL,
+				kind TEXT NOT NULL,
+				value INTEGER NOT NULL,
+				currency TEXT NOT NULL DEFAULT '',
+				valid_from DATETIME,
+				valid_until DATETIME,
+				max_uses INTEGER NOT NULL DEFAULT 0,
+				max_uses_per_email INTEGER NOT NULL DEFAULT 0,
+				active INTEGER NOT NULL DEFAULT 1,
+				created_at DATETIME NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS promo_code_services (
+				promo_code_id INTEGER NOT NULL,
+				service_id INTEGER NOT NULL,
+				PRIMARY KEY (promo_code_id, service_id),
+				FOREIGN KEY (promo_code_id) REFERENCES promo_codes (id),
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`ALTER TABLE bookings ADD COLUMN promo_code_id INTEGER REFERENCES promo_codes (id);`,
+			`ALTER TABLE bookings ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE bookings ADD COLUMN discount_cents INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE INDEX IF NOT EXISTS idx_bookings_promo ON bookings(promo_code_id);`,
+		},
+	},
+	{
+		version: 12,
+		name:    "gift vouchers",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS gift_vouchers (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				code TEXT UNIQUE NOT NULL,
+				service_id INTEGER,
+				service_name TEXT NOT NULL DEFAULT '',
+				amount_cents INTEGER NOT NULL,
+				balance_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				purchaser_name TEXT NOT NULL DEFAULT '',
+				recipient_name TEXT NOT NULL DEFAULT '',
+				recipient_email TEXT NOT NULL DEFAULT '',
+				message TEXT NOT NULL DEFAULT '',
+				locale TEXT NOT NULL DEFAULT 'en',
+				expires_on TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`ALTER TABLE bookings ADD COLUMN voucher_id INTEGER REFERENCES gift_vouchers (id);`,
+			`ALTER TABLE bookings ADD COLUMN voucher_code TEXT NOT NULL DEFAULT '';`,
+			`ALTER TABLE bookings ADD COLUMN voucher_cents INTEGER NOT NULL DEFAULT 0;`,
+			`CREATE INDEX IF NOT EXISTS idx_bookings_voucher ON bookings(voucher_id);`,
+		},
+	},
+	{
+		version: 13,
+		name:    "session packages",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS session_packages (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				email TEXT NOT NULL,
+				client_name TEXT NOT NULL DEFAULT '',
+				service_id INTEGER NOT NULL,
+				service_name TEXT NOT NULL,
+				sessions INTEGER NOT NULL,
+				remaining INTEGER NOT NULL,
+				expires_on TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_session_packages_email ON session_packages(email);`,
+			`ALTER TABLE bookings ADD COLUMN package_id INTEGER REFERENCES session_packages (id);`,
+		},
+	},
+	{
+		version: 14,
+		name:    "pricing rules",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS pricing_rules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				days TEXT NOT NULL DEFAULT '',
+				start_time TEXT NOT NULL DEFAULT '',
+				end_time TEXT NOT NULL DEFAULT '',
+				min_lead_hours INTEGER NOT NULL DEFAULT 0,
+				max_lead_hours INTEGER NOT NULL DEFAULT 0,
+				percent INTEGER NOT NULL,
+				created_at DATETIME NOT NULL
+			);`,
+			`CREATE TABLE IF NOT EXISTS pricing_rule_services (
+				pricing_rule_id INTEGER NOT NULL,
+				service_id INTEGER NOT NULL,
+				PRIMARY KEY (pricing_rule_id, service_id),
+				FOREIGN KEY (pricing_rule_id) REFERENCES pricing_rules (id),
+				FOREIGN KEY (service_id) REFERENCES massage_types (id)
+			);`,
+			`ALTER TABLE temporary_reservations ADD COLUMN price_cents INTEGER;`,
+			`ALTER TABLE temporary_reservations ADD COLUMN currency TEXT;`,
+		},
+	},
+	{
+		version: 15,
+		name:    "invoices",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS invoices (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				number TEXT NOT NULL UNIQUE,
+				sequence INTEGER NOT NULL UNIQUE,
+				booking_id INTEGER NOT NULL UNIQUE,
+				issued_on TEXT NOT NULL,
+				vat_rate INTEGER NOT NULL DEFAULT 0,
+				seller_name TEXT NOT NULL DEFAULT '',
+				seller_address TEXT NOT NULL DEFAULT '',
+				seller_registry_code TEXT NOT NULL DEFAULT '',
+				seller_vat_number TEXT NOT NULL DEFAULT '',
+				seller_email TEXT NOT NULL DEFAULT '',
+				seller_bank_account TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (booking_id) REFERENCES bookings (id)
+			);`,
+		},
+	},
+	{
+		version: 16,
+		name:    "tax rates",
+		statements: []string{
+			`ALTER TABLE massage_types ADD COLUMN tax_rate INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE massage_types ADD COLUMN tax_inclusive INTEGER NOT NULL DEFAULT 1;`,
+			`ALTER TABLE bookings ADD COLUMN tax_rate INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE bookings ADD COLUMN tax_inclusive INTEGER NOT NULL DEFAULT 1;`,
+			`ALTER TABLE bookings ADD COLUMN net_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE bookings ADD COLUMN tax_cents INTEGER NOT NULL DEFAULT 0;`,
+			`ALTER TABLE bookings ADD COLUMN gross_cents INTEGER NOT NULL DEFAULT 0;`,
+			// No tax was recorded before, so existing bookings are untaxed
+			`UPDATE bookings SET
+				gross_cents = CASE WHEN package_id IS NULL THEN price_cents - discount_cents ELSE 0 END,
+				net_cents = CASE WHEN package_id IS NULL THEN price_cents - discount_cents ELSE 0 END;`,
+		},
+	},
+	{
+		version: 17,
+		name:    "refunds",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS refunds (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				booking_id INTEGER NOT NULL,
+				payment_id INTEGER,
+				amount_cents INTEGER NOT NULL,
+				currency TEXT NOT NULL,
+				reason TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				provider_ref TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL,
+				error TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				FOREIGN KEY (booking_id) REFERENCES bookings (id),
+				FOREIGN KEY (payment_id) REFERENCES payments (id)
+			);`,
+			`CREATE INDEX IF NOT EXISTS idx_refunds_booking ON refunds (booking_id);`,
+		},
+	},
+	{
+		version: 18,
+		name:    "package codes",
+		statements: []string{
+			`ALTER TABLE session_packages ADD COLUMN code TEXT NOT NULL DEFAULT '';`,
+			// Existing packages get a random code that staff can look up and pass on
+			`UPDATE session_packages SET code = 'PACK-' || upper(hex(randomblob(2))) || '-' || upper(hex(randomblob(2)));`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_session_packages_code ON session_packages (code);`,
+		},
+	},
+	{
+		version: 19,
+		name:    "refunds of cancellations",
+		statements: []string{
+			`ALTER TABLE refunds ADD COLUMN cancellation INTEGER NOT NULL DEFAULT 0;`,
+		},
+	},
+}
+
+// migrate creates the schema_migrations table and applies any pending migrations
+func (s *Store) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+
+	current, err := s.schemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %v", m.version, err)
+		}
+		for _, stmt := range m.statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d (%s): %v", m.version, m.name, err)
+			}
+		}
+		if m.apply != nil {
+			if err := m.apply(ctx, tx, s.loc); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to apply migration %d (%s): %v", m.version, m.name, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.version, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %v", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %v", m.version, err)
+		}
+		log.Printf("Applied migration %d: %s", m.version, m.name)
+	}
+
+	return nil
+}
+
+// schemaVersion returns the highest applied migration version
+func (s *Store) schemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	return version, nil
+}
+
+// MigrationsApplied reports whether the database schema is at the latest known version
+func (s *Store) MigrationsApplied(ctx context.Context) (bool, error) {
+	version, err := s.schemaVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	return version >= migrations[len(migrations)-1].version, nil
+}
+
+// backfillStartsAt computes UTC start instants for existing slots and bookings
+// from their wall-clock date and time in the business timezone
+func backfillStartsAt(ctx context.Context, tx *sql.Tx, loc *time.Location) error {
+	for _, table := range []struct{ name, timeColumn string }{
+		{"time_slots", "time"},
+		{"bookings", "time_slot"},
+	} {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, date, %s FROM %s", table.timeColumn, table.name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", table.name, err)
+		}
+
+		starts := make(map[int]string)
+		for rows.Next() {
+			var id int
+			var date, hhmm string
+			if err := rows.Scan(&id, &date, &hhmm); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s: %v", table.name, err)
+			}
+			startsAt, err := clock.LocalTime(date, hhmm, loc)
+			if err != nil {
+				log.Printf("Skipping %s %d: %v", table.name, id, err)
+				continue
+			}
+			starts[id] = formatTimestamp(startsAt)
+		}
+		rows.Close()
+
+		for id, startsAt := range starts {
+			query := fmt.Sprintf("UPDATE %s SET starts_at = ? WHERE id = ?", table.name)
+			if _, err := tx.ExecContext(ctx, query, startsAt, id); err != nil {
+				return fmt.Errorf("failed to update %s %d: %v", table.name, id, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// enqueueEmail adds an email to the outbox; call it inside the transaction
+// that creates the data the email describes
+func enqueueEmail(ctx context.Context, ex execer, kind string, bookingID int, recipient string, now time.Time) error {
+	return enqueue(ctx, ex, models.ChannelEmail, kind, bookingID, recipient, now)
+}
+
+// enqueueSMS adds a text message for an E.164 number to the outbox
+func enqueueSMS(ctx context.Context, ex execer, kind string, bookingID int, phone string, now time.Time) error {
+	return enqueue(ctx, ex, models.ChannelSMS, kind, bookingID, phone, now)
+}
+
+func enqueue(ctx context.Context, ex execer, channel, kind string, bookingID int, recipient string, now time.Time) error {
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO email_outbox (channel, kind, booking_id, recipient, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, channel, kind, bookingID, recipient, models.OutboxStatusPending, formatTimestamp(now), formatTimestamp(now))
+	if err != nil {
+		return fmt.Errorf("failed to queue %s %s: %v", kind, channel, err)
+	}
+	return nil
+}
+
+const outboxColumns = `id, channel, kind, booking_id, recipient, status, attempts, last_error,
+	next_attempt_at, created_at, sent_at`
+
+// scanOutboxEmails reads outbox rows selected with outboxColumns
+func scanOutboxEmails(rows *sql.Rows) ([]models.OutboxEmail, error) {
+	defer rows.Close()
+
+	var emails []models.OutboxEmail
+	for rows.Next() {
+		var e models.OutboxEmail
+		var sentAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.Channel, &e.Kind, &e.BookingID, &e.Recipient, &e.Status, &e.Attempts, &e.LastError,
+			&e.NextAttemptAt, &e.CreatedAt, &sentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox email: %v", err)
+		}
+		if sentAt.Valid {
+			e.SentAt = &sentAt.Time
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
+
+// DueEmails returns pending outbox emails whose next attempt is due, oldest first
+func (s *Store) DueEmails(ctx context.Context, limit int) ([]models.OutboxEmail, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+outboxColumns+`
+		FROM email_outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+	`, models.OutboxStatusPending, s.now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due emails: %v", err)
+	}
+	return scanOutboxEmails(rows)
+}
+
+// ListEmails returns outbox emails, newest first, optionally filtered by status
+func (s *Store) ListEmails(ctx context.Context, status string) ([]models.OutboxEmail, error) {
+	query := "SELECT " + outboxColumns + " FROM email_outbox"
+	var args []any
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %v", err)
+	}
+	return scanOutboxEmails(rows)
+}
+
+// MarkEmailSent records a successful delivery
+func (s *Store) MarkEmailSent(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?, attempts = attempts + 1, last_error = '', sent_at = ?
+		WHERE id = ?
+	`, models.OutboxStatusSent, s.now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email %d sent: %v", id, err)
+	}
+	return nil
+}
+
+// MarkEmailFailed records a failed attempt. The email is retried at
+// nextAttemptAt, or moved to the dead-letter state when dead is true.
+func (s *Store) MarkEmailFailed(ctx context.Context, id int, sendErr error, nextAttemptAt time.Time, dead bool) error {
+	status := models.OutboxStatusPending
+	if dead {
+		status = models.OutboxStatusDead
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, status, sendErr.Error(), formatTimestamp(nextAttemptAt), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email %d failed: %v", id, err)
+	}
+	return nil
+}
+
+// MarkEmailSkipped records that an email was not sent because it is no longer needed
+func (s *Store) MarkEmailSkipped(ctx context.Context, id int, reason string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?, last_error = ?
+		WHERE id = ?
+	`, models.OutboxStatusSkipped, reason, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark email %d skipped: %v", id, err)
+	}
+	return nil
+}
+
+// ResendEmail puts an email back in the queue for immediate delivery with a fresh attempt count
+func (s *Store) ResendEmail(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = ?, attempts = 0, next_attempt_at = ?
+		WHERE id = ?
+	`, models.OutboxStatusPending, s.now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue email %d: %v", id, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrEmailNotFound
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"massage-booking/backend/models"
+)
+
+// Errors returned when a session package cannot be changed
+var (
+	ErrPackageNotFound      = errors.New("session package not found")
+	ErrPackageFull          = errors.New("session package has all its sessions left")
+	ErrPackageExpired       = errors.New("session package has expired")
+	ErrPackageUsedUp        = errors.New("session package has no sessions left")
+	ErrPackageNotApplicable = errors.New("session package is for another service")
+)
+
+// NormalizeEmail returns the form package emails are stored and matched in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePackageCode returns the form package codes are stored and matched in
+func NormalizePackageCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// packageQuery selects session packages for scanPackage
+const packageQuery = `
+	SELECT id, code, email, client_name, service_id, service_name, sessions, remaining, expires_on, created_at
+	FROM session_packages
+`
+
+// scanPackage reads a row selected with packageQuery
+func scanPackage(row rowScanner) (models.SessionPackage, error) {
+	var p models.SessionPackage
+	err := row.Scan(&p.ID, &p.Code, &p.Email, &p.ClientName, &p.ServiceID, &p.ServiceName, &p.Sessions, &p.Remaining, &p.ExpiresOn, &p.CreatedAt)
+	return p, err
+}
+
+// CreatePackage stores a new session package with all its sessions left
+// and a new random code
+func (s *Store) CreatePackage(ctx context.Context, req models.PackageRequest) (*models.SessionPackage, error) {
+	p := models.SessionPackage{
+		Email:      NormalizeEmail(req.Email),
+		ClientName: req.ClientName,
+		ServiceID:  req.ServiceID,
+		Sessions:   req.Sessions,
+		Remaining:  req.Sessions,
+		ExpiresOn:  req.ExpiresOn,
+		CreatedAt:  s.clock.Now().UTC(),
+	}
+
+	err := s.db.QueryRowContext(ctx, "SELECT name FROM massage_types WHERE id = ?", p.ServiceID).Scan(&p.ServiceName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrServiceNotFound
+		}
+		return nil, fmt.Errorf("failed to get service %d: %v", p.ServiceID, err)
+	}
+
+	// Codes are random, so a clash is unlikely but not impossible
+	for attempt := 0; p.Code == ""; attempt++ {
+		if attempt == 5 {
+			return nil, errors.New("failed to generate a unique package code")
+		}
+		code, err := generateCode("PACK")
+		if err != nil {
+			return nil, err
+		}
+		var exists bool
+		if err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM session_packages WHERE code = ?)", code).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check package code: %v", err)
+		}
+		if !exists {
+			p.Code = code
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO session_packages (code, email, client_name, service_id, service_name, sessions, remaining, expires_on, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Code, p.Email, p.ClientName, p.ServiceID, p.ServiceName, p.Sessions, p.Remaining, p.ExpiresOn, formatTimestamp(p.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session package: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session package ID: %v", err)
+	}
+	p.ID = int(id)
+	return &p, nil
+}
+
+// ListPackages returns the session packages bought with an email, or all
+// packages if email is empty, newest first
+func (s *Store) ListPackages(ctx context.Context, email string) ([]models.SessionPackage, error) {
+	query, args := packageQuery, []any{}
+	if email != "" {
+		query += "WHERE email = ? "
+		args = append(args, NormalizeEmail(email))
+	}
+	rows, err := s.db.QueryContext(ctx, query+"ORDER BY id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session packages: %v", err)
+	}
+	defer rows.Close()
+
+	packages := []models.SessionPackage{}
+	for rows.Next() {
+		p, err := scanPackage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session package: %v", err)
+		}
+		packages = append(packages, p)
+	}
+	return packages, rows.Err()
+}
+
+// GetPackage returns a session package by its code
+func (s *Store) GetPackage(ctx context.Context, code string) (*models.SessionPackage, error) {
+	p, err := scanPackage(s.db.QueryRowContext(ctx, packageQuery+"WHERE code = ?", NormalizePackageCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPackageNotFound
+		}
+		return nil, fmt.Errorf("failed to get session package: %v", err)
+	}
+	return &p, nil
+}
+
+// RestorePackageSession gives a session back to a package, for example when
+// staff void a booking that used one
+func (s *Store) RestorePackageSession(ctx context.Context, id int) (*models.SessionPackage, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	p, err := scanPackage(tx.QueryRowContext(ctx, packageQuery+"WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPackageNotFound
+		}
+		return nil, fmt.Errorf("failed to get session package %d: %v", id, err)
+	}
+	if p.Remaining >= p.Sessions {
+		return nil, ErrPackageFull
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE session_packages SET remaining = MIN(sessions, remaining + 1) WHERE id = ?", id); err != nil {
+		return nil, fmt.Errorf("failed to restore session to package %d: %v", id, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	p.Remaining++
+	return &p, nil
+}
+
+// restorePackageSession gives back the session a cancelled booking took
+// from its package, never raising it above the sessions bought
+func restorePackageSession(ctx context.Context, ex execer, bookingID int) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE session_packages
+		SET remaining = MIN(sessions, remaining + 1)
+		WHERE id = (SELECT package_id FROM bookings WHERE id = ?)
+	`, bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to restore session package of booking %d: %v", bookingID, err)
+	}
+	return nil
+}
+
+// findPackage looks up the package with code and checks that it can pay for
+// a booking of the service made with email today. It returns nil if no code
+// is given. A package sold to another email is reported as not found, so
+// the code alone does not reveal whose it is.
+func (s *Store) findPackage(ctx context.Context, q queryRower, code, email string, serviceID int) (*models.SessionPackage, error) {
+	if NormalizePackageCode(code) == "" {
+		return nil, nil
+	}
+	p, err := scanPackage(q.QueryRowContext(ctx, packageQuery+"WHERE code = ?", NormalizePackageCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPackageNotFound
+		}
+		return nil, fmt.Errorf("failed to find session package: %v", err)
+	}
+	switch {
+	case p.Email != NormalizeEmail(email):
+		return nil, ErrPackageNotFound
+	case p.ServiceID != serviceID:
+		return nil, ErrPackageNotApplicable
+	case p.ExpiresOn != "" && p.ExpiresOn < s.today():
+		return nil, ErrPackageExpired
+	case p.Remaining <= 0:
+		return nil, ErrPackageUsedUp
+	}
+	return &p, nil
+}
+
+// usePackageSession takes one session off a package
+func usePackageSession(ctx context.Context, ex execer, id int) error {
+	if _, err := ex.ExecContext(ctx, "UPDATE session_packages SET remaining = remaining - 1 WHERE id = ? AND remaining > 0", id); err != nil {
+		return fmt.Errorf("failed to use session of package %d: %v", id, err)
+	}
+	return nil
+}
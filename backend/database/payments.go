@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+)
+
+// Errors returned by the payment methods of Store
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrPaymentTooLate  = errors.New("payment arrived after the booking was released")
+)
+
+// AttachCheckout records the provider checkout created for a booking pending payment
+func (s *Store) AttachCheckout(ctx context.Context, p models.Payment) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO payments (booking_id, provider, checkout_id, amount, currency, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.BookingID, p.Provider, p.CheckoutID, p.Amount, p.Currency, models.PaymentStatusPending, s.now(), s.now())
+	if err != nil {
+		return fmt.Errorf("failed to record checkout for booking %d: %v", p.BookingID, err)
+	}
+	return nil
+}
+
+// ListPayments returns the payments of a booking, oldest first
+func (s *Store) ListPayments(ctx context.Context, bookingID int) ([]models.Payment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, booking_id, provider, checkout_id, payment_ref, amount, currency, status, created_at, updated_at
+		FROM payments WHERE booking_id = ? ORDER BY id
+	`, bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %v", err)
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.ID, &p.BookingID, &p.Provider, &p.CheckoutID, &p.PaymentRef, &p.Amount, &p.Currency,
+			&p.Status, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %v", err)
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// ConfirmPayment marks a checkout paid and confirms its booking, queueing
+// the confirmation in the same transaction. Repeated webhooks for a paid
+// checkout are ignored. If the hold was already released the payment is
+// still recorded and ErrPaymentTooLate is returned so it can be refunded.
+func (s *Store) ConfirmPayment(ctx context.Context, checkoutID, paymentRef string) (*models.BookingDetail, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var bookingID int
+	var paymentStatus, bookingStatus, email, phone string
+	var smsOptIn bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT p.booking_id, p.status, b.status, b.email, b.phone, b.sms_opt_in
+		FROM payments p
+		JOIN bookings b ON b.id = p.booking_id
+		WHERE p.checkout_id = ?
+	`, checkoutID).Scan(&bookingID, &paymentStatus, &bookingStatus, &email, &phone, &smsOptIn)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("failed to get payment %s: %v", checkoutID, err)
+	}
+	if paymentStatus == models.PaymentStatusPaid {
+		// Release the connection before reading outside the transaction
+		tx.Rollback()
+		return s.GetBookingByID(ctx, bookingID)
+	}
+
+	now := s.clock.Now().UTC()
+	if _, err = tx.ExecContext(ctx, "UPDATE payments SET status = ?, payment_ref = ?, updated_at = ? WHERE checkout_id = ?",
+		models.PaymentStatusPaid, paymentRef, formatTimestamp(now), checkoutID); err != nil {
+		return nil, fmt.Errorf("failed to mark payment %s paid: %v", checkoutID, err)
+	}
+
+	late := bookingStatus != models.BookingStatusPendingPayment
+	if !late {
+		if _, err = tx.ExecContext(ctx, "UPDATE bookings SET status = ?, hold_expires_at = NULL WHERE id = ?",
+			models.BookingStatusConfirmed, bookingID); err != nil {
+			return nil, fmt.Errorf("failed to confirm booking %d: %v", bookingID, err)
+		}
+		if err = s.enqueueConfirmation(ctx, tx, bookingID, email, phone, smsOptIn, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	metrics.Payments.Inc(models.PaymentStatusPaid)
+	if !late {
+		metrics.BookingsCreated.Inc()
+	}
+
+	booking, err := s.GetBookingByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if late {
+		return booking, ErrPaymentTooLate
+	}
+	return booking, nil
+}
+
+// FailPayment records that a checkout failed or expired and releases its
+// booking's slot if the booking is still waiting for payment
+func (s *Store) FailPayment(ctx context.Context, checkoutID, status string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var bookingID int
+	var paymentStatus string
+	err = tx.QueryRowContext(ctx, "SELECT booking_id, status FROM payments WHERE checkout_id = ?", checkoutID).
+		Scan(&bookingID, &paymentStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPaymentNotFound
+		}
+		return fmt.Errorf("failed to get payment %s: %v", checkoutID, err)
+	}
+	if paymentStatus != models.PaymentStatusPending {
+		return nil
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE payments SET status = ?, updated_at = ? WHERE checkout_id = ?",
+		status, s.now(), checkoutID); err != nil {
+		return fmt.Errorf("failed to mark payment %s %s: %v", checkoutID, status, err)
+	}
+	if err = s.releaseHold(ctx, tx, bookingID, status); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	metrics.Payments.Inc(status)
+	return nil
+}
+
+// ReleaseBooking gives up on the payment of a booking pending payment, e.g.
+// when no checkout could be created, and frees its slot
+func (s *Store) ReleaseBooking(ctx context.Context, bookingID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err = s.releaseHold(ctx, tx, bookingID, models.PaymentStatusFailed); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// ReleaseExpiredHolds cancels bookings whose payment hold has run out and frees their slots
+func (s *Store) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM bookings WHERE status = ? AND hold_expires_at <= ?",
+		models.BookingStatusPendingPayment, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired holds: %v", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to start transaction: %v", err)
+		}
+		if err = s.releaseHold(ctx, tx, id, models.PaymentStatusExpired); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if err = tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+		metrics.Payments.Inc(models.PaymentStatusExpired)
+	}
+
+	if len(ids) > 0 {
+		log.Printf("Released %d bookings whose payment hold expired", len(ids))
+	}
+	return len(ids), nil
+}
+
+// releaseHold cancels a booking that is still pending payment, frees its
+// slot and closes its pending payments with status
+func (s *Store) releaseHold(ctx context.Context, tx *sql.Tx, bookingID int, status string) error {
+	now := s.now()
+	result, err := tx.ExecContext(ctx, `
+		UPDATE bookings SET status = ?, cancelled_at = ?, hold_expires_at = NULL
+		WHERE id = ? AND status = ?
+	`, models.BookingStatusCancelled, now, bookingID, models.BookingStatusPendingPayment)
+	if err != nil {
+		return fmt.Errorf("failed to release booking %d: %v", bookingID, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	if err = releaseSlot(ctx, tx, bookingID); err != nil {
+		return err
+	}
+	if err = restoreVoucher(ctx, tx, bookingID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE payments SET status = ?, updated_at = ? WHERE booking_id = ? AND status = ?",
+		status, now, bookingID, models.PaymentStatusPending); err != nil {
+		return fmt.Errorf("failed to close payments of booking %d: %v", bookingID, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// ErrPricingRuleNotFound is returned when deleting a pricing rule that does not exist
+var ErrPricingRuleNotFound = errors.New("pricing rule not found")
+
+// CreatePricingRule stores a new pricing rule; it applies to slots listed
+// and reserved from then on
+func (s *Store) CreatePricingRule(ctx context.Context, r models.PricingRule) (*models.PricingRule, error) {
+	r.CreatedAt = s.clock.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO pricing_rules (name, days, start_time, end_time, min_lead_hours, max_lead_hours, percent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.Name, joinIDs(r.Days), r.StartTime, r.EndTime, r.MinLeadHours, r.MaxLeadHours, r.Percent, formatTimestamp(r.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pricing rule: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing rule ID: %v", err)
+	}
+	r.ID = int(id)
+
+	for _, serviceID := range r.ServiceIDs {
+		if _, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO pricing_rule_services (pricing_rule_id, service_id) VALUES (?, ?)",
+			r.ID, serviceID); err != nil {
+			return nil, fmt.Errorf("failed to restrict pricing rule %d to service %d: %v", r.ID, serviceID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	if r.Days == nil {
+		r.Days = []int{}
+	}
+	if r.ServiceIDs == nil {
+		r.ServiceIDs = []int{}
+	}
+	return &r, nil
+}
+
+// ListPricingRules returns all pricing rules, oldest first
+func (s *Store) ListPricingRules(ctx context.Context) ([]models.PricingRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.name, r.days, r.start_time, r.end_time, r.min_lead_hours, r.max_lead_hours, r.percent, r.created_at,
+		       (SELECT COALESCE(GROUP_CONCAT(service_id), '') FROM pricing_rule_services WHERE pricing_rule_id = r.id)
+		FROM pricing_rules r
+		ORDER BY r.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pricing rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []models.PricingRule{}
+	for rows.Next() {
+		var r models.PricingRule
+		var days, services string
+		if err := rows.Scan(&r.ID, &r.Name, &days, &r.StartTime, &r.EndTime, &r.MinLeadHours, &r.MaxLeadHours,
+			&r.Percent, &r.CreatedAt, &services); err != nil {
+			return nil, fmt.Errorf("failed to scan pricing rule: %v", err)
+		}
+		r.Days = splitIDs(days)
+		r.ServiceIDs = splitIDs(services)
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// DeletePricingRule removes a pricing rule. Reservations already made keep
+// the price they were given.
+func (s *Store) DeletePricingRule(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM pricing_rule_services WHERE pricing_rule_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete services of pricing rule %d: %v", id, err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM pricing_rules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete pricing rule %d: %v", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %v", err)
+	}
+	if n == 0 {
+		return ErrPricingRuleNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// slotPrice returns the price of a service for a slot starting at startsAt
+// with the pricing rules applied, and the total adjustment in percent
+func (s *Store) slotPrice(base models.Money, rules []models.PricingRule, serviceID int, startsAt time.Time) (models.Money, int) {
+	return models.SlotPrice(base, rules, serviceID, startsAt.In(s.loc), startsAt.Sub(s.clock.Now()))
+}
+
+// servicePrice returns the catalog price of a service
+func servicePrice(ctx context.Context, q queryRower, serviceID int) (models.Money, error) {
+	var price models.Money
+	err := q.QueryRowContext(ctx, "SELECT price_cents, currency FROM massage_types WHERE id = ?", serviceID).
+		Scan(&price.Amount, &price.Currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return price, ErrServiceNotFound
+		}
+		return price, fmt.Errorf("failed to get service %d: %v", serviceID, err)
+	}
+	return price, nil
+}
+
+// serviceTax returns the tax rate of a service and whether its price includes it
+func serviceTax(ctx context.Context, q queryRower, serviceID int) (models.TaxRate, bool, error) {
+	var rate models.TaxRate
+	var inclusive bool
+	err := q.QueryRowContext(ctx, "SELECT tax_rate, tax_inclusive FROM massage_types WHERE id = ?", serviceID).
+		Scan(&rate, &inclusive)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, ErrServiceNotFound
+		}
+		return 0, false, fmt.Errorf("failed to get tax of service %d: %v", serviceID, err)
+	}
+	return rate, inclusive, nil
+}
+
+// lockedPrice returns the price locked into an unexpired reservation for
+// the service, and false if there is none, for example because the
+// reservation has expired or was made before pricing rules. A reservation
+// for another service's slot returns ErrReservationMismatch.
+func (s *Store) lockedPrice(ctx context.Context, q queryRower, reservationID, serviceID int) (models.Money, bool, error) {
+	var amount sql.NullInt64
+	var currency sql.NullString
+	var slotServiceID int
+	err := q.QueryRowContext(ctx, `
+		SELECT tr.price_cents, tr.currency, ts.service_id
+		FROM temporary_reservations tr
+		JOIN time_slots ts ON ts.id = tr.slot_id
+		WHERE tr.id = ? AND tr.expires_at > ?
+	`, reservationID, s.now()).Scan(&amount, &currency, &slotServiceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Money{}, false, nil
+		}
+		return models.Money{}, false, fmt.Errorf("failed to get price of reservation %d: %v", reservationID, err)
+	}
+	if slotServiceID != serviceID {
+		return models.Money{}, false, ErrReservationMismatch
+	}
+	if !amount.Valid || !currency.Valid {
+		return models.Money{}, false, nil
+	}
+	return models.NewMoney(amount.Int64, currency.String), true, nil
+}
+
+// joinIDs formats IDs as a comma-separated list for a TEXT column
+func joinIDs(ids []int) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.Itoa(id)
+	}
+	return strings.Join(fields, ",")
+}
+
+// splitIDs parses a comma-separated list of IDs, skipping anything else
+func splitIDs(value string) []int {
+	ids := []int{}
+	for _, field := range strings.Split(value, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
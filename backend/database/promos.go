@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// Errors returned when a promo code cannot be used or created
+var (
+	ErrPromoNotFound      = errors.New("promo code not found")
+	ErrPromoNotValidNow   = errors.New("promo code is not valid at this time")
+	ErrPromoUsedUp        = errors.New("promo code has been used up")
+	ErrPromoAlreadyUsed   = errors.New("promo code has already been used with this email")
+	ErrPromoNotApplicable = errors.New("promo code does not apply to this service")
+	ErrPromoExists        = errors.New("promo code already exists")
+)
+
+// NormalizePromoCode returns the form promo codes are stored and matched in
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CreatePromoCode stores a new promo code. Fixed discounts without a
+// currency are in the currency set with SetCurrency.
+func (s *Store) CreatePromoCode(ctx context.Context, p models.PromoCode) (*models.PromoCode, error) {
+	p.Code = NormalizePromoCode(p.Code)
+	if p.Kind == models.PromoKindFixed && p.Currency == "" {
+		p.Currency = s.currency
+	}
+	p.Active = true
+	p.CreatedAt = s.clock.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM promo_codes WHERE code = ?)", p.Code).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check promo code %s: %v", p.Code, err)
+	}
+	if exists {
+		return nil, ErrPromoExists
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO promo_codes (code, kind, value, currency, valid_from, valid_until, max_uses, max_uses_per_email, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Code, p.Kind, p.Value, p.Currency, nullTimestamp(p.ValidFrom), nullTimestamp(p.ValidUntil),
+		p.MaxUses, p.MaxUsesPerEmail, formatTimestamp(p.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promo code %s: %v", p.Code, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo code ID: %v", err)
+	}
+	p.ID = int(id)
+
+	for _, serviceID := range p.ServiceIDs {
+		if _, err = tx.ExecContext(ctx, "INSERT OR IGNORE INTO promo_code_services (promo_code_id, service_id) VALUES (?, ?)",
+			p.ID, serviceID); err != nil {
+			return nil, fmt.Errorf("failed to restrict promo code %s to service %d: %v", p.Code, serviceID, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	if p.ServiceIDs == nil {
+		p.ServiceIDs = []int{}
+	}
+	return &p, nil
+}
+
+// ListPromoCodes returns all promo codes with their current number of uses, newest first
+func (s *Store) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.code, p.kind, p.value, p.currency, p.valid_from, p.valid_until,
+		       p.max_uses, p.max_uses_per_email, p.active, p.created_at,
+		       (SELECT COUNT(*) FROM bookings b WHERE b.promo_code_id = p.id AND b.status != ?),
+		       (SELECT COALESCE(GROUP_CONCAT(service_id), '') FROM promo_code_services WHERE promo_code_id = p.id)
+		FROM promo_codes p
+		ORDER BY p.id DESC
+	`, models.BookingStatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promo codes: %v", err)
+	}
+	defer rows.Close()
+
+	codes := []models.PromoCode{}
+	for rows.Next() {
+		var p models.PromoCode
+		var validFrom, validUntil sql.NullTime
+		var services string
+		if err := rows.Scan(&p.ID, &p.Code, &p.Kind, &p.Value, &p.Currency, &validFrom, &validUntil,
+			&p.MaxUses, &p.MaxUsesPerEmail, &p.Active, &p.CreatedAt, &p.Uses, &services); err != nil {
+			return nil, fmt.Errorf("failed to scan promo code: %v", err)
+		}
+		if validFrom.Valid {
+			p.ValidFrom = &validFrom.Time
+		}
+		if validUntil.Valid {
+			p.ValidUntil = &validUntil.Time
+		}
+		p.ServiceIDs = splitIDs(services)
+		codes = append(codes, p)
+	}
+	return codes, rows.Err()
+}
+
+// DeactivatePromoCode stops a promo code from being accepted; bookings
+// already made with it keep their discount
+func (s *Store) DeactivatePromoCode(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE promo_codes SET active = 0 WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate promo code %d: %v", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %v", err)
+	}
+	if n == 0 {
+		return ErrPromoNotFound
+	}
+	return nil
+}
+
+// Quote returns the price of a service with the promo code, tax and gift
+// voucher applied, if given. Per-email limits and session packages are only
+// checked when the request has an email; a package leaves nothing to pay.
+// With a reservation the price locked into it is quoted instead of the
+// catalog price.
+func (s *Store) Quote(ctx context.Context, req models.QuoteRequest) (*models.Quote, error) {
+	price, err := servicePrice(ctx, s.db, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	taxRate, taxInclusive, err := serviceTax(ctx, s.db, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ReservationID != 0 {
+		locked, ok, err := s.lockedPrice(ctx, s.db, req.ReservationID, req.ServiceID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			price = locked
+		}
+	}
+
+	quote := &models.Quote{
+		ServiceID:     req.ServiceID,
+		Price:         price,
+		Discount:      models.NewMoney(0, price.Currency),
+		VoucherAmount: models.NewMoney(0, price.Currency),
+	}
+
+	pkg, err := s.findPackage(ctx, s.db, req.PackageCode, req.Email, req.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if pkg != nil {
+		quote.PackageID = pkg.ID
+		quote.TaxAmounts = models.ApplyTax(models.NewMoney(0, price.Currency), taxRate, taxInclusive)
+		quote.Total = quote.Gross
+		return quote, nil
+	}
+
+	if NormalizePromoCode(req.PromoCode) != "" {
+		promo, err := s.findPromo(ctx, s.db, req.PromoCode, req.ServiceID, req.Email, price.Currency)
+		if err != nil {
+			return nil, err
+		}
+		quote.PromoCode = promo.Code
+		quote.Discount = promo.Discount(price)
+	}
+	quote.TaxAmounts = models.ApplyTax(models.NewMoney(price.Amount-quote.Discount.Amount, price.Currency), taxRate, taxInclusive)
+	quote.Total = quote.Gross
+	if NormalizeVoucherCode(req.VoucherCode) != "" {
+		voucher, err := s.findVoucher(ctx, s.db, req.VoucherCode, req.ServiceID, price.Currency)
+		if err != nil {
+			return nil, err
+		}
+		quote.VoucherCode = voucher.Code
+		quote.VoucherAmount = voucher.Covers(quote.Total)
+		quote.Total = models.NewMoney(quote.Total.Amount-quote.VoucherAmount.Amount, price.Currency)
+	}
+	return quote, nil
+}
+
+// findPromo looks up an active promo code and checks that it can be used
+// now for the service, in currency, and by email if set
+func (s *Store) findPromo(ctx context.Context, q queryRower, code string, serviceID int, email, currency string) (*models.PromoCode, error) {
+	var p models.PromoCode
+	var validFrom, validUntil sql.NullTime
+	err := q.QueryRowContext(ctx, `
+		SELECT id, code, kind, value, currency, valid_from, valid_until, max_uses, max_uses_per_email
+		FROM promo_codes WHERE code = ? AND active = 1
+	`, NormalizePromoCode(code)).Scan(&p.ID, &p.Code, &p.Kind, &p.Value, &p.Currency, &validFrom, &validUntil,
+		&p.MaxUses, &p.MaxUsesPerEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPromoNotFound
+		}
+		return nil, fmt.Errorf("failed to get promo code: %v", err)
+	}
+
+	now := s.clock.Now()
+	if (validFrom.Valid && now.Before(validFrom.Time)) || (validUntil.Valid && !now.Before(validUntil.Time)) {
+		return nil, ErrPromoNotValidNow
+	}
+	if p.Kind == models.PromoKindFixed && p.Currency != currency {
+		return nil, ErrPromoNotApplicable
+	}
+
+	var restricted, allowed int
+	err = q.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(service_id = ?), 0) FROM promo_code_services WHERE promo_code_id = ?
+	`, serviceID, p.ID).Scan(&restricted, &allowed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check promo code services: %v", err)
+	}
+	if restricted > 0 && allowed == 0 {
+		return nil, ErrPromoNotApplicable
+	}
+
+	// Bookings that were cancelled or never paid give their use back
+	var uses, emailUses int
+	err = q.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(LOWER(email) = LOWER(?)), 0)
+		FROM bookings WHERE promo_code_id = ? AND status != ?
+	`, email, p.ID, models.BookingStatusCancelled).Scan(&uses, &emailUses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count promo code uses: %v", err)
+	}
+	if p.MaxUses > 0 && uses >= p.MaxUses {
+		return nil, ErrPromoUsedUp
+	}
+	if email != "" && p.MaxUsesPerEmail > 0 && emailUses >= p.MaxUsesPerEmail {
+		return nil, ErrPromoAlreadyUsed
+	}
+	return &p, nil
+}
+
+// nullTimestamp formats an optional instant for a nullable DATETIME column
+func nullTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTimestamp(t.UTC())
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+)
+
+// Errors returned by the refund methods of Store
+var (
+	ErrRefundNotFound      = errors.New("refund not found")
+	ErrRefundTooLarge      = errors.New("refund is more than what is left to refund")
+	ErrNoPaymentToRefund   = errors.New("booking has no captured online payment to refund")
+	ErrPaymentRefMissing   = errors.New("captured payment has no provider reference to refund")
+	ErrBookingNotCancelled = errors.New("booking is not cancelled")
+)
+
+// refundQuery selects refunds for scanRefund
+const refundQuery = `
+	SELECT r.id, r.booking_id, b.reference, COALESCE(r.payment_id, 0), COALESCE(p.payment_ref, ''),
+	       r.amount_cents, r.currency, r.reason, r.provider, r.provider_ref, r.status, r.error, r.cancellation, r.created_at, r.updated_at
+	FROM refunds r
+	JOIN bookings b ON b.id = r.booking_id
+	LEFT JOIN payments p ON p.id = r.payment_id
+`
+
+// scanRefund reads a row selected with refundQuery
+func scanRefund(row rowScanner) (models.Refund, error) {
+	var r models.Refund
+	err := row.Scan(&r.ID, &r.BookingID, &r.Reference, &r.PaymentID, &r.PaymentRef,
+		&r.Amount.Amount, &r.Amount.Currency, &r.Reason, &r.Provider, &r.ProviderRef, &r.Status, &r.Error, &r.Cancellation, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+// refundablePayment is a captured online payment and what is left to refund of it
+type refundablePayment struct {
+	id       int
+	provider string
+	ref      string
+	left     int64
+}
+
+// CreateRefund records a refund of a booking in the ledger. A manual refund
+// has already been paid back and is recorded as succeeded; any other is
+// taken from the booking's captured online payment and stays pending until
+// RecordRefundResult is called with the provider's answer. Refunds that have
+// not failed never add up to more than the booking's total, nor to more
+// than the payment they are taken from.
+func (s *Store) CreateRefund(ctx context.Context, req models.RefundRequest) (*models.Refund, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	currency, left, err := refundableAmount(ctx, tx, req.BookingID, req.Cancellation)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount > left {
+		return nil, ErrRefundTooLarge
+	}
+
+	var paymentID any
+	provider, status := models.RefundProviderManual, models.RefundStatusSucceeded
+	if !req.Manual {
+		payments, err := refundablePayments(ctx, tx, req.BookingID)
+		if err != nil {
+			return nil, err
+		}
+		if len(payments) == 0 {
+			return nil, ErrNoPaymentToRefund
+		}
+		for _, p := range payments {
+			if p.left >= req.Amount {
+				paymentID, provider, status = p.id, p.provider, models.RefundStatusPending
+				break
+			}
+		}
+		if paymentID == nil {
+			return nil, ErrRefundTooLarge
+		}
+	}
+
+	id, err := insertRefund(ctx, tx, req, paymentID, currency, provider, status, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	if status == models.RefundStatusSucceeded {
+		metrics.Refunds.Inc(status)
+	}
+	return s.GetRefund(ctx, id)
+}
+
+// CancellationRefundReason is recorded on refunds made when a paid booking is cancelled
+const CancellationRefundReason = "Booking cancelled"
+
+// recordCancellationRefunds records pending refunds of everything left to
+// refund from the captured online payments of a booking being cancelled,
+// one per payment and linked to the cancellation. They are sent to the
+// provider once the cancellation has been committed.
+func (s *Store) recordCancellationRefunds(ctx context.Context, tx *sql.Tx, bookingID int) error {
+	currency, left, err := refundableAmount(ctx, tx, bookingID, true)
+	if err != nil {
+		return err
+	}
+	payments, err := refundablePayments(ctx, tx, bookingID)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, p := range payments {
+		if left <= 0 {
+			break
+		}
+		req := models.RefundRequest{BookingID: bookingID, Amount: min(p.left, left), Reason: CancellationRefundReason, Cancellation: true}
+		if _, err := insertRefund(ctx, tx, req, p.id, currency, p.provider, models.RefundStatusPending, now); err != nil {
+			return err
+		}
+		left -= req.Amount
+	}
+	return nil
+}
+
+// refundableAmount returns the currency of a booking and how much of its
+// total has not been refunded yet, counting refunds that have not failed.
+// A refund for a cancellation needs the booking to be cancelled.
+func refundableAmount(ctx context.Context, tx *sql.Tx, bookingID int, cancellation bool) (string, int64, error) {
+	var currency, status string
+	var total, refunded int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT b.currency, b.status, b.gross_cents - b.voucher_cents,
+		       (SELECT COALESCE(SUM(r.amount_cents), 0) FROM refunds r WHERE r.booking_id = b.id AND r.status != ?)
+		FROM bookings b WHERE b.id = ?
+	`, models.RefundStatusFailed, bookingID).Scan(&currency, &status, &total, &refunded)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", 0, ErrBookingNotFound
+		}
+		return "", 0, fmt.Errorf("failed to get booking %d: %v", bookingID, err)
+	}
+	if cancellation && status != models.BookingStatusCancelled {
+		return "", 0, ErrBookingNotCancelled
+	}
+	return currency, total - refunded, nil
+}
+
+// insertRefund adds a refund to the ledger and returns its ID
+func insertRefund(ctx context.Context, tx *sql.Tx, req models.RefundRequest, paymentID any, currency, provider, status string, now string) (int, error) {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO refunds (booking_id, payment_id, amount_cents, currency, reason, provider, provider_ref, status, cancellation, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, req.BookingID, paymentID, req.Amount, currency, req.Reason, provider, req.ProviderRef, status, req.Cancellation, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record refund for booking %d: %v", req.BookingID, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get refund ID: %v", err)
+	}
+	return int(id), nil
+}
+
+// refundablePayments returns the captured online payments of a booking
+// that still have money left to refund, newest first. A captured payment
+// without the provider's reference cannot be refunded through the provider,
+// so it is reported as ErrPaymentRefMissing rather than skipped.
+func refundablePayments(ctx context.Context, tx *sql.Tx, bookingID int) ([]refundablePayment, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT p.id, p.provider, p.payment_ref,
+		       p.amount - (SELECT COALESCE(SUM(r.amount_cents), 0) FROM refunds r WHERE r.payment_id = p.id AND r.status != ?)
+		FROM payments p
+		WHERE p.booking_id = ? AND p.status = ?
+		ORDER BY p.id DESC
+	`, models.RefundStatusFailed, bookingID, models.PaymentStatusPaid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments of booking %d: %v", bookingID, err)
+	}
+	defer rows.Close()
+
+	var payments []refundablePayment
+	for rows.Next() {
+		var p refundablePayment
+		if err := rows.Scan(&p.id, &p.provider, &p.ref, &p.left); err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %v", err)
+		}
+		if p.left <= 0 {
+			continue
+		}
+		if p.ref == "" {
+			return nil, fmt.Errorf("payment %d of booking %d: %w", p.id, bookingID, ErrPaymentRefMissing)
+		}
+		payments = append(payments, p)
+	}
+	return payments, rows.Err()
+}
+
+// RecordRefundResult stores the provider's answer to a pending refund: its
+// status, the provider's refund ID and, for failed refunds, the reason
+func (s *Store) RecordRefundResult(ctx context.Context, id int, status, providerRef, failure string) (*models.Refund, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE refunds SET status = ?, provider_ref = ?, error = ?, updated_at = ? WHERE id = ?
+	`, status, providerRef, failure, s.now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update refund %d: %v", id, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to check refund update: %v", err)
+	} else if n == 0 {
+		return nil, ErrRefundNotFound
+	}
+	if status != models.RefundStatusPending {
+		metrics.Refunds.Inc(status)
+	}
+	return s.GetRefund(ctx, id)
+}
+
+// GetRefundByProviderRef returns the refund a provider knows by its own refund ID
+func (s *Store) GetRefundByProviderRef(ctx context.Context, provider, providerRef string) (*models.Refund, error) {
+	refund, err := scanRefund(s.db.QueryRowContext(ctx, refundQuery+"WHERE r.provider = ? AND r.provider_ref = ?", provider, providerRef))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefundNotFound
+		}
+		return nil, fmt.Errorf("failed to get refund %s: %v", providerRef, err)
+	}
+	return &refund, nil
+}
+
+// GetRefund returns a refund by ID
+func (s *Store) GetRefund(ctx context.Context, id int) (*models.Refund, error) {
+	refund, err := scanRefund(s.db.QueryRowContext(ctx, refundQuery+"WHERE r.id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefundNotFound
+		}
+		return nil, fmt.Errorf("failed to get refund %d: %v", id, err)
+	}
+	return &refund, nil
+}
+
+// ListRefunds returns the refunds ledger, newest first, optionally only for
+// one booking (bookingID > 0) or in one status (status != "")
+func (s *Store) ListRefunds(ctx context.Context, bookingID int, status string) ([]models.Refund, error) {
+	var conditions []string
+	var args []any
+	if bookingID > 0 {
+		conditions = append(conditions, "r.booking_id = ?")
+		args = append(args, bookingID)
+	}
+	if status != "" {
+		conditions = append(conditions, "r.status = ?")
+		args = append(args, status)
+	}
+	query := refundQuery
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := s.db.QueryContext(ctx, query+" ORDER BY r.id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %v", err)
+	}
+	defer rows.Close()
+
+	refunds := []models.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %v", err)
+		}
+		refunds = append(refunds, refund)
+	}
+	return refunds, rows.Err()
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// DefaultReminderOffsets is used when REMINDER_OFFSETS is not set
+const DefaultReminderOffsets = "24h,2h"
+
+// ParseReminderOffsets parses a comma-separated list of durations before an
+// appointment at which reminders are sent, e.g. "24h,2h". "none" disables reminders.
+func ParseReminderOffsets(value string) ([]time.Duration, error) {
+	if value == "" {
+		value = DefaultReminderOffsets
+	}
+	if value == "none" {
+		return nil, nil
+	}
+
+	var offsets []time.Duration
+	for _, field := range strings.Split(value, ",") {
+		offset, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminder offset %q: %v", field, err)
+		}
+		if offset < time.Minute || offset%time.Minute != 0 {
+			return nil, fmt.Errorf("reminder offset %q must be a whole number of minutes", field)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, nil
+}
+
+// reminderCandidate is an upcoming booking that may need a reminder
+type reminderCandidate struct {
+	id        int
+	email     string
+	phone     string
+	smsOptIn  bool
+	startsAt  time.Time
+	createdAt time.Time
+	recorded  map[int]bool // offsets in minutes already handled
+}
+
+// EnqueueDueReminders queues a reminder email (and SMS, if the client opted
+// in) for every confirmed booking that has reached one of the offsets before
+// its start. Each offset is recorded in booking_reminders so it is handled
+// once, even across restarts. When several offsets are due at once only the
+// closest one is sent, and offsets that fell before the booking was made are
+// recorded without sending.
+func (s *Store) EnqueueDueReminders(ctx context.Context, offsets []time.Duration) (int, error) {
+	if len(offsets) == 0 {
+		return 0, nil
+	}
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	now := s.clock.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.id, b.email, b.phone, b.sms_opt_in, b.starts_at, b.created_at, r.offset_minutes
+		FROM bookings b
+		LEFT JOIN booking_reminders r ON r.booking_id = b.id
+		WHERE b.status = ? AND b.starts_at > ? AND b.starts_at <= ?
+		ORDER BY b.id
+	`, models.BookingStatusConfirmed, formatTimestamp(now), formatTimestamp(now.Add(sorted[len(sorted)-1])))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query upcoming bookings: %v", err)
+	}
+
+	var candidates []*reminderCandidate
+	for rows.Next() {
+		var c reminderCandidate
+		var offset *int
+		if err := rows.Scan(&c.id, &c.email, &c.phone, &c.smsOptIn, &c.startsAt, &c.createdAt, &offset); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan booking: %v", err)
+		}
+		if n := len(candidates); n == 0 || candidates[n-1].id != c.id {
+			c.recorded = map[int]bool{}
+			candidates = append(candidates, &c)
+		}
+		if offset != nil {
+			candidates[len(candidates)-1].recorded[*offset] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, c := range candidates {
+		sent := false
+		for _, offset := range sorted {
+			minutes := int(offset / time.Minute)
+			remindAt := c.startsAt.Add(-offset)
+			if c.recorded[minutes] || remindAt.After(now) {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO booking_reminders (booking_id, offset_minutes, created_at) VALUES (?, ?, ?)
+			`, c.id, minutes, formatTimestamp(now)); err != nil {
+				return 0, fmt.Errorf("failed to record reminder for booking %d: %v", c.id, err)
+			}
+
+			// Closest offset first; the rest are superseded
+			if sent || remindAt.Before(c.createdAt) {
+				continue
+			}
+			if err := enqueueEmail(ctx, tx, models.EmailKindBookingReminder, c.id, c.email, now); err != nil {
+				return 0, err
+			}
+			if c.smsOptIn {
+				if err := enqueueSMS(ctx, tx, models.EmailKindBookingReminder, c.id, c.phone, now); err != nil {
+					return 0, err
+				}
+			}
+			sent = true
+			queued++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return queued, nil
+}
+
+// StartReminderJob queues due reminders every minute.
+// The job stops when ctx is cancelled and marks wg done once it has exited.
+func (s *Store) StartReminderJob(ctx context.Context, wg *sync.WaitGroup, offsets []time.Duration) {
+	if len(offsets) == 0 {
+		log.Println("Appointment reminders are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(1 * time.Minute)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("Stopped appointment reminder job")
+				return
+			case <-ticker.C:
+				queued, err := s.EnqueueDueReminders(ctx, offsets)
+				if err != nil {
+					log.Printf("Error queueing reminders: %v", err)
+				} else if queued > 0 {
+					log.Printf("Queued %d appointment reminders", queued)
+				}
+			}
+		}
+	}()
+	log.Printf("Started appointment reminder job (offsets %v)", offsets)
+}
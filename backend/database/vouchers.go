@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+// Errors returned when a gift voucher cannot be used
+var (
+	ErrVoucherNotFound      = errors.New("gift voucher not found")
+	ErrVoucherExpired       = errors.New("gift voucher has expired")
+	ErrVoucherUsedUp        = errors.New("gift voucher has no balance left")
+	ErrVoucherNotApplicable = errors.New("gift voucher does not apply to this service")
+)
+
+// voucherCodeAlphabet leaves out characters that are easily confused when
+// a code is typed from a printed certificate
+const voucherCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// NormalizeVoucherCode returns the form voucher codes are stored and matched in
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateVoucherCode returns a random code like GIFT-7KQ2-M9XD
+func generateVoucherCode() (string, error) {
+	return generateCode("GIFT")
+}
+
+// generateCode returns a random code of eight characters after a
+// four-letter prefix, e.g. GIFT-7KQ2-M9XD
+func generateCode(prefix string) (string, error) {
+	var random [8]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", fmt.Errorf("failed to generate code: %v", err)
+	}
+	code := []byte(prefix + "-XXXX-XXXX")
+	for i, j := 0, 5; i < len(random); i, j = i+1, j+1 {
+		if code[j] == '-' {
+			j++
+		}
+		code[j] = voucherCodeAlphabet[int(random[i])%len(voucherCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// voucherQuery selects gift vouchers for scanVoucher
+const voucherQuery = `
+	SELECT id, code, COALESCE(service_id, 0), service_name, amount_cents, balance_cents, currency,
+	       purchaser_name, recipient_name, recipient_email, message, locale, expires_on, created_at
+	FROM gift_vouchers
+`
+
+// scanVoucher reads a row selected with voucherQuery
+func scanVoucher(row rowScanner) (models.GiftVoucher, error) {
+	var v models.GiftVoucher
+	err := row.Scan(&v.ID, &v.Code, &v.ServiceID, &v.ServiceName, &v.Value.Amount, &v.Balance.Amount, &v.Value.Currency,
+		&v.PurchaserName, &v.RecipientName, &v.RecipientEmail, &v.Message, &v.Locale, &v.ExpiresOn, &v.CreatedAt)
+	v.Balance.Currency = v.Value.Currency
+	return v, err
+}
+
+// IssueVoucher creates a gift voucher with a new random code. A service
+// voucher is worth the service's current price; a value voucher without a
+// currency is in the currency set with SetCurrency.
+func (s *Store) IssueVoucher(ctx context.Context, req models.VoucherRequest) (*models.GiftVoucher, error) {
+	v := models.GiftVoucher{
+		ServiceID:      req.ServiceID,
+		Value:          models.NewMoney(req.Amount, req.Currency),
+		PurchaserName:  req.PurchaserName,
+		RecipientName:  req.RecipientName,
+		RecipientEmail: req.RecipientEmail,
+		Message:        req.Message,
+		Locale:         string(i18n.Parse(req.Locale)),
+		ExpiresOn:      req.ExpiresOn,
+		CreatedAt:      s.clock.Now().UTC(),
+	}
+	if v.Value.Currency == "" {
+		v.Value.Currency = s.currency
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var serviceID any
+	if v.ServiceID != 0 {
+		err = tx.QueryRowContext(ctx, "SELECT name, price_cents, currency FROM massage_types WHERE id = ?", v.ServiceID).
+			Scan(&v.ServiceName, &v.Value.Amount, &v.Value.Currency)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrServiceNotFound
+			}
+			return nil, fmt.Errorf("failed to get service %d: %v", v.ServiceID, err)
+		}
+		serviceID = v.ServiceID
+	}
+	v.Balance = v.Value
+
+	// Codes are random, so a clash is unlikely but not impossible
+	for attempt := 0; v.Code == ""; attempt++ {
+		if attempt == 5 {
+			return nil, errors.New("failed to generate a unique voucher code")
+		}
+		code, err := generateVoucherCode()
+		if err != nil {
+			return nil, err
+		}
+		var exists bool
+		if err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM gift_vouchers WHERE code = ?)", code).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check voucher code: %v", err)
+		}
+		if !exists {
+			v.Code = code
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO gift_vouchers (code, service_id, service_name, amount_cents, balance_cents, currency,
+		                           purchaser_name, recipient_name, recipient_email, message, locale, expires_on, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, v.Code, serviceID, v.ServiceName, v.Value.Amount, v.Balance.Amount, v.Value.Currency,
+		v.PurchaserName, v.RecipientName, v.RecipientEmail, v.Message, v.Locale, v.ExpiresOn, formatTimestamp(v.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gift voucher: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gift voucher ID: %v", err)
+	}
+	v.ID = int(id)
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return &v, nil
+}
+
+// ListVouchers returns all gift vouchers, newest first
+func (s *Store) ListVouchers(ctx context.Context) ([]models.GiftVoucher, error) {
+	rows, err := s.db.QueryContext(ctx, voucherQuery+"ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query gift vouchers: %v", err)
+	}
+	defer rows.Close()
+
+	vouchers := []models.GiftVoucher{}
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan gift voucher: %v", err)
+		}
+		vouchers = append(vouchers, v)
+	}
+	return vouchers, rows.Err()
+}
+
+// GetVoucher returns the gift voucher with the given code
+func (s *Store) GetVoucher(ctx context.Context, code string) (*models.GiftVoucher, error) {
+	v, err := scanVoucher(s.db.QueryRowContext(ctx, voucherQuery+"WHERE code = ?", NormalizeVoucherCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVoucherNotFound
+		}
+		return nil, fmt.Errorf("failed to get gift voucher: %v", err)
+	}
+	return &v, nil
+}
+
+// findVoucher looks up a gift voucher and checks that it can pay for a
+// booking of the service, in currency, today
+func (s *Store) findVoucher(ctx context.Context, q queryRower, code string, serviceID int, currency string) (*models.GiftVoucher, error) {
+	v, err := scanVoucher(q.QueryRowContext(ctx, voucherQuery+"WHERE code = ?", NormalizeVoucherCode(code)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVoucherNotFound
+		}
+		return nil, fmt.Errorf("failed to get gift voucher: %v", err)
+	}
+
+	if v.ExpiresOn != "" && s.today() > v.ExpiresOn {
+		return nil, ErrVoucherExpired
+	}
+	if v.Balance.Amount <= 0 {
+		return nil, ErrVoucherUsedUp
+	}
+	if (v.ServiceID != 0 && v.ServiceID != serviceID) || v.Value.Currency != currency {
+		return nil, ErrVoucherNotApplicable
+	}
+	return &v, nil
+}
+
+// redeemVoucher takes amount off the voucher's balance; a service voucher
+// is used up by its one booking whatever the service costs by then
+func redeemVoucher(ctx context.Context, ex execer, v *models.GiftVoucher, amount int64) error {
+	balance := v.Balance.Amount - amount
+	if v.ServiceID != 0 {
+		balance = 0
+	}
+	if _, err := ex.ExecContext(ctx, "UPDATE gift_vouchers SET balance_cents = ? WHERE id = ?", balance, v.ID); err != nil {
+		return fmt.Errorf("failed to redeem gift voucher %s: %v", v.Code, err)
+	}
+	return nil
+}
+
+// restoreVoucher gives back what a cancelled booking took from its gift
+// voucher, never raising the balance above the voucher's value
+func restoreVoucher(ctx context.Context, ex execer, bookingID int) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE gift_vouchers
+		SET balance_cents = MIN(amount_cents, balance_cents + (SELECT voucher_cents FROM bookings WHERE id = ?))
+		WHERE id = (SELECT voucher_id FROM bookings WHERE id = ?)
+	`, bookingID, bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to restore gift voucher of booking %d: %v", bookingID, err)
+	}
+	return nil
+}
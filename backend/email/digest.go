@@ -0,0 +1,115 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"massage-booking/backend/clock"
+	"massage-booking/backend/models"
+)
+
+// DefaultDigestTime is used when DIGEST_TIME is not set
+const DefaultDigestTime = "18:00"
+
+// ParseDigestTime validates a business-timezone time of day such as "18:00".
+// It returns "" when the digest is disabled with "none".
+func ParseDigestTime(value string) (string, error) {
+	if value == "" {
+		value = DefaultDigestTime
+	}
+	if value == "none" {
+		return "", nil
+	}
+	if _, err := time.Parse("15:04", value); err != nil {
+		return "", fmt.Errorf("invalid digest time %q, expected HH:MM", value)
+	}
+	return value, nil
+}
+
+// DigestStore is the persistence the daily digest needs
+type DigestStore interface {
+	ListBookingsOn(ctx context.Context, date string) ([]models.BookingDetail, error)
+	DigestSent(ctx context.Context, date string) (bool, error)
+	RecordDigestSent(ctx context.Context, date string) error
+}
+
+// DigestSender delivers the staff digest
+type DigestSender interface {
+	SendStaffDigest(ctx context.Context, to, date string, bookings []models.BookingDetail) error
+}
+
+// Digest emails the staff the next day's schedule once a day
+type Digest struct {
+	store  DigestStore
+	sender DigestSender
+	clock  clock.Clock
+	loc    *time.Location
+	to     string
+	at     string // HH:MM in loc
+}
+
+// NewDigest creates a digest job that emails to the schedule for tomorrow
+// every day at the time at (HH:MM in loc)
+func NewDigest(store DigestStore, sender DigestSender, clk clock.Clock, loc *time.Location, to, at string) *Digest {
+	return &Digest{store: store, sender: sender, clock: clk, loc: loc, to: to, at: at}
+}
+
+// SendDue sends tomorrow's digest if today's digest time has passed and it
+// has not been sent yet. A failed send is retried on the next call.
+func (d *Digest) SendDue(ctx context.Context) (bool, error) {
+	now := d.clock.Now().In(d.loc)
+	today := now.Format("2006-01-02")
+	due, err := clock.LocalTime(today, d.at, d.loc)
+	if err != nil {
+		return false, err
+	}
+	if now.Before(due) {
+		return false, nil
+	}
+
+	tomorrow := now.AddDate(0, 0, 1).Format("2006-01-02")
+	sent, err := d.store.DigestSent(ctx, tomorrow)
+	if err != nil || sent {
+		return false, err
+	}
+
+	bookings, err := d.store.ListBookingsOn(ctx, tomorrow)
+	if err != nil {
+		return false, err
+	}
+	if err := d.sender.SendStaffDigest(ctx, d.to, tomorrow, bookings); err != nil {
+		return false, fmt.Errorf("failed to send digest for %s: %v", tomorrow, err)
+	}
+	if err := d.store.RecordDigestSent(ctx, tomorrow); err != nil {
+		return false, err
+	}
+
+	log.Printf("Sent staff digest for %s with %d bookings to %s", tomorrow, len(bookings), d.to)
+	return true, nil
+}
+
+// Start checks for a due digest every minute until ctx is cancelled and
+// marks wg done once it has exited
+func (d *Digest) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(1 * time.Minute)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("Stopped staff digest job")
+				return
+			case <-ticker.C:
+				if _, err := d.SendDue(context.WithoutCancel(ctx)); err != nil {
+					log.Printf("Error sending staff digest: %v", err)
+				}
+			}
+		}
+	}()
+	log.Printf("Started staff digest job at %s daily", d.at)
+}
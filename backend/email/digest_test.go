@@ -0,0 +1,62 @@
+package email
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// fakeDigestSender records the date and booking references of each digest
+type fakeDigestSender struct {
+	sent []string
+}
+
+func (f *fakeDigestSender) SendStaffDigest(ctx context.Context, to, date string, bookings []models.BookingDetail) error {
+	f.sent = append(f.sent, date)
+	for _, b := range bookings {
+		f.sent = append(f.sent, b.Reference)
+	}
+	return nil
+}
+
+func TestDigestSendsTomorrowsScheduleOnce(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-11", false)
+	sender := &fakeDigestSender{}
+	digest := NewDigest(store, sender, clk, store.Location(), "owner@example.com", "18:00")
+
+	// Before the digest time
+	if sent, err := digest.SendDue(ctx); err != nil || sent {
+		t.Fatalf("expected no digest before 18:00, got %v, %v", sent, err)
+	}
+
+	clk.Set(time.Date(2025, 3, 10, 18, 0, 0, 0, store.Location()))
+	if sent, err := digest.SendDue(ctx); err != nil || !sent {
+		t.Fatalf("expected the digest at 18:00, got %v, %v", sent, err)
+	}
+	clk.Advance(time.Minute)
+	if sent, err := digest.SendDue(ctx); err != nil || sent {
+		t.Fatalf("expected the digest to be sent once, got %v, %v", sent, err)
+	}
+
+	if len(sender.sent) != 2 || sender.sent[0] != "2025-03-11" || sender.sent[1] != booking.Reference {
+		t.Errorf("expected the 2025-03-11 schedule with %s, got %v", booking.Reference, sender.sent)
+	}
+}
+
+func TestParseDigestTime(t *testing.T) {
+	if at, err := ParseDigestTime(""); err != nil || at != DefaultDigestTime {
+		t.Errorf("default = %q, %v", at, err)
+	}
+	if at, err := ParseDigestTime("none"); err != nil || at != "" {
+		t.Errorf("none = %q, %v", at, err)
+	}
+	for _, bad := range []string{"6pm", "25:00", "18"} {
+		if _, err := ParseDigestTime(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
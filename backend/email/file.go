@@ -0,0 +1,80 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes each message as an .eml file into a drop directory
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer creates a file-drop transport, creating dir if needed
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("email drop directory is not configured")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create email drop directory %s: %v", dir, err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+// Name returns the transport name
+func (m *FileMailer) Name() string {
+	return TransportFile
+}
+
+// Check verifies that the drop directory still exists
+func (m *FileMailer) Check(ctx context.Context) error {
+	info, err := os.Stat(m.dir)
+	if err != nil {
+		return fmt.Errorf("email drop directory unavailable: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("email drop path %s is not a directory", m.dir)
+	}
+	return nil
+}
+
+// Send writes the message to a new file named after the time and recipient
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), safeFileName(msg.To))
+	path := filepath.Join(m.dir, name)
+
+	// Write to a temporary name first so readers never see a partial message
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write email file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write email file: %v", err)
+	}
+
+	log.Printf("Email to %s saved to %s", msg.To, path)
+	return nil
+}
+
+// safeFileName replaces characters that are not safe in file names
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
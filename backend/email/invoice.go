@@ -0,0 +1,161 @@
+package email
+
+import (
+	"strconv"
+
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+	"massage-booking/backend/pdf"
+)
+
+// invoicePDFKeys are the catalog keys printed on a PDF invoice
+var invoicePDFKeys = []string{
+	"invoice.title", "invoice.receipt", "invoice.number", "invoice.issued_on", "email.reference",
+	"invoice.seller", "invoice.buyer", "invoice.registry_code", "invoice.vat_number", "invoice.bank_account",
+	"invoice.description", "invoice.quantity", "invoice.unit_price", "invoice.amount", "invoice.appointment",
+	"invoice.discount", "invoice.package", "invoice.vat_rate", "invoice.net", "invoice.vat", "invoice.gross",
+	"invoice.paid_voucher", "invoice.paid_online", "invoice.due", "invoice.paid_in_full", "invoice.how_to_pay",
+	"invoice.prices_include_vat", "invoice.prices_exclude_vat",
+}
+
+// invoiceTitle names the document: a receipt when nothing is left to pay
+func invoiceTitle(inv *models.Invoice, locale i18n.Locale) string {
+	if inv.IsReceipt() {
+		return locale.T("invoice.receipt")
+	}
+	return locale.T("invoice.title")
+}
+
+// pdfLocale returns the locale to print a PDF invoice in. The standard PDF
+// fonts have no Cyrillic, so languages they cannot show fall back to English.
+func pdfLocale(locale i18n.Locale) i18n.Locale {
+	for _, key := range invoicePDFKeys {
+		if !pdf.Encodable(locale.T(key)) {
+			return i18n.English
+		}
+	}
+	return locale
+}
+
+// Columns of the PDF invoice, in points from the left edge
+const (
+	pdfLeft      = 50.0
+	pdfRight     = pdf.PageWidth - 50
+	pdfBuyer     = 320.0 // buyer details
+	pdfQuantity  = 360.0 // right edge of the quantity column
+	pdfUnitPrice = 450.0 // right edge of the unit price column and total labels
+)
+
+// RenderInvoicePDF lays out an invoice as a one-page A4 PDF in the booking's
+// locale, or English if the PDF fonts cannot show it
+func RenderInvoicePDF(inv *models.Invoice) []byte {
+	locale := pdfLocale(i18n.Parse(inv.Locale))
+	price := func(m models.Money) string {
+		return locale.FormatMoney(m.Amount, m.Currency)
+	}
+	title := invoiceTitle(inv, locale)
+
+	doc := pdf.New(title + " " + inv.Number)
+	doc.Text(pdfLeft, 70, 16, pdf.Bold, inv.Seller.Name)
+	doc.TextRight(pdfRight, 70, 22, pdf.Bold, title)
+
+	y := 100.0
+	for _, row := range [][2]string{
+		{locale.T("invoice.number"), inv.Number},
+		{locale.T("invoice.issued_on"), locale.FormatDateString(inv.IssuedOn)},
+		{locale.T("email.reference"), inv.Reference},
+	} {
+		doc.TextRight(pdfUnitPrice, y, 9, pdf.Regular, row[0])
+		doc.TextRight(pdfRight, y, 9, pdf.Bold, row[1])
+		y += 14
+	}
+
+	// Seller on the left, buyer on the right
+	y += 20
+	doc.Text(pdfLeft, y, 10, pdf.Bold, locale.T("invoice.seller"))
+	doc.Text(pdfBuyer, y, 10, pdf.Bold, locale.T("invoice.buyer"))
+	sellerLines := []string{inv.Seller.Name, inv.Seller.Address}
+	if inv.Seller.RegistryCode != "" {
+		sellerLines = append(sellerLines, locale.T("invoice.registry_code", inv.Seller.RegistryCode))
+	}
+	if inv.Seller.VATNumber != "" {
+		sellerLines = append(sellerLines, locale.T("invoice.vat_number", inv.Seller.VATNumber))
+	}
+	sellerLines = append(sellerLines, inv.Seller.Email)
+	buyerY := y
+	for _, line := range []string{inv.BuyerName, inv.BuyerEmail} {
+		buyerY += 14
+		doc.Text(pdfBuyer, buyerY, 10, pdf.Regular, line)
+	}
+	for _, line := range sellerLines {
+		if line != "" {
+			y += 14
+			doc.Text(pdfLeft, y, 10, pdf.Regular, line)
+		}
+	}
+
+	// Line items
+	y = max(y, buyerY) + 40
+	doc.Text(pdfLeft, y, 9, pdf.Bold, locale.T("invoice.description"))
+	doc.TextRight(pdfQuantity, y, 9, pdf.Bold, locale.T("invoice.quantity"))
+	doc.TextRight(pdfUnitPrice, y, 9, pdf.Bold, locale.T("invoice.unit_price"))
+	doc.TextRight(pdfRight, y, 9, pdf.Bold, locale.T("invoice.amount"))
+	doc.Line(pdfLeft, y+6, pdfRight, y+6, 0.75)
+	y += 6
+	for _, line := range inv.Lines {
+		y += 18
+		description := line.Description
+		switch line.Kind {
+		case models.InvoiceLineDiscount:
+			description = locale.T("invoice.discount", line.Description)
+		case models.InvoiceLinePackage:
+			description = locale.T("invoice.package")
+		}
+		doc.Text(pdfLeft, y, 10, pdf.Regular, description)
+		doc.TextRight(pdfQuantity, y, 10, pdf.Regular, strconv.Itoa(line.Quantity))
+		doc.TextRight(pdfUnitPrice, y, 10, pdf.Regular, price(line.UnitPrice))
+		doc.TextRight(pdfRight, y, 10, pdf.Regular, price(line.Amount))
+		if line.Kind == models.InvoiceLineService {
+			y += 12
+			doc.Text(pdfLeft, y, 8, pdf.Regular, locale.T("invoice.appointment", locale.FormatDateString(inv.ServiceDate), inv.TimeSlot))
+		}
+	}
+	doc.Line(pdfLeft, y+8, pdfRight, y+8, 0.5)
+
+	// Totals with the VAT breakdown, then what has been paid and what is due
+	y += 8
+	total := func(label, amount string, font pdf.Font) {
+		y += 16
+		doc.TextRight(pdfUnitPrice, y, 10, font, label)
+		doc.TextRight(pdfRight, y, 10, font, amount)
+	}
+	for _, vat := range inv.VAT {
+		total(locale.T("invoice.net")+" ("+locale.T("invoice.vat_rate")+" "+vat.Rate.String()+")", price(vat.Net), pdf.Regular)
+		total(locale.T("invoice.vat")+" "+vat.Rate.String(), price(vat.Tax), pdf.Regular)
+	}
+	total(locale.T("invoice.gross"), price(inv.Gross), pdf.Bold)
+	if inv.PaidVoucher.Amount != 0 {
+		total(locale.T("invoice.paid_voucher", inv.VoucherCode), "-"+price(inv.PaidVoucher), pdf.Regular)
+	}
+	if inv.PaidOnline.Amount != 0 {
+		total(locale.T("invoice.paid_online"), "-"+price(inv.PaidOnline), pdf.Regular)
+	}
+	total(locale.T("invoice.due"), price(inv.Due), pdf.Bold)
+
+	y += 40
+	if inv.IsReceipt() {
+		doc.Text(pdfLeft, y, 10, pdf.Regular, locale.T("invoice.paid_in_full"))
+	} else {
+		doc.Text(pdfLeft, y, 10, pdf.Regular, locale.T("invoice.how_to_pay"))
+		if inv.Seller.BankAccount != "" {
+			y += 14
+			doc.Text(pdfLeft, y, 10, pdf.Regular, locale.T("invoice.bank_account", inv.Seller.BankAccount))
+		}
+	}
+	footnote := "invoice.prices_exclude_vat"
+	if inv.PricesIncludeTax {
+		footnote = "invoice.prices_include_vat"
+	}
+	doc.Text(pdfLeft, pdf.PageHeight-50, 8, pdf.Regular, locale.T(footnote))
+	return doc.Bytes()
+}
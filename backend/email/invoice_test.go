@@ -0,0 +1,45 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+func TestSenderAttachesInvoice(t *testing.T) {
+	store, _ := newTestStore(t)
+	store.SetInvoiceSettings(database.InvoiceSettings{Seller: models.Seller{Name: "Salon OÜ"}})
+	booking := bookLatestSlot(t, store, "2025-03-10", false)
+
+	recorder := NewMemoryMailer()
+	sender := NewSender(recorder, newTestRenderer(t), &EmailConfig{FromName: "Salon", FromEmail: "salon@example.com"})
+	sender.AttachInvoices(store)
+	if err := sender.SendConfirmationEmail(context.Background(), booking); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := recorder.Messages()
+	if len(msgs) != 1 || len(msgs[0].Attachments) != 2 {
+		t.Fatalf("expected one message with a calendar event and an invoice, got %+v", msgs)
+	}
+	invoice := msgs[0].Attachments[1]
+	if invoice.Filename != "INV-000001.pdf" || invoice.ContentType != "application/pdf" {
+		t.Errorf("unexpected invoice attachment %q (%s)", invoice.Filename, invoice.ContentType)
+	}
+	if !strings.HasPrefix(string(invoice.Data), "%PDF-") || !strings.Contains(string(invoice.Data), "(Salon O\xdc)") {
+		t.Error("attachment is not the invoice PDF")
+	}
+}
+
+func TestInvoicePDFLocale(t *testing.T) {
+	if got := pdfLocale(i18n.Parse("et")); got != i18n.Parse("et") {
+		t.Errorf("Estonian can be printed, got %v", got)
+	}
+	if got := pdfLocale(i18n.Parse("ru")); got != i18n.English {
+		t.Errorf("Russian should fall back to English, got %v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package email
+
+import (
+	"context"
+	"fmt"
+)
+
+// Mailer is an email transport
+type Mailer interface {
+	// Name identifies the transport, e.g. "smtp" or "file"
+	Name() string
+	// Send delivers a message; an error means it should be retried
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Checker is implemented by transports that can verify they are reachable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Transport names accepted by EMAIL_TRANSPORT
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+	TransportLog    = "log"
+)
+
+// NewMailer creates the transport selected by config.Transport
+func NewMailer(config *EmailConfig) (Mailer, error) {
+	switch config.Transport {
+	case TransportSMTP:
+		return NewSMTPMailer(config)
+	case TransportFile:
+		return NewFileMailer(config.DropDir)
+	case TransportMemory:
+		return NewMemoryMailer(), nil
+	case TransportLog:
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown email transport %q", config.Transport)
+	}
+}
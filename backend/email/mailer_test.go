@@ -0,0 +1,189 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+func testMessage() *Message {
+	return &Message{
+		FromName:  "Massage Booking Team",
+		FromEmail: "noreply@example.com",
+		To:        "jane@example.com",
+		Subject:   "Booking Confirmation - BK-20250310-001",
+		HTMLBody:  "<p>Hello</p>",
+	}
+}
+
+func TestNewMailerSelectsTransport(t *testing.T) {
+	dir := t.TempDir()
+	for transport, want := range map[string]string{
+		TransportSMTP:   TransportSMTP,
+		TransportFile:   TransportFile,
+		TransportMemory: TransportMemory,
+		TransportLog:    TransportLog,
+	} {
+		m, err := NewMailer(&EmailConfig{Transport: transport, SMTPSecurity: SecurityStartTLS, DropDir: dir})
+		if err != nil {
+			t.Fatalf("NewMailer(%s): %v", transport, err)
+		}
+		if m.Name() != want {
+			t.Errorf("NewMailer(%s) returned %s transport", transport, m.Name())
+		}
+	}
+
+	if _, err := NewMailer(&EmailConfig{Transport: "pigeon"}); err == nil {
+		t.Error("expected error for unknown transport")
+	}
+}
+
+func TestFileMailerWritesMessage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "drop")
+	m, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v (%v)", files, err)
+	}
+	if !strings.HasSuffix(files[0], "-jane@example.com.eml") {
+		t.Errorf("unexpected file name %s", files[0])
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Subject: Booking Confirmation - BK-20250310-001\r\n") {
+		t.Errorf("file does not contain the message:\n%s", data)
+	}
+}
+
+func TestSenderDeliversThroughMailer(t *testing.T) {
+	recorder := NewMemoryMailer()
+	sender := NewSender(recorder, newTestRenderer(t), &EmailConfig{FromName: "Salon", FromEmail: "salon@example.com"})
+
+	booking := &models.BookingDetail{Reference: "BK-20250310-001", Email: "jane@example.com", Date: "2025-03-10"}
+	if err := sender.SendConfirmationEmail(context.Background(), booking); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := recorder.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected one message, got %d", len(msgs))
+	}
+	if msgs[0].To != "jane@example.com" || msgs[0].FromEmail != "salon@example.com" || !strings.Contains(msgs[0].Subject, "BK-20250310-001") {
+		t.Errorf("unexpected message %+v", msgs[0])
+	}
+
+	if mode, err := sender.CheckTransport(context.Background()); mode != TransportMemory || err != nil {
+		t.Errorf("CheckTransport = %q, %v", mode, err)
+	}
+}
+
+// fakeSMTPServer accepts one unauthenticated, unencrypted SMTP session and returns the transcript
+func fakeSMTPServer(t *testing.T) (addr string, transcript <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	out := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var log strings.Builder
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			log.WriteString(line)
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					reply("250 queued")
+				}
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "DATA":
+				inData = true
+				reply("354 go ahead")
+			case "QUIT":
+				reply("221 bye")
+				out <- log.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		out <- log.String()
+	}()
+
+	return ln.Addr().String(), out
+}
+
+func TestSMTPMailerWithoutAuth(t *testing.T) {
+	addr, transcript := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	m, err := NewSMTPMailer(&EmailConfig{SMTPHost: host, SMTPPort: port, SMTPSecurity: SecurityNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := <-transcript
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<jane@example.com>", "<p>Hello</p>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("transcript missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "AUTH") || strings.Contains(got, "STARTTLS") {
+		t.Errorf("expected no AUTH or STARTTLS:\n%s", got)
+	}
+}
+
+func TestSMTPMailerRequiresSTARTTLS(t *testing.T) {
+	addr, _ := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	m, err := NewSMTPMailer(&EmailConfig{SMTPHost: host, SMTPPort: port, SMTPSecurity: SecurityStartTLS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), testMessage()); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected STARTTLS error, got %v", err)
+	}
+}
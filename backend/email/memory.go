@@ -0,0 +1,63 @@
+package email
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// MemoryMailer records messages instead of sending them; intended for tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryMailer creates an empty in-memory recorder
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Name returns the transport name
+func (m *MemoryMailer) Name() string {
+	return TransportMemory
+}
+
+// Send records a copy of the message
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns the recorded messages in send order
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset discards all recorded messages
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+// LogMailer writes a summary of each message to the log; the fallback when no transport is configured
+type LogMailer struct{}
+
+// Name returns the transport name
+func (LogMailer) Name() string {
+	return TransportLog
+}
+
+// Send logs the message recipient and subject
+func (LogMailer) Send(ctx context.Context, msg *Message) error {
+	log.Printf("=== EMAIL NOTIFICATION ===")
+	log.Printf("To: %s", msg.To)
+	log.Printf("Subject: %s", msg.Subject)
+	log.Printf("Body: %d bytes of HTML (set EMAIL_TRANSPORT=file to keep a copy)", len(msg.HTMLBody))
+	log.Printf("=== END EMAIL ===")
+	return nil
+}
@@ -0,0 +1,228 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Attachment is a file sent alongside the message body
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an outgoing email with a plain-text and an HTML alternative
+type Message struct {
+	FromName  string
+	FromEmail string
+	To        string
+	ReplyTo   string // optional
+	Subject   string
+	TextBody  string
+	HTMLBody  string
+
+	// Attachments are optional; when present the body is wrapped in multipart/mixed
+	Attachments []Attachment
+
+	// Date and MessageID are filled in by Bytes when empty
+	Date      time.Time
+	MessageID string
+}
+
+// Bytes renders the message as RFC 5322 / MIME multipart/alternative, wrapped
+// in multipart/mixed when there are attachments.
+// Header values are RFC 2047 encoded and any CR or LF in them is rejected,
+// so user-supplied values cannot inject extra headers.
+func (m *Message) Bytes() ([]byte, error) {
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	if m.MessageID == "" {
+		m.MessageID = newMessageID(m.FromEmail)
+	}
+
+	from, err := formatAddress(m.FromName, m.FromEmail)
+	if err != nil {
+		return nil, fmt.Errorf("invalid From: %v", err)
+	}
+	to, err := formatAddress("", m.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid To: %v", err)
+	}
+	if err := checkHeaderValue(m.Subject); err != nil {
+		return nil, fmt.Errorf("invalid Subject: %v", err)
+	}
+	if err := checkHeaderValue(m.MessageID); err != nil {
+		return nil, fmt.Errorf("invalid Message-ID: %v", err)
+	}
+
+	headers := [][2]string{
+		{"Date", m.Date.Format(time.RFC1123Z)},
+		{"From", from},
+	}
+	if m.ReplyTo != "" {
+		replyTo, err := formatAddress("", m.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Reply-To: %v", err)
+		}
+		headers = append(headers, [2]string{"Reply-To", replyTo})
+	}
+	headers = append(headers,
+		[2]string{"To", to},
+		[2]string{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		[2]string{"Message-ID", m.MessageID},
+		[2]string{"MIME-Version", "1.0"},
+	)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.SetBoundary(boundaryFor(m.MessageID)); err != nil {
+		return nil, err
+	}
+	if err := writeTextPart(mw, "text/plain; charset=utf-8", m.TextBody); err != nil {
+		return nil, err
+	}
+	if err := writeTextPart(mw, "text/html; charset=utf-8", m.HTMLBody); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	contentType := fmt.Sprintf("multipart/alternative; boundary=%q", mw.Boundary())
+
+	if len(m.Attachments) > 0 {
+		var mixed bytes.Buffer
+		mx := multipart.NewWriter(&mixed)
+		if err := mx.SetBoundary(boundaryFor("mixed" + m.MessageID)); err != nil {
+			return nil, err
+		}
+		part, err := mx.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		part.Write(body.Bytes())
+		for _, a := range m.Attachments {
+			if err := writeAttachment(mx, a); err != nil {
+				return nil, err
+			}
+		}
+		if err := mx.Close(); err != nil {
+			return nil, err
+		}
+		body = mixed
+		contentType = fmt.Sprintf("multipart/mixed; boundary=%q", mx.Boundary())
+	}
+
+	var out bytes.Buffer
+	for _, h := range headers {
+		fmt.Fprintf(&out, "%s: %s\r\n", h[0], h[1])
+	}
+	fmt.Fprintf(&out, "Content-Type: %s\r\n\r\n", contentType)
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// writeAttachment adds a base64-encoded attachment part to a multipart body
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	if err := checkHeaderValue(a.Filename); err != nil {
+		return fmt.Errorf("invalid attachment name: %v", err)
+	}
+	if err := checkHeaderValue(a.ContentType); err != nil {
+		return fmt.Errorf("invalid attachment type: %v", err)
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", a.ContentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	// Base64 lines are limited to 76 characters (RFC 2045)
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(part, "%s\r\n", encoded[:76]); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = fmt.Fprintf(part, "%s\r\n", encoded)
+	return err
+}
+
+// writeTextPart adds a quoted-printable text part to a multipart body
+func writeTextPart(mw *multipart.Writer, contentType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// formatAddress validates an address and renders it with an encoded display name
+func formatAddress(name, address string) (string, error) {
+	if err := checkHeaderValue(name); err != nil {
+		return "", err
+	}
+	if err := checkHeaderValue(address); err != nil {
+		return "", err
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if name != "" {
+		parsed.Name = name
+	}
+	return parsed.String(), nil
+}
+
+// checkHeaderValue rejects values that could terminate a header line
+func checkHeaderValue(value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header value contains a line break")
+	}
+	return nil
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(fromEmail string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromEmail, "@"); at >= 0 && at < len(fromEmail)-1 {
+		domain = fromEmail[at+1:]
+	}
+
+	var random [12]byte
+	rand.Read(random[:])
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random[:]), domain)
+}
+
+// boundaryFor derives the multipart boundary from the Message-ID so output is reproducible
+func boundaryFor(messageID string) string {
+	sum := sha256.Sum256([]byte(messageID))
+	return "mb-" + hex.EncodeToString(sum[:12])
+}
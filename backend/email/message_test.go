@@ -0,0 +1,246 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// goldenBooking is the booking rendered into the golden messages
+var goldenBooking = &models.BookingDetail{
+	ID:          1,
+	Reference:   "BK-20250310-001",
+	ClientName:  "Jane Doe",
+	Email:       "jane@example.com",
+	Phone:       "+372 5123 4567",
+	ServiceID:   1,
+	ServiceName: "Swedish Massage",
+	Duration:    60,
+	Price:       models.NewMoney(5000, "EUR"),
+	Date:        "2025-03-10",
+	TimeSlot:    "10:00",
+}
+
+// checkGolden compares got with testdata/name, rewriting it when -update is set
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("message does not match %s (run with -update to accept):\n%s", path, got)
+	}
+}
+
+// testBranding matches the default branding
+var testBranding = Branding{
+	SalonName:    "Massage Booking Team",
+	PrimaryColor: "#4CAF50",
+	AccentColor:  "#1976d2",
+}
+
+// newTestRenderer returns a renderer using the embedded templates
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+
+	r, err := NewRenderer(testBranding, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func goldenMessage(t *testing.T) *Message {
+	t.Helper()
+
+	rendered, err := newTestRenderer(t).Render(TemplateConfirmation, goldenBooking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Message{
+		FromName:  "Massage Booking Team",
+		FromEmail: "noreply@massagebooking.com",
+		To:        goldenBooking.Email,
+		ReplyTo:   "bookings@massagebooking.com",
+		Subject:   rendered.Subject,
+		TextBody:  rendered.Text,
+		HTMLBody:  rendered.HTML,
+		Date:      time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC),
+		MessageID: "<1741593600.golden@massagebooking.com>",
+	}
+}
+
+func TestConfirmationMessageGolden(t *testing.T) {
+	data, err := goldenMessage(t).Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "confirmation.golden", data)
+}
+
+func TestMessageEncodesNonASCIIHeaders(t *testing.T) {
+	msg := goldenMessage(t)
+	msg.FromName = "Massaažistuudio Õie"
+	msg.Subject = "Broneeringu kinnitus – BK-20250310-001"
+	msg.ReplyTo = ""
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "encoded_headers.golden", data)
+
+	head := string(data[:strings.Index(string(data), "\r\n\r\n")])
+	for _, line := range strings.Split(head, "\r\n") {
+		for _, r := range line {
+			if r > 127 {
+				t.Fatalf("header line contains non-ASCII: %q", line)
+			}
+		}
+	}
+	if strings.Contains(head, "Reply-To") {
+		t.Error("expected no Reply-To header when unset")
+	}
+}
+
+func TestMessageRejectsHeaderInjection(t *testing.T) {
+	tests := map[string]func(*Message){
+		"subject":  func(m *Message) { m.Subject = "Hi\r\nBcc: victim@example.com" },
+		"to":       func(m *Message) { m.To = "jane@example.com\r\nBcc: victim@example.com" },
+		"reply-to": func(m *Message) { m.ReplyTo = "a@example.com\nBcc: victim@example.com" },
+		"name":     func(m *Message) { m.FromName = "Team\r\nX-Evil: 1" },
+		"bad to":   func(m *Message) { m.To = "not an address" },
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg := goldenMessage(t)
+			modify(msg)
+			if _, err := msg.Bytes(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestMessageFillsDateAndMessageID(t *testing.T) {
+	msg := goldenMessage(t)
+	msg.Date = time.Time{}
+	msg.MessageID = ""
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Date.IsZero() || !strings.HasSuffix(msg.MessageID, "@massagebooking.com>") {
+		t.Errorf("expected Date and Message-ID to be set, got %v %q", msg.Date, msg.MessageID)
+	}
+	if !strings.Contains(string(data), "Message-ID: "+msg.MessageID+"\r\n") {
+		t.Error("Message-ID header missing")
+	}
+}
+
+func TestMessageWithAttachment(t *testing.T) {
+	msg := goldenMessage(t)
+	ics := []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	msg.Attachments = []Attachment{{Filename: "BK-20250310-001.ics", ContentType: "text/calendar; charset=utf-8", Data: ics}}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected multipart/mixed, got %q (%v)", mediaType, err)
+	}
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	first, err := mr.NextPart()
+	if err != nil || !strings.HasPrefix(first.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Fatalf("expected alternative body first, got %v (%v)", first.Header, err)
+	}
+
+	attachment, err := mr.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "BK-20250310-001.ics" {
+		t.Errorf("unexpected attachment name %q", attachment.FileName())
+	}
+	decoded, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if err != nil || !bytes.Equal(decoded, ics) {
+		t.Errorf("attachment content %q (%v)", decoded, err)
+	}
+}
+
+func TestSenderAttachesCalendarEvent(t *testing.T) {
+	recorder := NewMemoryMailer()
+	sender := NewSender(recorder, newTestRenderer(t), &EmailConfig{FromName: "Salon", FromEmail: "salon@example.com"})
+
+	booking := *goldenBooking
+	booking.StartsAt = time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	if err := sender.SendConfirmationEmail(context.Background(), &booking); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := recorder.Messages()
+	if len(msgs) != 1 || len(msgs[0].Attachments) != 1 {
+		t.Fatalf("expected one message with an attachment, got %+v", msgs)
+	}
+	ics := string(msgs[0].Attachments[0].Data)
+	for _, want := range []string{"UID:BK-20250310-001\r\n", "DTSTART:20250310T080000Z\r\n", "DTEND:20250310T090000Z\r\n"} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("attachment missing %q:\n%s", want, ics)
+		}
+	}
+}
+
+func TestStaffEmailUsesStaffLocaleAndRepliesToClient(t *testing.T) {
+	recorder := NewMemoryMailer()
+	sender := NewSender(recorder, newTestRenderer(t), &EmailConfig{FromName: "Salon", FromEmail: "salon@example.com", StaffLocale: "en"})
+
+	booking := *goldenBooking
+	booking.Locale = "et"
+	if err := sender.SendStaffBookingEmail(context.Background(), &booking, "owner@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := recorder.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected one message, got %d", len(msgs))
+	}
+	if msgs[0].To != "owner@example.com" || msgs[0].ReplyTo != booking.Email {
+		t.Errorf("expected mail to the owner with replies to the client, got to %q reply-to %q", msgs[0].To, msgs[0].ReplyTo)
+	}
+	if want := "New booking: Swedish Massage on Monday, March 10, 2025 at 10:00"; msgs[0].Subject != want {
+		t.Errorf("subject = %q, want %q", msgs[0].Subject, want)
+	}
+}
@@ -0,0 +1,202 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"massage-booking/backend/clock"
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+)
+
+// OutboxStore is the persistence the outbox worker needs
+type OutboxStore interface {
+	DueEmails(ctx context.Context, limit int) ([]models.OutboxEmail, error)
+	MarkEmailSent(ctx context.Context, id int) error
+	MarkEmailFailed(ctx context.Context, id int, sendErr error, nextAttemptAt time.Time, dead bool) error
+	MarkEmailSkipped(ctx context.Context, id int, reason string) error
+	GetBookingByID(ctx context.Context, bookingID int) (*models.BookingDetail, error)
+}
+
+// BookingEmailSender delivers the emails queued for a booking
+type BookingEmailSender interface {
+	SendConfirmationEmail(ctx context.Context, booking *models.BookingDetail) error
+	SendReminderEmail(ctx context.Context, booking *models.BookingDetail) error
+	SendCancellationEmail(ctx context.Context, booking *models.BookingDetail) error
+	SendStaffBookingEmail(ctx context.Context, booking *models.BookingDetail, to string) error
+	SendStaffCancellationEmail(ctx context.Context, booking *models.BookingDetail, to string) error
+}
+
+// BookingSMSSender delivers text messages for a booking to an E.164 number
+type BookingSMSSender interface {
+	SendConfirmationSMS(ctx context.Context, booking *models.BookingDetail, to string) error
+	SendReminderSMS(ctx context.Context, booking *models.BookingDetail, to string) error
+}
+
+// errNotNeeded is returned by send when an email no longer applies
+var errNotNeeded = errors.New("not needed")
+
+// WorkerConfig controls polling and retry behaviour of the outbox worker
+type WorkerConfig struct {
+	PollInterval time.Duration // how often to look for due emails
+	BatchSize    int           // maximum emails sent per poll
+	MaxAttempts  int           // attempts before an email is dead-lettered
+	BaseBackoff  time.Duration // delay after the first failure; doubles per attempt
+	MaxBackoff   time.Duration // upper bound on the retry delay
+}
+
+// DefaultWorkerConfig returns the production outbox settings
+func DefaultWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+// Worker delivers queued outbox emails and text messages with exponential backoff
+type Worker struct {
+	store  OutboxStore
+	sender BookingEmailSender
+	sms    BookingSMSSender // nil when SMS is not configured
+	clock  clock.Clock
+	config WorkerConfig
+}
+
+// NewWorker creates an outbox worker; sms may be nil, in which case queued text messages are skipped
+func NewWorker(store OutboxStore, sender BookingEmailSender, sms BookingSMSSender, clk clock.Clock, config WorkerConfig) *Worker {
+	return &Worker{store: store, sender: sender, sms: sms, clock: clk, config: config}
+}
+
+// Start polls the outbox until ctx is cancelled and marks wg done once it has exited.
+// An email being sent when shutdown begins is allowed to finish.
+func (w *Worker) Start(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Println("Stopped email outbox worker")
+				return
+			case <-ticker.C:
+				if err := w.ProcessDue(context.WithoutCancel(ctx)); err != nil {
+					log.Printf("Error processing email outbox: %v", err)
+				}
+			}
+		}
+	}()
+	log.Println("Started email outbox worker")
+}
+
+// ProcessDue sends every email whose next attempt is due
+func (w *Worker) ProcessDue(ctx context.Context) error {
+	emails, err := w.store.DueEmails(ctx, w.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range emails {
+		counter := metrics.EmailsSent
+		if e.Channel == models.ChannelSMS {
+			counter = metrics.SMSSent
+		}
+
+		sendErr := w.send(ctx, e)
+		if errors.Is(sendErr, errNotNeeded) {
+			log.Printf("Skipping %s %d to %s: %v", e.Channel, e.ID, e.Recipient, sendErr)
+			if err := w.store.MarkEmailSkipped(ctx, e.ID, sendErr.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if sendErr == nil {
+			counter.Inc("success")
+			if err := w.store.MarkEmailSent(ctx, e.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		counter.Inc("failure")
+		attempt := e.Attempts + 1
+		dead := attempt >= w.config.MaxAttempts
+		if dead {
+			log.Printf("Giving up on email %d to %s after %d attempts: %v", e.ID, e.Recipient, attempt, sendErr)
+		} else {
+			log.Printf("Email %d to %s failed (attempt %d), will retry: %v", e.ID, e.Recipient, attempt, sendErr)
+		}
+
+		next := w.clock.Now().Add(w.backoff(attempt))
+		if err := w.store.MarkEmailFailed(ctx, e.ID, sendErr, next, dead); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send delivers a single outbox email or text message according to its channel and kind
+func (w *Worker) send(ctx context.Context, e models.OutboxEmail) error {
+	booking, err := w.store.GetBookingByID(ctx, e.BookingID)
+	if err != nil {
+		return err
+	}
+
+	if e.Kind == models.EmailKindBookingReminder {
+		if booking.Status == models.BookingStatusCancelled {
+			return fmt.Errorf("%w: booking cancelled", errNotNeeded)
+		}
+		if !booking.StartsAt.After(w.clock.Now()) {
+			return fmt.Errorf("%w: appointment already started", errNotNeeded)
+		}
+	}
+
+	if e.Channel == models.ChannelSMS {
+		if w.sms == nil {
+			return fmt.Errorf("%w: SMS is not configured", errNotNeeded)
+		}
+		switch e.Kind {
+		case models.EmailKindBookingConfirmation:
+			return w.sms.SendConfirmationSMS(ctx, booking, e.Recipient)
+		case models.EmailKindBookingReminder:
+			return w.sms.SendReminderSMS(ctx, booking, e.Recipient)
+		}
+		return fmt.Errorf("unknown SMS kind %q", e.Kind)
+	}
+
+	switch e.Kind {
+	case models.EmailKindBookingConfirmation:
+		return w.sender.SendConfirmationEmail(ctx, booking)
+	case models.EmailKindBookingReminder:
+		return w.sender.SendReminderEmail(ctx, booking)
+	case models.EmailKindBookingCancellation:
+		return w.sender.SendCancellationEmail(ctx, booking)
+	case models.EmailKindStaffNewBooking:
+		return w.sender.SendStaffBookingEmail(ctx, booking, e.Recipient)
+	case models.EmailKindStaffCancellation:
+		return w.sender.SendStaffCancellationEmail(ctx, booking, e.Recipient)
+	default:
+		return fmt.Errorf("unknown email kind %q", e.Kind)
+	}
+}
+
+// backoff returns the delay before the given retry attempt
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := 1; i < attempt && delay < w.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.config.MaxBackoff {
+		delay = w.config.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,446 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/clock"
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// fakeSender fails until failures is exhausted, then records deliveries
+type fakeSender struct {
+	failures  int
+	delivered []string
+}
+
+func (f *fakeSender) SendConfirmationEmail(ctx context.Context, booking *models.BookingDetail) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("smtp: connection refused")
+	}
+	f.delivered = append(f.delivered, booking.Reference)
+	return nil
+}
+
+func (f *fakeSender) SendReminderEmail(ctx context.Context, booking *models.BookingDetail) error {
+	f.delivered = append(f.delivered, "reminder "+booking.Reference)
+	return nil
+}
+
+func (f *fakeSender) SendCancellationEmail(ctx context.Context, booking *models.BookingDetail) error {
+	f.delivered = append(f.delivered, "cancellation "+booking.Reference)
+	return nil
+}
+
+func (f *fakeSender) SendStaffBookingEmail(ctx context.Context, booking *models.BookingDetail, to string) error {
+	f.delivered = append(f.delivered, "staff booking "+booking.Reference+" to "+to)
+	return nil
+}
+
+func (f *fakeSender) SendStaffCancellationEmail(ctx context.Context, booking *models.BookingDetail, to string) error {
+	f.delivered = append(f.delivered, "staff cancellation "+booking.Reference+" to "+to)
+	return nil
+}
+
+// newOutboxFixture creates a store with one booking and its queued confirmation
+func newOutboxFixture(t *testing.T) (*database.Store, *clock.Fake) {
+	t.Helper()
+
+	store, clk := newTestStore(t)
+	bookLatestSlot(t, store, "2025-03-10", false)
+	return store, clk
+}
+
+// newTestStore creates a seeded in-memory store whose clock starts on 2025-03-10 08:00
+func newTestStore(t *testing.T) (*database.Store, *clock.Fake) {
+	t.Helper()
+
+	ctx := context.Background()
+	loc, err := clock.LoadLocation("Europe/Tallinn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewFake(time.Date(2025, 3, 10, 8, 0, 0, 0, loc))
+	store, err := database.Open(ctx, ":memory:", clk, loc)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Seed(ctx); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+	return store, clk
+}
+
+// bookLatestSlot books the last available slot of service 1 on date
+func bookLatestSlot(t *testing.T, store *database.Store, date string, smsOptIn bool) *models.BookingDetail {
+	t.Helper()
+
+	ctx := context.Background()
+	slots, err := store.GetTimeSlots(ctx, date, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slot models.TimeSlot
+	for _, s := range slots {
+		if s.Available {
+			slot = s
+		}
+	}
+	reservation, err := store.CreateReservation(ctx, slot.ID)
+	if err != nil {
+		t.Fatalf("reserve slot: %v", err)
+	}
+	booking, err := store.CreateBooking(ctx, models.BookingRequest{
+		ReservationID: reservation.ID,
+		ClientName:    "Jane Doe",
+		Email:         "jane@example.com",
+		Phone:         "+372 5123 4567",
+		ServiceID:     slot.ServiceID,
+		Date:          slot.Date,
+		TimeSlot:      slot.Time,
+		SMSOptIn:      smsOptIn,
+	})
+	if err != nil {
+		t.Fatalf("create booking: %v", err)
+	}
+
+	detail, err := store.GetBookingByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return detail
+}
+
+func outboxStatus(t *testing.T, store *database.Store) models.OutboxEmail {
+	t.Helper()
+
+	emails, err := store.ListEmails(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 {
+		t.Fatalf("expected one outbox email, got %d", len(emails))
+	}
+	return emails[0]
+}
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{BatchSize: 10, MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute}
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newOutboxFixture(t)
+	sender := &fakeSender{failures: 1}
+	worker := NewWorker(store, sender, nil, clk, testWorkerConfig())
+
+	if err := worker.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e := outboxStatus(t, store)
+	if e.Status != models.OutboxStatusPending || e.Attempts != 1 || e.LastError == "" {
+		t.Fatalf("expected a pending retry after first failure, got %+v", e)
+	}
+	if want := clk.Now().Add(time.Minute).UTC(); !e.NextAttemptAt.Equal(want) {
+		t.Errorf("expected next attempt at %v, got %v", want, e.NextAttemptAt)
+	}
+
+	// Not due yet
+	clk.Advance(30 * time.Second)
+	if err := worker.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.delivered) != 0 {
+		t.Fatal("email was retried before its backoff elapsed")
+	}
+
+	clk.Advance(30 * time.Second)
+	if err := worker.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e := outboxStatus(t, store); e.Status != models.OutboxStatusSent || e.SentAt == nil {
+		t.Fatalf("expected email to be sent, got %+v", e)
+	}
+	if len(sender.delivered) != 1 {
+		t.Errorf("expected one delivery, got %v", sender.delivered)
+	}
+}
+
+func TestWorkerDeadLettersAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newOutboxFixture(t)
+	sender := &fakeSender{failures: 100}
+	worker := NewWorker(store, sender, nil, clk, testWorkerConfig())
+
+	for i := 0; i < 3; i++ {
+		if err := worker.ProcessDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(time.Hour)
+	}
+
+	e := outboxStatus(t, store)
+	if e.Status != models.OutboxStatusDead || e.Attempts != 3 {
+		t.Fatalf("expected dead letter after 3 attempts, got %+v", e)
+	}
+
+	// Resending puts it back in the queue with a fresh attempt count
+	sender.failures = 0
+	if err := store.ResendEmail(ctx, e.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if e := outboxStatus(t, store); e.Status != models.OutboxStatusSent {
+		t.Fatalf("expected resent email to be delivered, got %+v", e)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	w := NewWorker(nil, nil, nil, nil, testWorkerConfig())
+	for attempt, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 10: 10 * time.Minute} {
+		if got := w.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+// emailsOfKind returns the outbox emails of one kind, newest first
+func emailsOfKind(t *testing.T, store *database.Store, kind string) []models.OutboxEmail {
+	t.Helper()
+
+	emails, err := store.ListEmails(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matching []models.OutboxEmail
+	for _, e := range emails {
+		if e.Kind == kind {
+			matching = append(matching, e)
+		}
+	}
+	return matching
+}
+
+func enqueueReminders(t *testing.T, store *database.Store, offsets []time.Duration) int {
+	t.Helper()
+
+	queued, err := store.EnqueueDueReminders(context.Background(), offsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queued
+}
+
+func TestRemindersAreQueuedOncePerOffset(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-12", false)
+	offsets := []time.Duration{24 * time.Hour, 2 * time.Hour}
+
+	if n := enqueueReminders(t, store, offsets); n != 0 {
+		t.Fatalf("expected no reminders yet, got %d", n)
+	}
+
+	clk.Set(booking.StartsAt.Add(-24 * time.Hour))
+	if n := enqueueReminders(t, store, offsets); n != 1 {
+		t.Fatalf("expected the 24h reminder, got %d", n)
+	}
+	// Already recorded, so running again (or after a restart) queues nothing
+	if n := enqueueReminders(t, store, offsets); n != 0 {
+		t.Fatalf("expected the 24h reminder to be queued once, got %d", n)
+	}
+
+	sender := &fakeSender{}
+	if err := NewWorker(store, sender, nil, clk, testWorkerConfig()).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.delivered) != 2 || sender.delivered[1] != "reminder "+booking.Reference {
+		t.Fatalf("expected confirmation and reminder, got %v", sender.delivered)
+	}
+
+	clk.Set(booking.StartsAt.Add(-2 * time.Hour))
+	if n := enqueueReminders(t, store, offsets); n != 1 {
+		t.Fatalf("expected the 2h reminder, got %d", n)
+	}
+	if got := emailsOfKind(t, store, models.EmailKindBookingReminder); len(got) != 2 {
+		t.Errorf("expected two reminder emails, got %+v", got)
+	}
+}
+
+func TestRemindersBeforeBookingWasMadeAreNotSent(t *testing.T) {
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-10", false)
+	offsets := []time.Duration{24 * time.Hour, 2 * time.Hour}
+
+	// The 24h mark passed before the booking was made
+	if n := enqueueReminders(t, store, offsets); n != 0 {
+		t.Fatalf("expected no reminder for a same-day booking, got %d", n)
+	}
+
+	// The server was down at the 2h mark; only one reminder is sent late
+	clk.Set(booking.StartsAt.Add(-30 * time.Minute))
+	if n := enqueueReminders(t, store, []time.Duration{24 * time.Hour, 2 * time.Hour, time.Hour}); n != 1 {
+		t.Fatalf("expected a single catch-up reminder, got %d", n)
+	}
+}
+
+func TestWorkerSkipsReminderForStartedAppointment(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-12", false)
+
+	clk.Set(booking.StartsAt.Add(-2 * time.Hour))
+	enqueueReminders(t, store, []time.Duration{2 * time.Hour})
+
+	clk.Set(booking.StartsAt.Add(time.Minute))
+	sender := &fakeSender{}
+	if err := NewWorker(store, sender, nil, clk, testWorkerConfig()).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	reminders := emailsOfKind(t, store, models.EmailKindBookingReminder)
+	if len(reminders) != 1 || reminders[0].Status != models.OutboxStatusSkipped {
+		t.Fatalf("expected the reminder to be skipped, got %+v", reminders)
+	}
+	for _, d := range sender.delivered {
+		if d == "reminder "+booking.Reference {
+			t.Error("reminder was delivered after the appointment started")
+		}
+	}
+}
+
+func TestParseReminderOffsets(t *testing.T) {
+	offsets, err := database.ParseReminderOffsets("")
+	if err != nil || len(offsets) != 2 || offsets[0] != 24*time.Hour || offsets[1] != 2*time.Hour {
+		t.Errorf("default offsets = %v, %v", offsets, err)
+	}
+	if offsets, err := database.ParseReminderOffsets("none"); err != nil || offsets != nil {
+		t.Errorf("none = %v, %v", offsets, err)
+	}
+	for _, bad := range []string{"tomorrow", "30s", "-1h"} {
+		if _, err := database.ParseReminderOffsets(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// fakeSMSSender records text messages
+type fakeSMSSender struct {
+	sent []string
+}
+
+func (f *fakeSMSSender) SendConfirmationSMS(ctx context.Context, booking *models.BookingDetail, to string) error {
+	f.sent = append(f.sent, "confirmation "+to)
+	return nil
+}
+
+func (f *fakeSMSSender) SendReminderSMS(ctx context.Context, booking *models.BookingDetail, to string) error {
+	f.sent = append(f.sent, "reminder "+to)
+	return nil
+}
+
+func TestWorkerDeliversSMSForOptedInBookings(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-12", true)
+
+	clk.Set(booking.StartsAt.Add(-2 * time.Hour))
+	enqueueReminders(t, store, []time.Duration{2 * time.Hour})
+
+	sms := &fakeSMSSender{}
+	if err := NewWorker(store, &fakeSender{}, sms, clk, testWorkerConfig()).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sms.sent) != 2 || sms.sent[0] != "confirmation +372 5123 4567" || sms.sent[1] != "reminder +372 5123 4567" {
+		t.Errorf("expected SMS confirmation and reminder, got %v", sms.sent)
+	}
+}
+
+func TestWorkerSkipsSMSWhenNotConfigured(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	bookLatestSlot(t, store, "2025-03-12", true)
+
+	if err := NewWorker(store, &fakeSender{}, nil, clk, testWorkerConfig()).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	texts := emailsOfKind(t, store, models.EmailKindBookingConfirmation)
+	for _, e := range texts {
+		if e.Channel == models.ChannelSMS && e.Status != models.OutboxStatusSkipped {
+			t.Errorf("expected SMS to be skipped, got %+v", e)
+		}
+	}
+}
+
+func TestStaffNotifiedOfBookingAndCancellation(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	store.SetStaffEmail("owner@example.com")
+	booking := bookLatestSlot(t, store, "2025-03-12", false)
+
+	clk.Set(booking.StartsAt.Add(-2 * time.Hour))
+	enqueueReminders(t, store, []time.Duration{2 * time.Hour})
+	if _, err := store.CancelBooking(ctx, booking.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &fakeSender{}
+	if err := NewWorker(store, sender, nil, clk, testWorkerConfig()).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		booking.Reference,
+		"staff booking " + booking.Reference + " to owner@example.com",
+		"cancellation " + booking.Reference,
+		"staff cancellation " + booking.Reference + " to owner@example.com",
+	}
+	if strings.Join(sender.delivered, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected deliveries %v, got %v", want, sender.delivered)
+	}
+	if reminders := emailsOfKind(t, store, models.EmailKindBookingReminder); len(reminders) != 1 || reminders[0].Status != models.OutboxStatusSkipped {
+		t.Errorf("expected the reminder of the cancelled booking to be skipped, got %+v", reminders)
+	}
+}
+
+func TestCancelBooking(t *testing.T) {
+	ctx := context.Background()
+	store, clk := newTestStore(t)
+	booking := bookLatestSlot(t, store, "2025-03-12", false)
+
+	cancelled, err := store.CancelBooking(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != models.BookingStatusCancelled || cancelled.CancelledAt == nil {
+		t.Errorf("expected a cancelled booking, got %+v", cancelled)
+	}
+	if _, err := store.CancelBooking(ctx, booking.ID); !errors.Is(err, database.ErrBookingCancelled) {
+		t.Errorf("expected ErrBookingCancelled, got %v", err)
+	}
+
+	// The slot can be booked again
+	slots, err := store.GetTimeSlots(ctx, booking.Date, booking.ServiceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slot := range slots {
+		if slot.Time == booking.TimeSlot && !slot.Available {
+			t.Error("expected the cancelled slot to be available")
+		}
+	}
+
+	// Appointments that have started cannot be cancelled
+	other := bookLatestSlot(t, store, "2025-03-13", false)
+	clk.Set(other.StartsAt)
+	if _, err := store.CancelBooking(ctx, other.ID); !errors.Is(err, database.ErrBookingStarted) {
+		t.Errorf("expected ErrBookingStarted, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+// ErrUnknownTemplate is returned when previewing a template that does not describe a booking
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// PreviewTemplates lists the booking templates that can be previewed and test-sent
+var PreviewTemplates = []string{
+	TemplateConfirmation, TemplateReminder, TemplateCancellation,
+	TemplateStaffBooking, TemplateStaffCancellation,
+}
+
+// SampleBooking returns a made-up booking for previewing templates, starting
+// at 10:00 the day after now
+func SampleBooking(now time.Time) *models.BookingDetail {
+	day := now.AddDate(0, 0, 1)
+	startsAt := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, now.Location())
+	return &models.BookingDetail{
+		Reference:   "BK-" + startsAt.Format("20060102") + "-001",
+		ClientName:  "Jane Doe",
+		Email:       "jane@example.com",
+		Phone:       "+37251234567",
+		ServiceID:   1,
+		ServiceName: "Swedish Massage",
+		Duration:    60,
+		Price:       models.NewMoney(5000, models.DefaultCurrency),
+		Date:        startsAt.Format("2006-01-02"),
+		TimeSlot:    "10:00",
+		StartsAt:    startsAt,
+		Status:      models.BookingStatusConfirmed,
+		Locale:      string(i18n.Default),
+		CreatedAt:   now,
+	}
+}
+
+// Preview renders a booking template as it would be sent. An empty locale
+// uses the one the real email would use: the booking's for client emails
+// and STAFF_LOCALE for staff emails.
+func (s *Sender) Preview(template string, booking *models.BookingDetail, locale string) (*Rendered, error) {
+	if !slices.Contains(PreviewTemplates, template) {
+		return nil, ErrUnknownTemplate
+	}
+
+	if locale == "" {
+		locale = booking.Locale
+		if template == TemplateStaffBooking || template == TemplateStaffCancellation {
+			locale = s.config.StaffLocale
+		}
+	}
+	return s.renderer.RenderIn(template, booking, i18n.Parse(locale))
+}
+
+// SendPreview renders a booking template like Preview and sends it to an
+// arbitrary address through the configured transport, bypassing the outbox
+func (s *Sender) SendPreview(ctx context.Context, template string, booking *models.BookingDetail, locale, to string) error {
+	rendered, err := s.Preview(template, booking, locale)
+	if err != nil {
+		return err
+	}
+
+	var attachments []Attachment
+	if template == TemplateConfirmation && !booking.StartsAt.IsZero() {
+		attachments = append(attachments, s.calendarAttachment(booking))
+	}
+	return s.deliver(ctx, rendered, to, s.config.ReplyTo, attachments...)
+}
@@ -1,119 +1,231 @@
 package email
 
 import (
-	"fmt"
+	"context"
 	"log"
-	"net/smtp"
 	"os"
 
+	"massage-booking/backend/calendar"
+	"massage-booking/backend/i18n"
 	"massage-booking/backend/models"
 )
 
-// EmailConfig holds SMTP configuration
+// EmailConfig holds email transport configuration
 type EmailConfig struct {
+	Transport    string // smtp, file, memory or log
 	SMTPHost     string
 	SMTPPort     string
 	SMTPUser     string
 	SMTPPassword string
+	SMTPSecurity string // starttls, tls or none
+	DropDir      string // directory used by the file transport
 	FromEmail    string
 	FromName     string
+	ReplyTo      string // optional Reply-To address
+	TemplateDir  string // optional directory whose templates override the embedded ones
+
+	StaffEmail  string // optional address notified of bookings and sent the daily digest
+	StaffLocale string // language of staff emails
+	DigestTime  string // business-timezone time of the daily digest, e.g. 18:00, or "none"
+
+	AttachInvoices bool // attach the invoice PDF to confirmation emails
 }
 
-// GetEmailConfig loads email configuration from environment variables
+// GetEmailConfig loads email configuration from environment variables.
+// Without EMAIL_TRANSPORT, SMTP is used when SMTP_USER is set and emails are
+// only logged otherwise.
 func GetEmailConfig() *EmailConfig {
-	return &EmailConfig{
+	config := &EmailConfig{
+		Transport:    getEnvOrDefault("EMAIL_TRANSPORT", ""),
 		SMTPHost:     getEnvOrDefault("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:     getEnvOrDefault("SMTP_PORT", "587"),
 		SMTPUser:     getEnvOrDefault("SMTP_USER", ""),
 		SMTPPassword: getEnvOrDefault("SMTP_PASS", ""),
+		SMTPSecurity: getEnvOrDefault("SMTP_SECURITY", SecurityStartTLS),
+		DropDir:      getEnvOrDefault("EMAIL_DROP_DIR", "./maildrop"),
 		FromEmail:    getEnvOrDefault("FROM_EMAIL", "noreply@massagebooking.com"),
 		FromName:     getEnvOrDefault("FROM_NAME", "Massage Booking Team"),
+		ReplyTo:      getEnvOrDefault("REPLY_TO", ""),
+		TemplateDir:  getEnvOrDefault("EMAIL_TEMPLATE_DIR", ""),
+		StaffEmail:   getEnvOrDefault("STAFF_EMAIL", ""),
+		StaffLocale:  getEnvOrDefault("STAFF_LOCALE", string(i18n.Default)),
+		DigestTime:   getEnvOrDefault("DIGEST_TIME", DefaultDigestTime),
+
+		AttachInvoices: getEnvOrDefault("INVOICE_ATTACH", "false") == "true",
+	}
+
+	if config.Transport == "" {
+		config.Transport = TransportLog
+		if config.SMTPUser != "" {
+			config.Transport = TransportSMTP
+		}
 	}
+
+	return config
 }
 
-// SendConfirmationEmail sends booking confirmation email
-func SendConfirmationEmail(booking *models.BookingDetail) error {
-	config := GetEmailConfig()
+// InvoiceIssuer issues the invoice of a booking, numbering it the first time
+type InvoiceIssuer interface {
+	IssueInvoice(ctx context.Context, bookingID int) (*models.Invoice, error)
+}
 
-	// If SMTP credentials are not configured, log email instead
-	if config.SMTPUser == "" || config.SMTPPassword == "" {
-		return logEmailToConsole(booking)
+// Sender renders booking emails and delivers them through a Mailer
+type Sender struct {
+	mailer   Mailer
+	renderer *Renderer
+	config   *EmailConfig
+	invoices InvoiceIssuer // attaches invoices to confirmations when set
+}
+
+// NewSender creates a sender that renders with renderer and delivers through mailer
+func NewSender(mailer Mailer, renderer *Renderer, config *EmailConfig) *Sender {
+	return &Sender{mailer: mailer, renderer: renderer, config: config}
+}
+
+// CheckTransport verifies that the configured email transport is usable.
+// It returns the transport name and an error if the transport cannot be reached.
+func (s *Sender) CheckTransport(ctx context.Context) (string, error) {
+	name := s.mailer.Name()
+	if checker, ok := s.mailer.(Checker); ok {
+		if err := checker.Check(ctx); err != nil {
+			return name, err
+		}
 	}
+	return name, nil
+}
 
-	// Generate email content
-	subject := GetEmailSubject(booking)
-	body := RenderEmailTemplate(booking)
+// AttachInvoices makes confirmation emails carry the booking's invoice as a PDF
+func (s *Sender) AttachInvoices(invoices InvoiceIssuer) {
+	s.invoices = invoices
+}
 
-	// Send email via SMTP
-	return sendSMTPEmail(config, booking.Email, subject, body)
+// SendConfirmationEmail sends booking confirmation email with the appointment
+// as an .ics attachment and, when enabled, the invoice as a PDF
+func (s *Sender) SendConfirmationEmail(ctx context.Context, booking *models.BookingDetail) error {
+	var attachments []Attachment
+	if !booking.StartsAt.IsZero() {
+		attachments = append(attachments, s.calendarAttachment(booking))
+	}
+	if s.invoices != nil {
+		// The confirmation matters more than the invoice, which can be downloaded later
+		inv, err := s.invoices.IssueInvoice(ctx, booking.ID)
+		if err != nil {
+			log.Printf("Sending confirmation of booking %d without an invoice: %v", booking.ID, err)
+		} else {
+			attachments = append(attachments, invoiceAttachment(inv))
+		}
+	}
+	return s.send(ctx, TemplateConfirmation, booking.Email, booking, attachments...)
 }
 
-// logEmailToConsole logs email content to console (fallback when SMTP not configured)
-func logEmailToConsole(booking *models.BookingDetail) error {
-	subject := GetEmailSubject(booking)
-	body := RenderEmailTemplate(booking)
+// SendReminderEmail sends an appointment reminder
+func (s *Sender) SendReminderEmail(ctx context.Context, booking *models.BookingDetail) error {
+	return s.send(ctx, TemplateReminder, booking.Email, booking)
+}
 
-	log.Printf("=== EMAIL NOTIFICATION ===")
-	log.Printf("To: %s", booking.Email)
-	log.Printf("Subject: %s", subject)
-	log.Printf("Body (HTML):\n%s", body)
-	log.Printf("=== END EMAIL ===")
+// SendCancellationEmail tells the client their booking has been cancelled
+func (s *Sender) SendCancellationEmail(ctx context.Context, booking *models.BookingDetail) error {
+	return s.send(ctx, TemplateCancellation, booking.Email, booking)
+}
 
-	// Also save to file for reference
-	filename := fmt.Sprintf("email_%s.html", booking.Reference)
-	if err := os.WriteFile(filename, []byte(body), 0644); err != nil {
-		log.Printf("Warning: Could not save email to file %s: %v", filename, err)
-	} else {
-		log.Printf("Email content saved to %s", filename)
+// SendVoucherEmail sends a gift voucher certificate to its recipient
+func (s *Sender) SendVoucherEmail(ctx context.Context, voucher *models.GiftVoucher) error {
+	rendered, err := s.renderer.RenderVoucher(voucher)
+	if err != nil {
+		return err
 	}
+	return s.deliver(ctx, rendered, voucher.RecipientEmail, s.config.ReplyTo)
+}
+
+// VoucherCertificate renders a gift voucher certificate for printing
+func (s *Sender) VoucherCertificate(voucher *models.GiftVoucher) (*Rendered, error) {
+	return s.renderer.RenderVoucher(voucher)
+}
 
-	return nil
+// Invoice renders the invoice of a booking as an HTML page
+func (s *Sender) Invoice(inv *models.Invoice) (*Rendered, error) {
+	return s.renderer.RenderInvoice(inv)
 }
 
-// sendSMTPEmail sends email via SMTP
-func sendSMTPEmail(config *EmailConfig, to, subject, htmlBody string) error {
-	// SMTP server configuration
-	auth := smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)
-	addr := config.SMTPHost + ":" + config.SMTPPort
+// InvoicePDF renders the invoice of a booking as a PDF file
+func (s *Sender) InvoicePDF(inv *models.Invoice) []byte {
+	return RenderInvoicePDF(inv)
+}
 
-	// Email headers and body
-	from := fmt.Sprintf("%s <%s>", config.FromName, config.FromEmail)
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
+// SendStaffBookingEmail notifies the staff of a new booking
+func (s *Sender) SendStaffBookingEmail(ctx context.Context, booking *models.BookingDetail, to string) error {
+	return s.sendStaff(ctx, TemplateStaffBooking, booking, to)
+}
 
-	// Build message
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+// SendStaffCancellationEmail notifies the staff of a cancelled booking
+func (s *Sender) SendStaffCancellationEmail(ctx context.Context, booking *models.BookingDetail, to string) error {
+	return s.sendStaff(ctx, TemplateStaffCancellation, booking, to)
+}
+
+// SendStaffDigest sends the staff the schedule of bookings for a date
+func (s *Sender) SendStaffDigest(ctx context.Context, to, date string, bookings []models.BookingDetail) error {
+	rendered, err := s.renderer.RenderDigest(date, bookings, i18n.Parse(s.config.StaffLocale))
+	if err != nil {
+		return err
 	}
-	message += "\r\n" + htmlBody
+	return s.deliver(ctx, rendered, to, s.config.ReplyTo)
+}
 
-	// Send email
-	err := smtp.SendMail(addr, auth, config.FromEmail, []string{to}, []byte(message))
+// sendStaff renders a staff template in the staff locale; replies go to the client
+func (s *Sender) sendStaff(ctx context.Context, template string, booking *models.BookingDetail, to string) error {
+	rendered, err := s.renderer.RenderIn(template, booking, i18n.Parse(s.config.StaffLocale))
 	if err != nil {
-		log.Printf("Failed to send email to %s: %v", to, err)
-		// Fallback to console logging
-		return logEmailToConsole(&models.BookingDetail{
-			Email:     to,
-			Reference: "SMTP_FAILED",
-		})
+		return err
 	}
+	return s.deliver(ctx, rendered, to, booking.Email)
+}
 
-	log.Printf("Email sent successfully to %s", to)
-	return nil
+// calendarAttachment returns the booking as an iCalendar event
+func (s *Sender) calendarAttachment(booking *models.BookingDetail) Attachment {
+	brand := s.renderer.Branding()
+	cal := calendar.Calendar{
+		Method: "PUBLISH",
+		Events: []calendar.Event{calendar.BookingEvent(booking, calendar.Salon{Name: brand.SalonName, Address: brand.Address})},
+	}
+	return Attachment{
+		Filename:    booking.Reference + ".ics",
+		ContentType: calendar.ContentType + "; method=PUBLISH",
+		Data:        cal.Bytes(),
+	}
 }
 
-// SendEmailAsync sends email in background goroutine
-func SendEmailAsync(booking *models.BookingDetail) {
-	go func() {
-		if err := SendConfirmationEmail(booking); err != nil {
-			log.Printf("Error sending confirmation email: %v", err)
-		}
-	}()
+// invoiceAttachment returns an invoice as a PDF file named after its number
+func invoiceAttachment(inv *models.Invoice) Attachment {
+	return Attachment{
+		Filename:    inv.Number + ".pdf",
+		ContentType: "application/pdf",
+		Data:        RenderInvoicePDF(inv),
+	}
+}
+
+// send renders the named template for a booking and delivers it to the recipient
+func (s *Sender) send(ctx context.Context, template, to string, booking *models.BookingDetail, attachments ...Attachment) error {
+	rendered, err := s.renderer.Render(template, booking)
+	if err != nil {
+		return err
+	}
+	return s.deliver(ctx, rendered, to, s.config.ReplyTo, attachments...)
+}
+
+// deliver sends a rendered email through the mailer
+func (s *Sender) deliver(ctx context.Context, rendered *Rendered, to, replyTo string, attachments ...Attachment) error {
+	msg := &Message{
+		FromName:  s.config.FromName,
+		FromEmail: s.config.FromEmail,
+		To:        to,
+		ReplyTo:   replyTo,
+		Subject:   rendered.Subject,
+		TextBody:  rendered.Text,
+		HTMLBody:  rendered.HTML,
+
+		Attachments: attachments,
+	}
+	return s.mailer.Send(ctx, msg)
 }
 
 // getEnvOrDefault gets environment variable or returns default value
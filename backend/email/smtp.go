@@ -0,0 +1,154 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP connection security modes accepted by SMTP_SECURITY
+const (
+	SecurityStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SecurityTLS      = "tls"      // implicit TLS from the first byte, usually port 465
+	SecurityNone     = "none"     // unencrypted, for local relays only
+)
+
+// smtpTimeout bounds a single SMTP conversation when ctx has no deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	security string
+}
+
+// NewSMTPMailer creates an SMTP transport. Authentication is skipped when
+// no username is configured, which suits local relays.
+func NewSMTPMailer(config *EmailConfig) (*SMTPMailer, error) {
+	switch config.SMTPSecurity {
+	case SecurityStartTLS, SecurityTLS, SecurityNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP security mode %q", config.SMTPSecurity)
+	}
+
+	return &SMTPMailer{
+		host:     config.SMTPHost,
+		port:     config.SMTPPort,
+		username: config.SMTPUser,
+		password: config.SMTPPassword,
+		security: config.SMTPSecurity,
+	}, nil
+}
+
+// Name returns the transport name
+func (m *SMTPMailer) Name() string {
+	return TransportSMTP
+}
+
+// Check verifies that the SMTP server accepts a connection and, if configured, TLS
+func (m *SMTPMailer) Check(ctx context.Context) error {
+	c, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.Quit()
+}
+
+// Send delivers a message via SMTP
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server %s does not support authentication", m.host)
+		}
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+
+	if err := c.Mail(msg.FromEmail); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %v", err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("SMTP RCPT TO %s failed: %v", msg.To, err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %v", err)
+	}
+
+	if err := c.Quit(); err != nil {
+		log.Printf("Warning: SMTP QUIT failed after sending to %s: %v", msg.To, err)
+	}
+
+	log.Printf("Email sent successfully to %s", msg.To)
+	return nil
+}
+
+// connect dials the server, applies the configured security mode and returns a client ready for AUTH
+func (m *SMTPMailer) connect(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.host, m.port)
+	tlsConfig := &tls.Config{ServerName: m.host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	if m.security == SecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SMTP server %s unreachable: %v", addr, err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SMTP handshake with %s failed: %v", addr, err)
+	}
+
+	if m.security == SecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("STARTTLS with %s failed: %v", addr, err)
+		}
+	}
+
+	return c, nil
+}
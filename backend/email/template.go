@@ -1,178 +1,223 @@
 package email
 
 import (
+	"bytes"
+	"embed"
+	"errors"
 	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"regexp"
 	"strings"
-	"time"
+	texttemplate "text/template"
 
+	"massage-booking/backend/i18n"
 	"massage-booking/backend/models"
 )
 
-// RenderEmailTemplate generates HTML email content for booking confirmation
-func RenderEmailTemplate(booking *models.BookingDetail) string {
-	// Format date for display
-	date, err := time.Parse("2006-01-02", booking.Date)
-	var formattedDate string
+// Email template names; each has <name>.html and <name>.txt files
+const (
+	TemplateConfirmation = "confirmation"
+	TemplateReminder     = "reminder"
+	TemplateCancellation = "cancellation"
+	TemplateVoucher      = "voucher" // gift voucher certificate
+	TemplateInvoice      = "invoice" // invoice or receipt for a booking
+
+	// Staff templates are rendered in the staff locale rather than the client's
+	TemplateStaffBooking      = "staff_booking"
+	TemplateStaffCancellation = "staff_cancellation"
+	TemplateStaffDigest       = "staff_digest"
+)
+
+// templateNames lists every template, checked when the renderer is created
+var templateNames = []string{
+	TemplateConfirmation, TemplateReminder, TemplateCancellation, TemplateVoucher, TemplateInvoice,
+	TemplateStaffBooking, TemplateStaffCancellation, TemplateStaffDigest,
+}
+
+//go:embed templates/*.html templates/*.txt
+var embeddedTemplates embed.FS
+
+// Branding is the salon identity shown in every email
+type Branding struct {
+	SalonName    string
+	LogoURL      string // optional
+	PrimaryColor string // CSS hex colour for headings and rules
+	AccentColor  string // CSS hex colour for the booking reference
+	Address      string // optional postal address shown in the footer
+}
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// GetBranding loads branding from environment variables
+func GetBranding() (Branding, error) {
+	b := Branding{
+		SalonName:    getEnvOrDefault("SALON_NAME", "Massage Booking Team"),
+		LogoURL:      getEnvOrDefault("BRAND_LOGO_URL", ""),
+		PrimaryColor: getEnvOrDefault("BRAND_PRIMARY_COLOR", "#4CAF50"),
+		AccentColor:  getEnvOrDefault("BRAND_ACCENT_COLOR", "#1976d2"),
+		Address:      getEnvOrDefault("SALON_ADDRESS", ""),
+	}
+
+	for name, colour := range map[string]string{"BRAND_PRIMARY_COLOR": b.PrimaryColor, "BRAND_ACCENT_COLOR": b.AccentColor} {
+		if !hexColor.MatchString(colour) {
+			return Branding{}, fmt.Errorf("%s must be a hex colour like #4CAF50, got %q", name, colour)
+		}
+	}
+	return b, nil
+}
+
+// TemplateData is the value templates are executed with
+type TemplateData struct {
+	Brand   Branding
+	Booking *models.BookingDetail
+	Locale  i18n.Locale
+
+	// Set for the staff digest only
+	Date     string
+	Bookings []models.BookingDetail
+
+	// Set for the gift voucher certificate only
+	Voucher *models.GiftVoucher
+
+	// Set for the invoice only
+	Invoice *models.Invoice
+}
+
+// Rendered is the output of rendering one email template
+type Rendered struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// Renderer executes the email templates. Templates are embedded in the
+// binary; files with the same name in an override directory take precedence
+// and are re-read on every render, so wording can change without a rebuild.
+type Renderer struct {
+	branding    Branding
+	overrideDir string
+	files       fs.FS
+}
+
+// NewRenderer creates a renderer and checks that every template parses
+func NewRenderer(branding Branding, overrideDir string) (*Renderer, error) {
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{branding: branding, overrideDir: overrideDir, files: embedded}
+	if overrideDir != "" {
+		r.files = overlayFS{primary: os.DirFS(overrideDir), fallback: embedded}
+	}
+
+	for _, name := range templateNames {
+		if _, _, err := r.parse(name, i18n.Default); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Branding returns the branding the renderer was created with
+func (r *Renderer) Branding() Branding {
+	return r.branding
+}
+
+// templateFuncs returns the functions available in every template:
+// t translates a catalog key, date and price format for the locale, and
+// invoiceTitle names an invoice or receipt
+func templateFuncs(locale i18n.Locale) map[string]any {
+	return map[string]any{
+		"t":    locale.T,
+		"date": locale.FormatDateString,
+		"price": func(m models.Money) string {
+			return locale.FormatMoney(m.Amount, m.Currency)
+		},
+		"invoiceTitle": func(inv *models.Invoice) string {
+			return invoiceTitle(inv, locale)
+		},
+	}
+}
+
+// parse loads the HTML and text templates for an email in a locale
+func (r *Renderer) parse(name string, locale i18n.Locale) (*htmltemplate.Template, *texttemplate.Template, error) {
+	funcs := templateFuncs(locale)
+	html, err := htmltemplate.New("layout.html").Funcs(funcs).ParseFS(r.files, "layout.html", name+".html")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s.html: %v", name, err)
+	}
+	text, err := texttemplate.New(name+".txt").Funcs(funcs).ParseFS(r.files, name+".txt")
 	if err != nil {
-		formattedDate = booking.Date
-	} else {
-		formattedDate = date.Format("Monday, January 2, 2006")
+		return nil, nil, fmt.Errorf("failed to parse %s.txt: %v", name, err)
 	}
+	return html, text, nil
+}
+
+// Render executes the named email template for a booking in the booking's locale
+func (r *Renderer) Render(name string, booking *models.BookingDetail) (*Rendered, error) {
+	return r.RenderIn(name, booking, i18n.Parse(booking.Locale))
+}
+
+// RenderIn executes the named email template for a booking in the given locale
+func (r *Renderer) RenderIn(name string, booking *models.BookingDetail, locale i18n.Locale) (*Rendered, error) {
+	return r.render(name, TemplateData{Booking: booking, Locale: locale})
+}
+
+// RenderDigest executes the staff digest template for the bookings on a date
+func (r *Renderer) RenderDigest(date string, bookings []models.BookingDetail, locale i18n.Locale) (*Rendered, error) {
+	return r.render(TemplateStaffDigest, TemplateData{Date: date, Bookings: bookings, Locale: locale})
+}
+
+// RenderVoucher executes the gift voucher certificate in the voucher's locale
+func (r *Renderer) RenderVoucher(voucher *models.GiftVoucher) (*Rendered, error) {
+	return r.render(TemplateVoucher, TemplateData{Voucher: voucher, Locale: i18n.Parse(voucher.Locale)})
+}
 
-	template := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Booking Confirmation</title>
-    <style>
-        body {
-            font-family: Arial, sans-serif;
-            line-height: 1.6;
-            color: #333;
-            max-width: 600px;
-            margin: 0 auto;
-            padding: 20px;
-            background-color: #f4f4f4;
-        }
-        .container {
-            background: white;
-            padding: 30px;
-            border-radius: 10px;
-            box-shadow: 0 0 10px rgba(0,0,0,0.1);
-        }
-        .header {
-            text-align: center;
-            border-bottom: 2px solid #4CAF50;
-            padding-bottom: 20px;
-            margin-bottom: 30px;
-        }
-        .header h1 {
-            color: #4CAF50;
-            margin: 0;
-        }
-        .booking-details {
-            background: #f8f9fa;
-            padding: 20px;
-            border-radius: 8px;
-            margin: 20px 0;
-        }
-        .detail-row {
-            display: flex;
-            justify-content: space-between;
-            margin-bottom: 10px;
-            padding: 5px 0;
-            border-bottom: 1px solid #eee;
-        }
-        .detail-label {
-            font-weight: bold;
-            color: #555;
-        }
-        .detail-value {
-            color: #333;
-        }
-        .reference {
-            background: #e3f2fd;
-            padding: 15px;
-            border-radius: 8px;
-            text-align: center;
-            margin: 20px 0;
-            border-left: 4px solid #2196f3;
-        }
-        .reference-number {
-            font-size: 18px;
-            font-weight: bold;
-            color: #1976d2;
-        }
-        .footer {
-            text-align: center;
-            margin-top: 30px;
-            padding-top: 20px;
-            border-top: 1px solid #eee;
-            color: #666;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>Booking Confirmation</h1>
-            <p>Your massage appointment has been confirmed!</p>
-        </div>
-
-        <div class="reference">
-            <p>Booking Reference Number:</p>
-            <div class="reference-number">{{.Reference}}</div>
-        </div>
-
-        <div class="booking-details">
-            <h3>Appointment Details</h3>
-            <div class="detail-row">
-                <span class="detail-label">Service:</span>
-                <span class="detail-value">{{.ServiceName}}</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Duration:</span>
-                <span class="detail-value">{{.Duration}} minutes</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Price:</span>
-                <span class="detail-value">€{{.Price}}</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Date:</span>
-                <span class="detail-value">{{.FormattedDate}}</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Time:</span>
-                <span class="detail-value">{{.TimeSlot}}</span>
-            </div>
-        </div>
-
-        <div class="booking-details">
-            <h3>Customer Information</h3>
-            <div class="detail-row">
-                <span class="detail-label">Name:</span>
-                <span class="detail-value">{{.ClientName}}</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Email:</span>
-                <span class="detail-value">{{.Email}}</span>
-            </div>
-            <div class="detail-row">
-                <span class="detail-label">Phone:</span>
-                <span class="detail-value">{{.Phone}}</span>
-            </div>
-        </div>
-
-        <div class="footer">
-            <p>We look forward to seeing you!</p>
-            <p><strong>Massage Booking Team</strong></p>
-            <p style="font-size: 12px; color: #999;">
-                Please save this email for your records. If you need to make any changes, 
-                please contact us with your booking reference number.
-            </p>
-        </div>
-    </div>
-</body>
-</html>`
-
-	// Replace placeholders
-	content := strings.ReplaceAll(template, "{{.Reference}}", booking.Reference)
-	content = strings.ReplaceAll(content, "{{.ServiceName}}", booking.ServiceName)
-	content = strings.ReplaceAll(content, "{{.Duration}}", fmt.Sprintf("%d", booking.Duration))
-	content = strings.ReplaceAll(content, "{{.Price}}", fmt.Sprintf("%.2f", booking.Price))
-	content = strings.ReplaceAll(content, "{{.FormattedDate}}", formattedDate)
-	content = strings.ReplaceAll(content, "{{.TimeSlot}}", booking.TimeSlot)
-	content = strings.ReplaceAll(content, "{{.ClientName}}", booking.ClientName)
-	content = strings.ReplaceAll(content, "{{.Email}}", booking.Email)
-	content = strings.ReplaceAll(content, "{{.Phone}}", booking.Phone)
-
-	return content
-}
-
-// GetEmailSubject generates email subject line
-func GetEmailSubject(booking *models.BookingDetail) string {
-	return fmt.Sprintf("Booking Confirmation - %s", booking.Reference)
+// RenderInvoice executes the invoice template in the booking's locale
+func (r *Renderer) RenderInvoice(inv *models.Invoice) (*Rendered, error) {
+	return r.render(TemplateInvoice, TemplateData{Invoice: inv, Locale: i18n.Parse(inv.Locale)})
+}
+
+// render executes a template with data, filling in the branding
+func (r *Renderer) render(name string, data TemplateData) (*Rendered, error) {
+	html, text, err := r.parse(name, data.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Brand = r.branding
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %v", name, err)
+	}
+	if err := text.ExecuteTemplate(&textBody, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %v", name, err)
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s HTML: %v", name, err)
+	}
+
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
+
+// overlayFS serves files from primary, falling back to fallback when missing
+type overlayFS struct {
+	primary  fs.FS
+	fallback fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.primary.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.fallback.Open(name)
+	}
+	return f, err
 }
@@ -0,0 +1,247 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+func TestRenderEscapesUserInput(t *testing.T) {
+	booking := *goldenBooking
+	booking.ClientName = `<script>alert("x")</script>`
+	booking.Phone = `<img src=x onerror=alert(1)>`
+
+	rendered, err := newTestRenderer(t).Render(TemplateConfirmation, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(rendered.HTML, "<script>") || strings.Contains(rendered.HTML, "<img src=x") {
+		t.Errorf("user input was not escaped:\n%s", rendered.HTML)
+	}
+	if !strings.Contains(rendered.HTML, "&lt;script&gt;") {
+		t.Error("expected escaped client name in HTML")
+	}
+	// Plain text is not HTML and keeps the value as entered
+	if !strings.Contains(rendered.Text, booking.ClientName) {
+		t.Error("expected raw client name in text body")
+	}
+}
+
+func TestRenderShowsDiscount(t *testing.T) {
+	booking := *goldenBooking
+	booking.PromoCode = "SPRING20"
+	booking.Discount = models.NewMoney(1000, "EUR")
+	booking.Total = models.NewMoney(4000, "EUR")
+
+	rendered, err := newTestRenderer(t).Render(TemplateConfirmation, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Discount (SPRING20): -€10.00", "Total: €40.00"} {
+		if !strings.Contains(rendered.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, rendered.Text)
+		}
+	}
+	if !strings.Contains(rendered.HTML, "-€10.00") {
+		t.Error("HTML missing the discount")
+	}
+}
+
+func TestRenderShowsTax(t *testing.T) {
+	booking := *goldenBooking
+	booking.TaxAmounts = models.ApplyTax(models.NewMoney(5000, "EUR"), 2200, true)
+	booking.Total = booking.Gross
+
+	rendered, err := newTestRenderer(t).Render(TemplateConfirmation, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered.Text, "Including VAT (22%): €9.02") || strings.Contains(rendered.Text, "Total:") {
+		t.Errorf("expected the included tax without a total:\n%s", rendered.Text)
+	}
+
+	booking.TaxAmounts = models.ApplyTax(models.NewMoney(5000, "EUR"), 950, false)
+	booking.Total = booking.Gross
+	rendered, err = newTestRenderer(t).Render(TemplateConfirmation, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Price: €50.00", "VAT (9.5%): €4.75", "Total: €54.75"} {
+		if !strings.Contains(rendered.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, rendered.Text)
+		}
+	}
+	if !strings.Contains(rendered.HTML, "€54.75") {
+		t.Error("HTML missing the total with tax")
+	}
+}
+
+func TestRenderVoucher(t *testing.T) {
+	voucher := &models.GiftVoucher{
+		Code:          "GIFT-7KQ2-M9XD",
+		Value:         models.NewMoney(7500, "EUR"),
+		Balance:       models.NewMoney(7500, "EUR"),
+		PurchaserName: "Mari",
+		RecipientName: "Jaan",
+		Message:       "Palju õnne! <3",
+		Locale:        "et",
+		ExpiresOn:     "2027-03-31",
+	}
+
+	rendered, err := newTestRenderer(t).RenderVoucher(voucher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Väärtus: 75,00\u00a0€", "Kinkekaardi kood: GIFT-7KQ2-M9XD", "Kinkija: Mari", "31. märts 2027"} {
+		if !strings.Contains(rendered.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, rendered.Text)
+		}
+	}
+	if !strings.Contains(rendered.HTML, "Palju õnne! &lt;3") {
+		t.Error("HTML should contain the escaped message")
+	}
+
+	voucher.ServiceID, voucher.ServiceName, voucher.Locale = 2, "Deep Tissue Massage", "en"
+	rendered, err = newTestRenderer(t).RenderVoucher(voucher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered.Text, "Valid for: Deep Tissue Massage") || strings.Contains(rendered.Text, "€75.00") {
+		t.Errorf("service voucher should name the service, not a value:\n%s", rendered.Text)
+	}
+}
+
+func TestRenderUsesBranding(t *testing.T) {
+	r, err := NewRenderer(Branding{
+		SalonName:    "Serenity Spa",
+		LogoURL:      "https://example.com/logo.png",
+		PrimaryColor: "#123456",
+		AccentColor:  "#abcdef",
+		Address:      "Narva mnt 1, Tallinn",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rendered, err := r.Render(TemplateConfirmation, goldenBooking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Serenity Spa", `src="https://example.com/logo.png"`, "#123456", "#abcdef", "Narva mnt 1, Tallinn"} {
+		if !strings.Contains(rendered.HTML, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+	if strings.Contains(rendered.HTML, "Massage Booking Team") {
+		t.Error("HTML still contains the default salon name")
+	}
+	if !strings.Contains(rendered.Text, "Serenity Spa") {
+		t.Error("text missing salon name")
+	}
+}
+
+func TestRenderOverrideDirectory(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "subject"}}Your visit {{.Booking.Reference}}{{end}}{{define "text"}}custom{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "confirmation.txt"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRenderer(testBranding, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := r.Render(TemplateConfirmation, goldenBooking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Your visit BK-20250310-001" || rendered.Text != "custom" {
+		t.Errorf("override not used: %q / %q", rendered.Subject, rendered.Text)
+	}
+	// The HTML template was not overridden and still comes from the binary
+	if !strings.Contains(rendered.HTML, "Booking Confirmation") {
+		t.Error("expected embedded HTML template")
+	}
+
+	// Edits are picked up without restarting
+	override = `{{define "subject"}}Edited{{end}}{{define "text"}}custom{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "confirmation.txt"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rendered, err = r.Render(TemplateConfirmation, &models.BookingDetail{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Edited" {
+		t.Errorf("expected edited subject, got %q", rendered.Subject)
+	}
+}
+
+func TestGetBrandingRejectsInvalidColour(t *testing.T) {
+	t.Setenv("BRAND_PRIMARY_COLOR", "red; background:url(x)")
+	if _, err := GetBranding(); err == nil {
+		t.Error("expected error for invalid colour")
+	}
+}
+
+func TestRenderInBookingLocale(t *testing.T) {
+	booking := *goldenBooking
+	booking.Locale = "et"
+
+	rendered, err := newTestRenderer(t).Render(TemplateConfirmation, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Broneeringu kinnitus - BK-20250310-001" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+	for _, want := range []string{"esmaspäev, 10. märts 2025", "50,00\u00a0€", "60 minutit"} {
+		if !strings.Contains(rendered.Text, want) || !strings.Contains(rendered.HTML, want) {
+			t.Errorf("rendered email missing %q", want)
+		}
+	}
+	if !strings.Contains(rendered.HTML, `<html lang="et">`) {
+		t.Error("expected lang attribute")
+	}
+
+	booking.Locale = "ru"
+	rendered, err = newTestRenderer(t).Render(TemplateReminder, &booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Напоминание: Swedish Massage понедельник, 10 марта 2025 г. в 10:00"; rendered.Subject != want {
+		t.Errorf("subject = %q, want %q", rendered.Subject, want)
+	}
+}
+
+func TestRenderStaffDigest(t *testing.T) {
+	second := *goldenBooking
+	second.Reference = "BK-20250310-002"
+	second.ClientName = "John Smith"
+	second.TimeSlot = "14:00"
+
+	rendered, err := newTestRenderer(t).RenderDigest("2025-03-10", []models.BookingDetail{*goldenBooking, second}, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Schedule for Monday, March 10, 2025" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+	for _, want := range []string{"10:00", "Jane Doe", "14:00", "John Smith"} {
+		if !strings.Contains(rendered.Text, want) || !strings.Contains(rendered.HTML, want) {
+			t.Errorf("digest missing %q", want)
+		}
+	}
+
+	empty, err := newTestRenderer(t).RenderDigest("2025-03-10", nil, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(empty.Text, "No appointments") || !strings.Contains(empty.HTML, "No appointments") {
+		t.Errorf("expected an empty schedule notice:\n%s", empty.Text)
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+)
+
+// requireAdmin rejects requests that do not carry the configured admin bearer token
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminToken == "" {
+			http.Error(w, "Admin API is disabled", http.StatusForbidden)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// ListOutbox handles GET /api/admin/outbox?status=pending|sent|dead|skipped
+func (s *Server) ListOutbox(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.OutboxStatusPending, models.OutboxStatusSent, models.OutboxStatusDead, models.OutboxStatusSkipped:
+	default:
+		http.Error(w, "Invalid status parameter", http.StatusBadRequest)
+		return
+	}
+
+	emails, err := s.store.ListEmails(r.Context(), status)
+	if err != nil {
+		log.Printf("Error listing outbox emails: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if emails == nil {
+		emails = []models.OutboxEmail{}
+	}
+
+	if err := json.NewEncoder(w).Encode(emails); err != nil {
+		log.Printf("Error encoding outbox response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ResendOutboxEmail handles POST /api/admin/outbox/:id/resend
+func (s *Server) ResendOutboxEmail(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid email ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.ResendEmail(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrEmailNotFound) {
+			http.Error(w, "Email not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error requeueing email %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	log.Printf("Requeued outbox email %d", id)
+}
+
+// CancelBooking handles POST /api/admin/bookings/:id/cancel
+func (s *Server) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := s.store.CancelBooking(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrBookingNotFound):
+			http.Error(w, "Booking not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrBookingCancelled):
+			http.Error(w, "Booking is already cancelled", http.StatusConflict)
+		case errors.Is(err, database.ErrBookingStarted):
+			http.Error(w, "Booking has already started", http.StatusConflict)
+		case errors.Is(err, database.ErrBookingUnpaid):
+			http.Error(w, "Booking is awaiting payment", http.StatusConflict)
+		default:
+			log.Printf("Error cancelling booking %d: %v", id, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	metrics.BookingsCancelled.Inc()
+	log.Printf("Cancelled booking %s", booking.Reference)
+	// The booking is cancelled whether or not the client stays connected
+	s.sendCancellationRefunds(context.WithoutCancel(r.Context()), booking)
+
+	if err := json.NewEncoder(w).Encode(booking); err != nil {
+		log.Printf("Error encoding booking response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// ListAdminBookings handles GET /api/admin/bookings?date=YYYY-MM-DD
+func (s *Server) ListAdminBookings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		http.Error(w, "Missing required parameter: date", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "Invalid date parameter", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	bookings, err := s.store.ListAllBookingsOn(ctx, date)
+	if err != nil {
+		log.Printf("Error listing bookings on %s: %v", date, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	result, err := s.withCurrentPrices(ctx, bookings)
+	if err != nil {
+		log.Printf("Error getting current prices: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding bookings response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// GetAdminBooking handles GET /api/admin/bookings/:id
+func (s *Server) GetAdminBooking(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	booking, err := s.store.GetBookingByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrBookingNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error getting booking %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	result, err := s.withCurrentPrices(ctx, []models.BookingDetail{*booking})
+	if err != nil {
+		log.Printf("Error getting current prices: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result[0]); err != nil {
+		log.Printf("Error encoding booking response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// withCurrentPrices pairs bookings with the current catalog price of their service
+func (s *Server) withCurrentPrices(ctx context.Context, bookings []models.BookingDetail) ([]models.AdminBooking, error) {
+	services, err := s.store.GetMassageTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[int]models.Money, len(services))
+	for _, service := range services {
+		prices[service.ID] = service.Price
+	}
+
+	result := make([]models.AdminBooking, 0, len(bookings))
+	for _, booking := range bookings {
+		admin := models.AdminBooking{BookingDetail: booking}
+		if price, ok := prices[booking.ServiceID]; ok {
+			admin.CurrentPrice = &price
+		}
+		result = append(result, admin)
+	}
+	return result, nil
+}
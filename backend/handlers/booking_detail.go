@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -11,7 +12,7 @@ import (
 )
 
 // GetBooking handles GET /api/bookings/:id
-func GetBooking(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetBooking(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -44,10 +45,10 @@ func GetBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get booking from database
-	booking, err := database.GetBookingByID(bookingID)
+	booking, err := s.store.GetBookingByID(r.Context(), bookingID)
 	if err != nil {
 		log.Printf("Error getting booking %d: %v", bookingID, err)
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, database.ErrBookingNotFound) {
 			http.Error(w, "Booking not found", http.StatusNotFound)
 			return
 		}
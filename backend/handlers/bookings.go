@@ -1,21 +1,23 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
+	"unicode/utf8"
 
 	"massage-booking/backend/database"
-	"massage-booking/backend/email"
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/metrics"
 	"massage-booking/backend/models"
+	"massage-booking/backend/sms"
 )
 
 // CreateBooking handles POST /api/bookings
-func CreateBooking(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
@@ -42,107 +44,62 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request fields
-	if err := validateBookingRequest(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	// Messages use the requested locale, or the browser's language when none is given
+	locale := i18n.Parse(req.Locale)
+	if req.Locale == "" {
+		locale = i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
 	}
+	req.Locale = string(locale)
 
-	// Check if reservation exists and is not expired
-	var slotID int
-	var expiresAt string
-	err := database.DB.QueryRow(`
-		SELECT slot_id, expires_at 
-		FROM temporary_reservations 
-		WHERE id = ? AND expires_at > datetime('now')
-	`, req.ReservationID).Scan(&slotID, &expiresAt)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Reservation not found or expired", http.StatusNotFound)
-			return
-		}
-		log.Printf("Error checking reservation %d: %v", req.ReservationID, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Start database transaction
-	tx, err := database.DB.Begin()
-	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-	defer tx.Rollback()
-
-	// Generate booking reference
-	reference, err := database.GenerateBookingReference(req.Date)
-	if err != nil {
-		log.Printf("Error generating booking reference: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Create booking with reference
-	result, err := tx.Exec(`
-		INSERT INTO bookings (reference, client_name, email, phone, service_id, date, time_slot)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, reference, req.ClientName, req.Email, req.Phone, req.ServiceID, req.Date, req.TimeSlot)
-
-	if err != nil {
-		log.Printf("Error creating booking: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Validate request fields
+	if err := validateBookingRequest(req, locale); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	bookingID, err := result.LastInsertId()
-	if err != nil {
-		log.Printf("Error getting booking ID: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Store phone numbers in E.164 where possible; SMS delivery requires it
+	if phone, err := sms.NormalizePhone(req.Phone, s.config.PhoneCountryCode); err == nil {
+		req.Phone = phone
+	} else if req.SMSOptIn {
+		http.Error(w, locale.T("validation.phone_sms"), http.StatusBadRequest)
 		return
 	}
 
-	// Mark slot as unavailable
-	_, err = tx.Exec("UPDATE time_slots SET available = 0 WHERE id = ?", slotID)
-	if err != nil {
-		log.Printf("Error marking slot %d as unavailable: %v", slotID, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	// With online payment the slot is held until the client has paid,
+	// unless a discount or gift voucher leaves nothing to pay
+	if s.config.Payments != nil {
+		quote, err := s.store.Quote(r.Context(), models.QuoteRequest{
+			ServiceID:     req.ServiceID,
+			ReservationID: req.ReservationID,
+			PromoCode:     req.PromoCode,
+			VoucherCode:   req.VoucherCode,
+			PackageCode:   req.PackageCode,
+			Email:         req.Email,
+		})
+		if err != nil {
+			s.bookingError(w, req, err)
+			return
+		}
+		if s.config.Payment.DepositAmount(quote.Total.Amount) > 0 {
+			s.createPaidBooking(w, r, req)
+			return
+		}
 	}
 
-	// Delete reservation
-	_, err = tx.Exec("DELETE FROM temporary_reservations WHERE id = ?", req.ReservationID)
+	// Create booking from the reservation in a single transaction
+	booking, err := s.store.CreateBooking(r.Context(), req)
 	if err != nil {
-		log.Printf("Error deleting reservation %d: %v", req.ReservationID, err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		s.bookingError(w, req, err)
 		return
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		log.Printf("Error committing transaction: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
+	metrics.BookingsCreated.Inc()
 
 	// Get the created booking with full details for response
-	bookingDetail, err := database.GetBookingByID(int(bookingID))
+	bookingDetail, err := s.store.GetBookingByID(r.Context(), booking.ID)
 	if err != nil {
 		log.Printf("Error getting booking details: %v", err)
 		// Fallback to basic response
-		booking := models.Booking{
-			ID:         int(bookingID),
-			Reference:  reference,
-			ClientName: req.ClientName,
-			Email:      req.Email,
-			Phone:      req.Phone,
-			ServiceID:  req.ServiceID,
-			Date:       req.Date,
-			TimeSlot:   req.TimeSlot,
-			CreatedAt:  time.Now(),
-		}
-
 		if err := json.NewEncoder(w).Encode(booking); err != nil {
 			log.Printf("Error encoding booking response: %v", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -150,13 +107,10 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		}
 
 		log.Printf("Created booking %d (reference: %s) for %s (%s) on %s at %s",
-			bookingID, reference, req.ClientName, req.Email, req.Date, req.TimeSlot)
+			booking.ID, booking.Reference, req.ClientName, req.Email, req.Date, req.TimeSlot)
 		return
 	}
 
-	// Send confirmation email asynchronously
-	email.SendEmailAsync(bookingDetail)
-
 	// Send response with booking details
 	if err := json.NewEncoder(w).Encode(bookingDetail); err != nil {
 		log.Printf("Error encoding booking response: %v", err)
@@ -165,50 +119,78 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Created booking %d (reference: %s) for %s (%s) on %s at %s",
-		bookingID, reference, req.ClientName, req.Email, req.Date, req.TimeSlot)
+		booking.ID, booking.Reference, req.ClientName, req.Email, req.Date, req.TimeSlot)
+}
+
+// bookingError responds to a booking that could not be created, with the
+// problem explained in the request's locale
+func (s *Server) bookingError(w http.ResponseWriter, req models.BookingRequest, err error) {
+	locale := i18n.Parse(req.Locale)
+	if message, ok := redemptionErrorMessage(err, locale); ok {
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+	switch {
+	case errors.Is(err, database.ErrReservationNotFound):
+		http.Error(w, locale.T("validation.reservation_expired"), http.StatusNotFound)
+	case errors.Is(err, database.ErrReservationMismatch):
+		http.Error(w, locale.T("validation.reservation_mismatch"), http.StatusBadRequest)
+	case errors.Is(err, database.ErrServiceNotFound):
+		http.Error(w, locale.T("validation.service_invalid"), http.StatusBadRequest)
+	default:
+		log.Printf("Error creating booking for reservation %d: %v", req.ReservationID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
 }
 
-// validateBookingRequest validates the booking request fields
-func validateBookingRequest(req models.BookingRequest) error {
-	// Validate name
+// Patterns for validating booking contact details
+var (
+	namePattern  = regexp.MustCompile(`^[\p{L}\p{M}\s]+$`)
+	emailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	phonePattern = regexp.MustCompile(`^\+?[\d\s\-()]{8,}$`)
+)
+
+// validateBookingRequest validates the booking request fields, with messages in locale
+func validateBookingRequest(req models.BookingRequest, locale i18n.Locale) error {
+	// Validate name; letters in any script are accepted
 	if strings.TrimSpace(req.ClientName) == "" {
-		return &ValidationError{Field: "client_name", Message: "Name is required"}
+		return &ValidationError{Field: "client_name", Message: locale.T("validation.name_required")}
 	}
-	if len(strings.TrimSpace(req.ClientName)) < 2 {
-		return &ValidationError{Field: "client_name", Message: "Name must be at least 2 characters"}
+	if utf8.RuneCountInString(strings.TrimSpace(req.ClientName)) < 2 {
+		return &ValidationError{Field: "client_name", Message: locale.T("validation.name_too_short")}
 	}
-	if !regexp.MustCompile(`^[a-zA-Z\s]+$`).MatchString(req.ClientName) {
-		return &ValidationError{Field: "client_name", Message: "Name should contain only letters and spaces"}
+	if !namePattern.MatchString(req.ClientName) {
+		return &ValidationError{Field: "client_name", Message: locale.T("validation.name_letters")}
 	}
 
 	// Validate email
 	if strings.TrimSpace(req.Email) == "" {
-		return &ValidationError{Field: "email", Message: "Email is required"}
+		return &ValidationError{Field: "email", Message: locale.T("validation.email_required")}
 	}
-	if !regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`).MatchString(req.Email) {
-		return &ValidationError{Field: "email", Message: "Please enter a valid email"}
+	if !emailPattern.MatchString(req.Email) {
+		return &ValidationError{Field: "email", Message: locale.T("validation.email_invalid")}
 	}
 
 	// Validate phone
 	if strings.TrimSpace(req.Phone) == "" {
-		return &ValidationError{Field: "phone", Message: "Phone is required"}
+		return &ValidationError{Field: "phone", Message: locale.T("validation.phone_required")}
 	}
-	if !regexp.MustCompile(`^\+?[\d\s\-()]{8,}$`).MatchString(req.Phone) {
-		return &ValidationError{Field: "phone", Message: "Please enter a valid phone number"}
+	if !phonePattern.MatchString(req.Phone) {
+		return &ValidationError{Field: "phone", Message: locale.T("validation.phone_invalid")}
 	}
 
 	// Validate other required fields
 	if req.ReservationID <= 0 {
-		return &ValidationError{Field: "reservation_id", Message: "Invalid reservation ID"}
+		return &ValidationError{Field: "reservation_id", Message: locale.T("validation.reservation_invalid")}
 	}
 	if req.ServiceID <= 0 {
-		return &ValidationError{Field: "service_id", Message: "Invalid service ID"}
+		return &ValidationError{Field: "service_id", Message: locale.T("validation.service_invalid")}
 	}
 	if req.Date == "" {
-		return &ValidationError{Field: "date", Message: "Date is required"}
+		return &ValidationError{Field: "date", Message: locale.T("validation.date_required")}
 	}
 	if req.TimeSlot == "" {
-		return &ValidationError{Field: "time_slot", Message: "Time slot is required"}
+		return &ValidationError{Field: "time_slot", Message: locale.T("validation.time_required")}
 	}
 
 	return nil
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"massage-booking/backend/calendar"
+	"massage-booking/backend/database"
+)
+
+// GetBookingICS handles GET /api/bookings/:id/ics
+func (s *Server) GetBookingICS(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bookingID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+		return
+	}
+
+	booking, err := s.store.GetBookingByID(r.Context(), bookingID)
+	if err != nil {
+		if errors.Is(err, database.ErrBookingNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error getting booking %d: %v", bookingID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cal := calendar.Calendar{
+		Method: "PUBLISH",
+		Events: []calendar.Event{calendar.BookingEvent(booking, s.config.Salon)},
+	}
+
+	w.Header().Set("Content-Type", calendar.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", booking.Reference+".ics"))
+	w.Write(cal.Bytes())
+}
+
+// CalendarFeed handles GET /api/calendar/feed.ics?token=...
+// Calendar apps cannot send an Authorization header when subscribing to a
+// feed, so the token is passed in the query string.
+func (s *Server) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.config.CalendarToken == "" {
+		http.Error(w, "Calendar feed is disabled", http.StatusForbidden)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.CalendarToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bookings, err := s.store.ListUpcomingBookings(r.Context(), s.clock.Now())
+	if err != nil {
+		log.Printf("Error listing upcoming bookings: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	cal := calendar.Calendar{Name: s.config.Salon.Name}
+	for i := range bookings {
+		cal.Events = append(cal.Events, calendar.BookingEvent(&bookings[i], s.config.Salon))
+	}
+
+	w.Header().Set("Content-Type", calendar.ContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(cal.Bytes())
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// book reserves and books the first available slot for the service
+func (e *testEnv) book(t *testing.T, serviceID int) models.BookingDetail {
+	t.Helper()
+
+	slot := e.availableSlot(t, serviceID)
+	reservation := e.reserve(t, slot.ID)
+
+	var booking models.BookingDetail
+	decode(t, e.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot)), &booking)
+	return booking
+}
+
+func TestGetBookingICS(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+
+	rec := env.do(t, "GET", fmt.Sprintf("/api/bookings/%d/ics", booking.ID), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, booking.Reference+".ics") {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+
+	body := rec.Body.String()
+	start := booking.StartsAt.UTC()
+	end := start.Add(time.Duration(booking.Duration) * time.Minute)
+	for _, want := range []string{
+		"UID:" + booking.Reference,
+		"DTSTART:" + start.Format("20060102T150405Z"),
+		"DTEND:" + end.Format("20060102T150405Z"),
+		`LOCATION:Test Salon\, Narva mnt 1\, Tallinn`,
+	} {
+		if !strings.Contains(body, want+"\r\n") {
+			t.Errorf("calendar missing %q:\n%s", want, body)
+		}
+	}
+
+	if rec := env.do(t, "GET", "/api/bookings/9999/ics", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown booking, got %d", rec.Code)
+	}
+	// The plain booking endpoint still serves JSON
+	var fetched models.BookingDetail
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", booking.ID), nil), &fetched)
+}
+
+func TestCalendarFeed(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+
+	if rec := env.do(t, "GET", "/api/calendar/feed.ics", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := env.do(t, "GET", "/api/calendar/feed.ics?token=wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", rec.Code)
+	}
+
+	rec := env.do(t, "GET", "/api/calendar/feed.ics?token="+testCalendarToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "X-WR-CALNAME:Test Salon\r\n") || !strings.Contains(body, "UID:"+booking.Reference+"\r\n") {
+		t.Errorf("feed missing calendar name or booking:\n%s", body)
+	}
+
+	// A cancelled booking stays in the feed as cancelled so subscribers drop it
+	if rec := env.do(t, "POST", fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, path := range []string{"/api/calendar/feed.ics?token=" + testCalendarToken, fmt.Sprintf("/api/bookings/%d/ics", booking.ID)} {
+		body := env.do(t, "GET", path, nil).Body.String()
+		if !strings.Contains(body, "UID:"+booking.Reference+"\r\n") || !strings.Contains(body, "SEQUENCE:1\r\n") ||
+			!strings.Contains(body, "STATUS:CANCELLED\r\n") {
+			t.Errorf("%s: expected the booking marked cancelled:\n%s", path, body)
+		}
+	}
+
+	// Once the appointment has started it drops out of the feed
+	env.clock.Set(booking.StartsAt.Add(time.Second))
+	rec = env.do(t, "GET", "/api/calendar/feed.ics?token="+testCalendarToken, nil)
+	if strings.Contains(rec.Body.String(), "BEGIN:VEVENT") {
+		t.Errorf("expected no upcoming events:\n%s", rec.Body.String())
+	}
+}
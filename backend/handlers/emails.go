@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/email"
+	"massage-booking/backend/models"
+)
+
+// PreviewEmail handles GET /api/admin/emails/:template/preview?booking_id=&locale=&format=html|text|json
+func (s *Server) PreviewEmail(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	switch format {
+	case "", "html", "text", "json":
+	default:
+		http.Error(w, "Invalid format parameter", http.StatusBadRequest)
+		return
+	}
+
+	bookingID := 0
+	if value := query.Get("booking_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid booking ID", http.StatusBadRequest)
+			return
+		}
+		bookingID = id
+	}
+
+	booking, ok := s.previewBooking(w, r, bookingID)
+	if !ok {
+		return
+	}
+
+	template := r.PathValue("template")
+	rendered, err := s.mailer.Preview(template, booking, query.Get("locale"))
+	if err != nil {
+		s.previewError(w, template, err)
+		return
+	}
+
+	switch format {
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "Subject: %s\n\n%s", rendered.Subject, rendered.Text)
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rendered); err != nil {
+			log.Printf("Error encoding preview response: %v", err)
+		}
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, rendered.HTML)
+	}
+}
+
+// SendTestEmail handles POST /api/admin/emails/:template/test-send
+func (s *Server) SendTestEmail(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.EmailTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !emailPattern.MatchString(req.To) {
+		http.Error(w, "Invalid recipient address", http.StatusBadRequest)
+		return
+	}
+
+	booking, ok := s.previewBooking(w, r, req.BookingID)
+	if !ok {
+		return
+	}
+
+	template := r.PathValue("template")
+	if err := s.mailer.SendPreview(r.Context(), template, booking, req.Locale, req.To); err != nil {
+		if errors.Is(err, email.ErrUnknownTemplate) {
+			s.previewError(w, template, err)
+			return
+		}
+		log.Printf("Error sending test %s email to %s: %v", template, req.To, err)
+		http.Error(w, "Failed to send email: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	log.Printf("Sent test %s email to %s", template, req.To)
+}
+
+// previewBooking loads the booking to render, or sample data when bookingID is 0.
+// It writes an error response and returns false if the booking cannot be loaded.
+func (s *Server) previewBooking(w http.ResponseWriter, r *http.Request, bookingID int) (*models.BookingDetail, bool) {
+	if bookingID == 0 {
+		return email.SampleBooking(s.clock.Now()), true
+	}
+
+	booking, err := s.store.GetBookingByID(r.Context(), bookingID)
+	if err != nil {
+		if errors.Is(err, database.ErrBookingNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("Error getting booking %d: %v", bookingID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return booking, true
+}
+
+// previewError reports a failed render; unknown templates are a 404
+func (s *Server) previewError(w http.ResponseWriter, template string, err error) {
+	if errors.Is(err, email.ErrUnknownTemplate) {
+		http.Error(w, "Unknown email template", http.StatusNotFound)
+		return
+	}
+	log.Printf("Error rendering %s email: %v", template, err)
+	http.Error(w, "Failed to render email: "+err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/email"
+	"massage-booking/backend/models"
+)
+
+func TestPreviewEmailFormats(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+
+	rec := env.do(t, "GET", fmt.Sprintf("/api/admin/emails/confirmation/preview?booking_id=%d", booking.ID), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), booking.Reference) {
+		t.Error("expected the booking reference in the HTML preview")
+	}
+
+	rec = env.do(t, "GET", "/api/admin/emails/reminder/preview?format=text&locale=et", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "Subject: Meeldetuletus") {
+		t.Errorf("expected an Estonian text preview of sample data, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var rendered email.Rendered
+	decode(t, env.do(t, "GET", "/api/admin/emails/cancellation/preview?format=json", nil), &rendered)
+	if rendered.Subject == "" || rendered.Text == "" || rendered.HTML == "" {
+		t.Errorf("expected subject, text and HTML, got %+v", rendered)
+	}
+}
+
+func TestPreviewEmailErrors(t *testing.T) {
+	env := newTestEnv(t)
+
+	for path, want := range map[string]int{
+		"/api/admin/emails/layout/preview":                     http.StatusNotFound,
+		"/api/admin/emails/confirmation/preview?booking_id=99": http.StatusNotFound,
+		"/api/admin/emails/confirmation/preview?format=pdf":    http.StatusBadRequest,
+	} {
+		if rec := env.do(t, "GET", path, nil); rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+}
+
+func TestSendTestEmail(t *testing.T) {
+	env := newTestEnv(t)
+
+	rec := env.do(t, "POST", "/api/admin/emails/confirmation/test-send", models.EmailTestRequest{To: "owner@example.com"})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	msgs := env.mail.Messages()
+	if len(msgs) != 1 || msgs[0].To != "owner@example.com" || len(msgs[0].Attachments) != 1 {
+		t.Fatalf("expected one confirmation with an .ics to the owner, got %+v", msgs)
+	}
+
+	if rec := env.do(t, "POST", "/api/admin/emails/confirmation/test-send", models.EmailTestRequest{To: "not-an-address"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid address, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExportBookings(t *testing.T) {
+	env := newTestEnv(t)
+	env.setServiceTax(t, 2, map[string]any{"tax_rate": 20, "tax_inclusive": false})
+	env.book(t, 1)
+	slot := env.availableSlot(t, 2)
+	reservation := env.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.Email = "=1+2@example.com"
+	if rec := env.do(t, "POST", "/api/bookings", req); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := env.do(t, "GET", "/api/admin/exports/bookings?from=2025-03-10&to=2025-03-10", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a CSV, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(bookingExportHeader, ",") {
+		t.Fatalf("expected a header and two bookings, got %q", rows)
+	}
+
+	column := func(row []string, name string) string {
+		for i, h := range bookingExportHeader {
+			if h == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("no column %q", name)
+		return ""
+	}
+	var taxed []string
+	for _, row := range rows[1:] {
+		if column(row, "service") == "Deep Tissue" {
+			taxed = row
+		}
+	}
+	if taxed == nil {
+		t.Fatalf("Deep Tissue booking missing from %q", rows)
+	}
+	for name, want := range map[string]string{
+		"price": "7000", "tax_rate": "20", "tax_inclusive": "false", "net": "7000", "tax": "1400", "gross": "8400", "total": "8400",
+		"email": "'=1+2@example.com",
+	} {
+		if got := column(taxed, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, path := range []string{
+		"/api/admin/exports/bookings?from=2025-03-10",
+		"/api/admin/exports/bookings?from=2025-03-10&to=March",
+		"/api/admin/exports/bookings?from=2025-03-11&to=2025-03-10",
+	} {
+		if rec := env.do(t, "GET", path, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds how long the readiness checks may take in total
+const readinessTimeout = 3 * time.Second
+
+// HealthStatus is the response body of the health and readiness endpoints
+type HealthStatus struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Healthz handles GET /healthz; it reports that the process is up without touching dependencies
+func (s *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeHealth(w, http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Readyz handles GET /readyz; it checks the database, schema migrations and email transport
+func (s *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	status := HealthStatus{Status: "ok", Checks: make(map[string]string)}
+	fail := func(check string, err error) {
+		status.Status = "unavailable"
+		status.Checks[check] = err.Error()
+		log.Printf("Readiness check %s failed: %v", check, err)
+	}
+
+	if err := s.store.Ping(ctx); err != nil {
+		fail("database", err)
+	} else {
+		status.Checks["database"] = "ok"
+
+		applied, err := s.store.MigrationsApplied(ctx)
+		switch {
+		case err != nil:
+			fail("migrations", err)
+		case !applied:
+			status.Status = "unavailable"
+			status.Checks["migrations"] = "pending"
+		default:
+			status.Checks["migrations"] = "ok"
+		}
+	}
+
+	mode, err := s.mailer.CheckTransport(ctx)
+	switch {
+	case err != nil:
+		fail("email", err)
+	case mode != "smtp":
+		status.Checks["email"] = "fallback: " + mode
+	default:
+		status.Checks["email"] = "ok"
+	}
+
+	code := http.StatusOK
+	if status.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, status)
+}
+
+// writeHealth encodes a health status response
+func writeHealth(w http.ResponseWriter, code int, status HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Printf("Error encoding health response: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// GetInvoice handles GET /api/invoices/:reference?email=&format=html|pdf.
+// The booking's email must be given so that references alone, which are
+// easy to guess, do not reveal invoices.
+func (s *Server) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	booking, ok := s.lookupBookingByReference(w, r)
+	if !ok {
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(r.URL.Query().Get("email")), booking.Email) {
+		http.Error(w, "Booking not found", http.StatusNotFound)
+		return
+	}
+	s.writeInvoice(w, r, booking)
+}
+
+// AdminInvoice handles GET /api/admin/invoices/:reference?format=html|pdf|json
+func (s *Server) AdminInvoice(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	booking, ok := s.lookupBookingByReference(w, r)
+	if !ok {
+		return
+	}
+	s.writeInvoice(w, r, booking)
+}
+
+// lookupBookingByReference loads the booking named in the path. It writes an
+// error response and returns false if it cannot be loaded.
+func (s *Server) lookupBookingByReference(w http.ResponseWriter, r *http.Request) (*models.BookingDetail, bool) {
+	reference := r.PathValue("reference")
+	booking, err := s.store.GetBookingByReference(r.Context(), reference)
+	if err != nil {
+		if errors.Is(err, database.ErrBookingNotFound) {
+			http.Error(w, "Booking not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("Error getting booking %s: %v", reference, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return booking, true
+}
+
+// writeInvoice issues the booking's invoice if needed and responds with it
+// in the format asked for: an HTML page (the default), a PDF download or JSON
+func (s *Server) writeInvoice(w http.ResponseWriter, r *http.Request, booking *models.BookingDetail) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "html" && format != "pdf" && format != "json" {
+		http.Error(w, "format must be html, pdf or json", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := s.store.IssueInvoice(r.Context(), booking.ID)
+	if err != nil {
+		if errors.Is(err, database.ErrInvoiceNotAvailable) {
+			http.Error(w, "No invoice is available for this booking", http.StatusConflict)
+			return
+		}
+		log.Printf("Error issuing invoice for booking %s: %v", booking.Reference, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch format {
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", inv.Number+".pdf"))
+		w.Write(s.mailer.InvoicePDF(inv))
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(inv); err != nil {
+			log.Printf("Error encoding invoice response: %v", err)
+		}
+	default:
+		rendered, err := s.mailer.Invoice(inv)
+		if err != nil {
+			log.Printf("Error rendering invoice %s: %v", inv.Number, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, rendered.HTML)
+	}
+}
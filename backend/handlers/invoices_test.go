@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// adminInvoice fetches a booking's invoice as JSON from the admin endpoint
+func (e *testEnv) adminInvoice(t *testing.T, reference string) models.Invoice {
+	t.Helper()
+
+	var inv models.Invoice
+	decode(t, e.do(t, "GET", "/api/admin/invoices/"+reference+"?format=json", nil), &inv)
+	return inv
+}
+
+func TestInvoiceNumbersAreSequential(t *testing.T) {
+	env := newTestEnv(t)
+	first := env.book(t, 1)
+	second := env.book(t, 2)
+
+	// Numbers follow the order invoices are first asked for, not bookings
+	if inv := env.adminInvoice(t, second.Reference); inv.Number != "INV-000001" {
+		t.Errorf("expected INV-000001, got %q", inv.Number)
+	}
+	if inv := env.adminInvoice(t, first.Reference); inv.Number != "INV-000002" {
+		t.Errorf("expected INV-000002, got %q", inv.Number)
+	}
+	if inv := env.adminInvoice(t, second.Reference); inv.Number != "INV-000001" || inv.IssuedOn != "2025-03-10" {
+		t.Errorf("reissuing should keep the number and date, got %+v", inv)
+	}
+}
+
+func TestInvoiceVATBreakdown(t *testing.T) {
+	env := newTestEnv(t)
+	env.store.SetInvoiceSettings(database.InvoiceSettings{
+		Seller: models.Seller{Name: "Test Salon OÜ", RegistryCode: "12345678", VATNumber: "EE123456789"},
+		Prefix: "TS-",
+	})
+	env.setServiceTax(t, 1, map[string]any{"tax_rate": 24})
+	booking := env.book(t, 1)
+
+	inv := env.adminInvoice(t, booking.Reference)
+	if inv.Number != "TS-000001" || inv.Seller.VATNumber != "EE123456789" || inv.BuyerEmail != "jane@example.com" {
+		t.Errorf("unexpected invoice %+v", inv)
+	}
+	if inv.Gross.Amount != 5000 || inv.Tax.Amount != 968 || inv.Net.Amount != 4032 {
+		t.Errorf("expected 50.00 including 9.68 VAT, got gross %d tax %d net %d", inv.Gross.Amount, inv.Tax.Amount, inv.Net.Amount)
+	}
+	if len(inv.VAT) != 1 || inv.VAT[0].Rate != 2400 || len(inv.Lines) != 1 {
+		t.Errorf("unexpected breakdown %+v and lines %+v", inv.VAT, inv.Lines)
+	}
+	if inv.Due.Amount != 5000 || inv.IsReceipt() {
+		t.Errorf("an unpaid booking should get an invoice for the full price, got due %d", inv.Due.Amount)
+	}
+
+	// Changing the settings or the service's tax does not touch invoices already issued
+	env.store.SetInvoiceSettings(database.InvoiceSettings{Seller: models.Seller{Name: "Other"}})
+	env.setServiceTax(t, 1, map[string]any{"tax_rate": 9})
+	if again := env.adminInvoice(t, booking.Reference); again.Seller.Name != "Test Salon OÜ" || again.VATRate != 2400 {
+		t.Errorf("issued invoice changed: %+v", again)
+	}
+}
+
+func TestGetInvoiceFormats(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+	path := "/api/invoices/" + booking.Reference + "?email=JANE@example.com"
+
+	rec := env.do(t, "GET", path, nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected an HTML invoice, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "INV-000001") || !strings.Contains(body, booking.Reference) {
+		t.Errorf("HTML invoice missing its number or reference:\n%s", body)
+	}
+
+	rec = env.do(t, "GET", path+"&format=pdf", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" {
+		t.Fatalf("expected a PDF, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "INV-000001.pdf") {
+		t.Errorf("unexpected content disposition %q", cd)
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(body, "%PDF-") || !strings.HasSuffix(body, "%%EOF\n") {
+		t.Error("response is not a PDF file")
+	}
+
+	rec = env.do(t, "GET", path+"&format=json", nil)
+	var inv models.Invoice
+	if err := json.Unmarshal(rec.Body.Bytes(), &inv); err != nil || inv.Number != "INV-000001" {
+		t.Errorf("expected the same invoice as JSON, got %q (%v)", rec.Body.String(), err)
+	}
+}
+
+func TestGetInvoiceErrors(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+	pending, _ := env.startPaidBooking(t, 1)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"wrong email", "/api/invoices/" + pending.Reference + "?email=someone@example.com", http.StatusNotFound},
+		{"no email", "/api/invoices/" + pending.Reference, http.StatusNotFound},
+		{"unknown reference", "/api/invoices/BK-20990101-001?email=jane@example.com", http.StatusNotFound},
+		{"bad format", "/api/invoices/" + pending.Reference + "?email=jane@example.com&format=doc", http.StatusBadRequest},
+		{"awaiting payment", "/api/invoices/" + pending.Reference + "?email=jane@example.com", http.StatusConflict},
+		{"admin awaiting payment", "/api/admin/invoices/" + pending.Reference, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := env.do(t, "GET", tt.path, nil); rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"massage-booking/backend/database"
+	"massage-booking/backend/models"
 )
 
 // GetMassageTypesHandler handles GET /api/massage-types
-func GetMassageTypesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetMassageTypesHandler(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -29,7 +32,7 @@ func GetMassageTypesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get massage types from database
-	massageTypes, err := database.GetMassageTypes()
+	massageTypes, err := s.store.GetMassageTypes(r.Context())
 	if err != nil {
 		log.Printf("Error getting massage types: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -45,3 +48,48 @@ func GetMassageTypesHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Successfully returned %d massage types", len(massageTypes))
 }
+
+// SetServiceTax handles PUT /api/admin/massage-types/:id/tax
+func (s *Server) SetServiceTax(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow PUT method
+	if r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid service ID", http.StatusBadRequest)
+		return
+	}
+
+	// Rates outside 0-100% are rejected while decoding
+	var req models.ServiceTaxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.TaxRate == nil {
+		http.Error(w, "tax_rate is required", http.StatusBadRequest)
+		return
+	}
+	inclusive := req.TaxInclusive == nil || *req.TaxInclusive
+
+	service, err := s.store.SetServiceTax(r.Context(), id, *req.TaxRate, inclusive)
+	if err != nil {
+		if errors.Is(err, database.ErrServiceNotFound) {
+			http.Error(w, "Service not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error setting tax of service %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Set tax of service %d to %s (inclusive: %t)", id, service.TaxRate, service.TaxInclusive)
+	if err := json.NewEncoder(w).Encode(service); err != nil {
+		log.Printf("Error encoding service response: %v", err)
+	}
+}
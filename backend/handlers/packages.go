@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// GetPackageBalance handles GET /api/packages/:code so clients can see the
+// sessions left on their package
+func (s *Server) GetPackageBalance(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json")
+
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pkg, err := s.store.GetPackage(r.Context(), r.PathValue("code"))
+	if err != nil {
+		if errors.Is(err, database.ErrPackageNotFound) {
+			http.Error(w, "Package not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error getting session package: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Whose package it is is only shown to staff
+	pkg.Email, pkg.ClientName = "", ""
+	if err := json.NewEncoder(w).Encode(pkg); err != nil {
+		log.Printf("Error encoding session package response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Packages handles GET and POST /api/admin/packages
+func (s *Server) Packages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.listPackages(w, r)
+	case "POST":
+		s.createPackage(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listPackages responds with all session packages, or those of ?email=
+func (s *Server) listPackages(w http.ResponseWriter, r *http.Request) {
+	packages, err := s.store.ListPackages(r.Context(), r.URL.Query().Get("email"))
+	if err != nil {
+		log.Printf("Error listing session packages: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(packages); err != nil {
+		log.Printf("Error encoding session packages response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// createPackage validates and stores a session package from the request body
+func (s *Server) createPackage(w http.ResponseWriter, r *http.Request) {
+	var req models.PackageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validatePackageRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pkg, err := s.store.CreatePackage(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, database.ErrServiceNotFound) {
+			http.Error(w, "Service not found", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error creating session package for %s: %v", req.Email, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created package %d of %d x %s for %s", pkg.ID, pkg.Sessions, pkg.ServiceName, pkg.Email)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(pkg); err != nil {
+		log.Printf("Error encoding session package response: %v", err)
+	}
+}
+
+// validatePackageRequest checks a session package before it is created
+func validatePackageRequest(req *models.PackageRequest) error {
+	req.Email = strings.TrimSpace(req.Email)
+	req.ClientName = strings.TrimSpace(req.ClientName)
+	if !emailPattern.MatchString(req.Email) {
+		return &ValidationError{Field: "email", Message: "Invalid email address"}
+	}
+	if req.ServiceID <= 0 {
+		return &ValidationError{Field: "service_id", Message: "Invalid service ID"}
+	}
+	if req.Sessions < 1 || req.Sessions > 100 {
+		return &ValidationError{Field: "sessions", Message: "Sessions must be from 1 to 100"}
+	}
+	if req.ExpiresOn != "" {
+		if _, err := time.Parse("2006-01-02", req.ExpiresOn); err != nil {
+			return &ValidationError{Field: "expires_on", Message: "expires_on must be a date like 2026-12-31"}
+		}
+	}
+	return nil
+}
+
+// RestorePackageSession handles POST /api/admin/packages/:id/restore,
+// giving one session back to the package
+func (s *Server) RestorePackageSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid package ID", http.StatusBadRequest)
+		return
+	}
+
+	pkg, err := s.store.RestorePackageSession(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrPackageNotFound):
+			http.Error(w, "Package not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrPackageFull):
+			http.Error(w, "Package already has all its sessions", http.StatusConflict)
+		default:
+			log.Printf("Error restoring session to package %d: %v", id, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	log.Printf("Restored a session to package %d (%d of %d left)", pkg.ID, pkg.Remaining, pkg.Sessions)
+	if err := json.NewEncoder(w).Encode(pkg); err != nil {
+		log.Printf("Error encoding session package response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
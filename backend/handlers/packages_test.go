@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// createPackage creates a session package through the admin API
+func (e *testEnv) createPackage(t *testing.T, req models.PackageRequest) models.SessionPackage {
+	t.Helper()
+
+	rec := e.do(t, "POST", "/api/admin/packages", req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var pkg models.SessionPackage
+	if err := json.Unmarshal(rec.Body.Bytes(), &pkg); err != nil {
+		t.Fatalf("decode session package: %v", err)
+	}
+	return pkg
+}
+
+// sessionsLeft returns the sessions left on a package from the public lookup
+func (e *testEnv) sessionsLeft(t *testing.T, code string) int {
+	t.Helper()
+
+	var pkg models.SessionPackage
+	decode(t, e.do(t, "GET", "/api/packages/"+url.PathEscape(code), nil), &pkg)
+	return pkg.Remaining
+}
+
+// bookWithPackage books the next free slot of the service paying with the
+// package code and returns the response
+func (e *testEnv) bookWithPackage(t *testing.T, serviceID int, code string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	slot := e.availableSlot(t, serviceID)
+	reservation := e.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.PackageCode = code
+	return e.do(t, "POST", "/api/bookings", req)
+}
+
+func TestSessionPackageCoversBookings(t *testing.T) {
+	env := newTestEnv(t)
+	pkg := env.createPackage(t, models.PackageRequest{Email: " Jane@Example.com ", ServiceID: 1, Sessions: 2})
+	if pkg.Email != "jane@example.com" || pkg.Remaining != 2 || pkg.ServiceName == "" || !strings.HasPrefix(pkg.Code, "PACK-") {
+		t.Fatalf("unexpected package %+v", pkg)
+	}
+
+	// Without the code the booking is charged as usual
+	if booking := env.book(t, 1); booking.PackageID != 0 || booking.Total.Amount != 5000 {
+		t.Errorf("expected a normal booking without the package code, got %+v", booking)
+	}
+
+	var booking models.BookingDetail
+	decode(t, env.bookWithPackage(t, 1, strings.ToLower(pkg.Code)), &booking)
+	if booking.PackageID != pkg.ID || booking.Price.Amount != 5000 || booking.Total.Amount != 0 {
+		t.Errorf("expected the package to pay for the booking, got %+v", booking)
+	}
+	if left := env.sessionsLeft(t, pkg.Code); left != 1 {
+		t.Errorf("expected 1 session left, got %d", left)
+	}
+
+	// The package only pays for its own service
+	if rec := env.bookWithPackage(t, 2, pkg.Code); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for another service, got %d", rec.Code)
+	}
+
+	decode(t, env.bookWithPackage(t, 1, pkg.Code), &booking)
+	if rec := env.bookWithPackage(t, 1, pkg.Code); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 once the package is used up, got %d", rec.Code)
+	}
+
+	// Staff can give sessions back, but not more than were bought
+	restore := fmt.Sprintf("/api/admin/packages/%d/restore", pkg.ID)
+	var restored models.SessionPackage
+	decode(t, env.do(t, "POST", restore, nil), &restored)
+	if restored.Remaining != 1 {
+		t.Errorf("expected 1 session after restoring, got %d", restored.Remaining)
+	}
+	env.do(t, "POST", restore, nil)
+	if rec := env.do(t, "POST", restore, nil); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a full package, got %d", rec.Code)
+	}
+	if left := env.sessionsLeft(t, pkg.Code); left != 2 {
+		t.Errorf("expected no more than the 2 sessions bought, got %d", left)
+	}
+	if rec := env.do(t, "POST", "/api/admin/packages/99/restore", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown package, got %d", rec.Code)
+	}
+}
+
+func TestCancellingRestoresPackageSession(t *testing.T) {
+	env := newTestEnv(t)
+	pkg := env.createPackage(t, models.PackageRequest{Email: "jane@example.com", ServiceID: 1, Sessions: 1})
+
+	var booking models.BookingDetail
+	decode(t, env.bookWithPackage(t, 1, pkg.Code), &booking)
+	if left := env.sessionsLeft(t, pkg.Code); left != 0 {
+		t.Fatalf("expected the session to be used, got %d left", left)
+	}
+
+	cancel := fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID)
+	if rec := env.do(t, "POST", cancel, nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if left := env.sessionsLeft(t, pkg.Code); left != 1 {
+		t.Errorf("expected the session back after cancelling, got %d left", left)
+	}
+
+	// Cancelling again must not give a second session back
+	env.do(t, "POST", cancel, nil)
+	if left := env.sessionsLeft(t, pkg.Code); left != 1 {
+		t.Errorf("expected 1 session left, got %d", left)
+	}
+
+	// The restored session can be used again
+	decode(t, env.bookWithPackage(t, 1, pkg.Code), &booking)
+	if booking.PackageID != pkg.ID || booking.Total.Amount != 0 {
+		t.Errorf("expected the restored session to pay for the booking, got %+v", booking)
+	}
+}
+
+func TestExpiredSessionPackageIsNotUsed(t *testing.T) {
+	env := newTestEnv(t)
+	pkg := env.createPackage(t, models.PackageRequest{Email: "jane@example.com", ServiceID: 1, Sessions: 5, ExpiresOn: testNow.Format("2006-01-02")})
+
+	env.clock.Advance(24 * time.Hour)
+	slot := env.availableSlotOn(t, env.clock.Now().Format("2006-01-02"), 1)
+	reservation := env.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.PackageCode = pkg.Code
+	rec := env.do(t, "POST", "/api/bookings", req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("an expired package must not pay for a booking, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSessionPackageSkipsPayment(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	pkg := env.createPackage(t, models.PackageRequest{Email: "jane@example.com", ServiceID: 1, Sessions: 5})
+
+	var quote models.Quote
+	decode(t, env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, Email: "jane@example.com", PackageCode: pkg.Code, PromoCode: "IGNORED"}), &quote)
+	if quote.PackageID != pkg.ID || quote.Total.Amount != 0 {
+		t.Errorf("expected the quote to use the package, got %+v", quote)
+	}
+
+	var booking models.BookingDetail
+	decode(t, env.bookWithPackage(t, 1, pkg.Code), &booking)
+	if booking.Status != models.BookingStatusConfirmed || booking.CheckoutURL != "" {
+		t.Errorf("expected a confirmed booking without checkout, got %+v", booking)
+	}
+	if len(provider.Checkouts()) != 0 {
+		t.Error("no checkout should be created for a booking paid by a package")
+	}
+}
+
+func TestSessionPackageValidation(t *testing.T) {
+	env := newTestEnv(t)
+
+	for name, req := range map[string]models.PackageRequest{
+		"bad email":       {Email: "jane", ServiceID: 1, Sessions: 5},
+		"no service":      {Email: "jane@example.com", Sessions: 5},
+		"unknown service": {Email: "jane@example.com", ServiceID: 99, Sessions: 5},
+		"no sessions":     {Email: "jane@example.com", ServiceID: 1},
+		"bad expiry":      {Email: "jane@example.com", ServiceID: 1, Sessions: 5, ExpiresOn: "soon"},
+	} {
+		if rec := env.do(t, "POST", "/api/admin/packages", req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := env.do(t, "GET", "/api/packages/PACK-0000-0000", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown package code, got %d", rec.Code)
+	}
+}
+
+func TestSessionPackageCodeNeedsOwnerEmail(t *testing.T) {
+	env := newTestEnv(t)
+	pkg := env.createPackage(t, models.PackageRequest{Email: "someone@example.com", ClientName: "Someone Else", ServiceID: 1, Sessions: 5})
+
+	// The public lookup does not say whose package it is
+	rec := env.do(t, "GET", "/api/packages/"+pkg.Code, nil)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "someone") || strings.Contains(rec.Body.String(), "Someone") {
+		t.Errorf("expected a lookup without the owner, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// A booking with another email cannot use the code
+	if rec := env.bookWithPackage(t, 1, pkg.Code); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a booking with another email, got %d", rec.Code)
+	}
+	if left := env.sessionsLeft(t, pkg.Code); left != 5 {
+		t.Errorf("expected no session to be used, got %d left", left)
+	}
+}
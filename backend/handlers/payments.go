@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// maxWebhookBody bounds the size of accepted payment webhooks
+const maxWebhookBody = 64 << 10
+
+// createPaidBooking creates a booking that holds its slot while the client
+// pays, and responds with the checkout URL to send the client to
+func (s *Server) createPaidBooking(w http.ResponseWriter, r *http.Request, req models.BookingRequest) {
+	ctx := r.Context()
+
+	holdUntil := s.clock.Now().Add(s.config.Payment.Timeout)
+	booking, err := s.store.CreatePendingBooking(ctx, req, holdUntil)
+	if err != nil {
+		s.bookingError(w, req, err)
+		return
+	}
+
+	detail, err := s.store.GetBookingByID(ctx, booking.ID)
+	if err != nil {
+		s.abandonPayment(w, booking.ID, fmt.Errorf("failed to load booking: %v", err))
+		return
+	}
+
+	description := fmt.Sprintf("%s, %s %s", detail.ServiceName, detail.Date, detail.TimeSlot)
+	if s.config.Payment.DepositPercent < 100 {
+		description += fmt.Sprintf(" (%d%% deposit)", s.config.Payment.DepositPercent)
+	}
+	amount := s.config.Payment.DepositAmount(detail.Total.Amount)
+
+	checkout, err := s.config.Payments.CreateCheckout(ctx, payment.CheckoutRequest{
+		BookingID:   detail.ID,
+		Reference:   detail.Reference,
+		Description: description,
+		Email:       detail.Email,
+		Amount:      amount,
+		Currency:    detail.Total.Currency,
+		SuccessURL:  fmt.Sprintf("%s/confirmation.html?id=%d", s.config.Payment.PublicURL, detail.ID),
+		CancelURL:   s.config.Payment.PublicURL + "/?payment=cancelled",
+		ExpiresAt:   holdUntil,
+	})
+	if err != nil {
+		s.abandonPayment(w, booking.ID, err)
+		return
+	}
+
+	err = s.store.AttachCheckout(ctx, models.Payment{
+		BookingID:  detail.ID,
+		Provider:   s.config.Payments.Name(),
+		CheckoutID: checkout.ID,
+		Amount:     amount,
+		Currency:   detail.Total.Currency,
+	})
+	if err != nil {
+		s.abandonPayment(w, booking.ID, err)
+		return
+	}
+
+	detail.CheckoutURL = checkout.URL
+	if err := json.NewEncoder(w).Encode(detail); err != nil {
+		log.Printf("Error encoding booking response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created booking %d (reference: %s) pending payment until %s",
+		detail.ID, detail.Reference, holdUntil.UTC().Format("15:04:05"))
+}
+
+// abandonPayment releases the slot of a booking whose checkout could not be started
+func (s *Server) abandonPayment(w http.ResponseWriter, bookingID int, cause error) {
+	log.Printf("Error starting payment for booking %d: %v", bookingID, cause)
+	// The request may have been cancelled; releasing must still happen
+	if err := s.store.ReleaseBooking(context.Background(), bookingID); err != nil {
+		log.Printf("Error releasing booking %d: %v", bookingID, err)
+	}
+	http.Error(w, "Online payment is unavailable, please try again later", http.StatusBadGateway)
+}
+
+// PaymentWebhook handles POST /api/payments/webhook
+func (s *Server) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	provider := s.config.Payments
+	if provider == nil {
+		http.Error(w, "Online payment is disabled", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	event, err := provider.ParseWebhook(r.Header, body)
+	if err != nil {
+		log.Printf("Rejected payment webhook: %v", err)
+		http.Error(w, "Invalid webhook", http.StatusBadRequest)
+		return
+	}
+	if event == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ctx := r.Context()
+	switch event.Type {
+	case payment.EventPaid:
+		booking, err := s.store.ConfirmPayment(ctx, event.CheckoutID, event.PaymentID)
+		switch {
+		case errors.Is(err, database.ErrPaymentTooLate):
+			log.Printf("Payment %s for booking %s arrived after its hold was released", event.CheckoutID, booking.Reference)
+			s.refundLatePayment(r.Context(), booking, event.CheckoutID)
+		case err != nil:
+			s.webhookError(w, fmt.Errorf("failed to confirm payment %s: %w", event.CheckoutID, err))
+			return
+		default:
+			log.Printf("Booking %s confirmed by payment %s", booking.Reference, event.CheckoutID)
+		}
+	case payment.EventFailed, payment.EventExpired:
+		status := models.PaymentStatusFailed
+		if event.Type == payment.EventExpired {
+			status = models.PaymentStatusExpired
+		}
+		if err := s.store.FailPayment(ctx, event.CheckoutID, status); err != nil {
+			s.webhookError(w, fmt.Errorf("failed to record %s payment %s: %w", status, event.CheckoutID, err))
+			return
+		}
+		log.Printf("Payment %s %s", event.CheckoutID, status)
+	case payment.EventRefund:
+		if err := s.updateRefund(ctx, provider.Name(), event); err != nil {
+			s.webhookError(w, fmt.Errorf("failed to update refund %s: %w", event.RefundID, err))
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// webhookError answers a webhook that could not be processed. Checkouts and
+// refunds this server does not know about are acknowledged so the provider
+// stops retrying; other failures return 500 so the provider delivers the
+// event again.
+func (s *Server) webhookError(w http.ResponseWriter, err error) {
+	log.Printf("Error processing payment webhook: %v", err)
+	if errors.Is(err, database.ErrPaymentNotFound) || errors.Is(err, database.ErrRefundNotFound) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
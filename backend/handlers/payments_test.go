@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// newPaymentTestEnv creates a test environment that takes payment through a fake provider
+func newPaymentTestEnv(t *testing.T) (*testEnv, *payment.FakeProvider) {
+	t.Helper()
+
+	provider := payment.NewFakeProvider("http://localhost:8080")
+	env := newTestEnvAt(t, testNow, func(c *Config) {
+		c.Payments = provider
+		c.Payment = payment.Config{
+			Provider:       payment.ProviderFake,
+			DepositPercent: 100,
+			Timeout:        30 * time.Minute,
+			PublicURL:      "http://localhost:8080",
+		}
+	})
+	return env, provider
+}
+
+// startPaidBooking books the first slot of the service and returns the
+// booking pending payment together with its slot
+func (e *testEnv) startPaidBooking(t *testing.T, serviceID int) (models.BookingDetail, models.TimeSlot) {
+	t.Helper()
+
+	slot := e.availableSlot(t, serviceID)
+	reservation := e.reserve(t, slot.ID)
+
+	rec := e.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var booking models.BookingDetail
+	decode(t, rec, &booking)
+	return booking, slot
+}
+
+// slotAvailable reports whether the slot is offered again
+func (e *testEnv) slotAvailable(t *testing.T, slot models.TimeSlot) bool {
+	t.Helper()
+
+	var slots []models.TimeSlot
+	decode(t, e.do(t, "GET", fmt.Sprintf("/api/slots?date=%s&service_id=%d", slot.Date, slot.ServiceID), nil), &slots)
+	for _, s := range slots {
+		if s.ID == slot.ID {
+			return s.Available
+		}
+	}
+	t.Fatalf("slot %d not listed", slot.ID)
+	return false
+}
+
+func TestPaidBookingConfirmedByWebhook(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	created := metrics.BookingsCreated.Value()
+	booking, slot := env.startPaidBooking(t, 1)
+
+	if metrics.BookingsCreated.Value() != created {
+		t.Error("a booking awaiting payment must not count as created")
+	}
+	if booking.Status != models.BookingStatusPendingPayment || booking.HoldExpiresAt == nil {
+		t.Fatalf("expected a booking pending payment, got %+v", booking)
+	}
+	wantURL := fmt.Sprintf("http://localhost:8080/confirmation.html?id=%d", booking.ID)
+	if booking.CheckoutURL != wantURL {
+		t.Errorf("expected checkout url %q, got %q", wantURL, booking.CheckoutURL)
+	}
+	checkouts := provider.Checkouts()
+	if len(checkouts) != 1 || checkouts[0].Amount != booking.Price.Amount || checkouts[0].Currency != booking.Price.Currency {
+		t.Fatalf("unexpected checkouts %+v", checkouts)
+	}
+	if env.slotAvailable(t, slot) {
+		t.Error("slot should be held while the client pays")
+	}
+
+	// Nothing is sent before the payment arrives
+	ctx := context.Background()
+	emails, err := env.store.ListEmails(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 0 {
+		t.Errorf("expected no emails before payment, got %d", len(emails))
+	}
+
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	for i := 0; i < 2; i++ {
+		if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if got := metrics.BookingsCreated.Value() - created; got != 1 {
+		t.Errorf("expected the paid booking counted once, got %v", got)
+	}
+
+	var confirmed models.BookingDetail
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", booking.ID), nil), &confirmed)
+	if confirmed.Status != models.BookingStatusConfirmed || confirmed.HoldExpiresAt != nil {
+		t.Errorf("expected a confirmed booking, got %+v", confirmed)
+	}
+
+	emails, err = env.store.ListEmails(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Kind != models.EmailKindBookingConfirmation {
+		t.Errorf("expected one confirmation email despite the repeated webhook, got %+v", emails)
+	}
+
+	payments, err := env.store.ListPayments(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 || payments[0].Status != models.PaymentStatusPaid || payments[0].PaymentRef != "pi_1" {
+		t.Errorf("unexpected payments %+v", payments)
+	}
+}
+
+func TestFailedPaymentReleasesSlot(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+	booking, slot := env.startPaidBooking(t, 1)
+
+	webhook := map[string]string{"type": payment.EventFailed, "checkout_id": "fake_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var released models.BookingDetail
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", booking.ID), nil), &released)
+	if released.Status != models.BookingStatusCancelled {
+		t.Errorf("expected a cancelled booking, got %q", released.Status)
+	}
+	if !env.slotAvailable(t, slot) {
+		t.Error("slot should be bookable again after the payment failed")
+	}
+
+	// A late success is recorded but cannot revive the booking
+	webhook = map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", booking.ID), nil), &released)
+	if released.Status != models.BookingStatusCancelled {
+		t.Errorf("late payment must not confirm a released booking, got %q", released.Status)
+	}
+}
+
+func TestPaymentHoldExpires(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+	booking, slot := env.startPaidBooking(t, 1)
+	ctx := context.Background()
+
+	env.clock.Advance(29 * time.Minute)
+	if n, err := env.store.ReleaseExpiredHolds(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no expired holds, got %d, %v", n, err)
+	}
+
+	env.clock.Advance(2 * time.Minute)
+	if n, err := env.store.ReleaseExpiredHolds(ctx); err != nil || n != 1 {
+		t.Fatalf("expected one expired hold, got %d, %v", n, err)
+	}
+	if !env.slotAvailable(t, slot) {
+		t.Error("slot should be bookable again after the hold expired")
+	}
+
+	payments, err := env.store.ListPayments(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 || payments[0].Status != models.PaymentStatusExpired {
+		t.Errorf("unexpected payments %+v", payments)
+	}
+}
+
+func TestCheckoutFailureReleasesSlot(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	provider.FailWith(errors.New("provider down"))
+
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+	rec := env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !env.slotAvailable(t, slot) {
+		t.Error("slot should be bookable again after the checkout failed")
+	}
+}
+
+func TestPaymentWebhookErrors(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+
+	if rec := env.do(t, "POST", "/api/payments/webhook", "not json"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid body, got %d", rec.Code)
+	}
+	// Unknown checkouts and event types are acknowledged
+	unknown := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_99"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", unknown); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for an unknown checkout, got %d", rec.Code)
+	}
+	if rec := env.do(t, "POST", "/api/payments/webhook", map[string]string{"type": "refunded"}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for an ignored event, got %d", rec.Code)
+	}
+
+	disabled := newTestEnv(t)
+	if rec := disabled.do(t, "POST", "/api/payments/webhook", unknown); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when payments are disabled, got %d", rec.Code)
+	}
+}
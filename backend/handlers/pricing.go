@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// PricingRules handles GET and POST /api/admin/pricing-rules
+func (s *Server) PricingRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.listPricingRules(w, r)
+	case "POST":
+		s.createPricingRule(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listPricingRules responds with all pricing rules
+func (s *Server) listPricingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.store.ListPricingRules(r.Context())
+	if err != nil {
+		log.Printf("Error listing pricing rules: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(rules); err != nil {
+		log.Printf("Error encoding pricing rules response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// createPricingRule validates and stores a pricing rule from the request body
+func (s *Server) createPricingRule(w http.ResponseWriter, r *http.Request) {
+	var req models.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validatePricingRule(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := s.store.CreatePricingRule(r.Context(), req)
+	if err != nil {
+		log.Printf("Error creating pricing rule %q: %v", req.Name, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created pricing rule %d %q (%+d%%)", rule.ID, rule.Name, rule.Percent)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rule); err != nil {
+		log.Printf("Error encoding pricing rule response: %v", err)
+	}
+}
+
+// validatePricingRule checks a pricing rule before it is created and
+// normalizes its times to HH:MM
+func validatePricingRule(p *models.PricingRule) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return &ValidationError{Field: "name", Message: "Name is required"}
+	}
+	if p.Percent == 0 || p.Percent < -100 || p.Percent > 100 {
+		return &ValidationError{Field: "percent", Message: "Percent must be from -100 to 100 and not zero"}
+	}
+	for _, day := range p.Days {
+		if day < 0 || day > 6 {
+			return &ValidationError{Field: "days", Message: "Days must be from 0 (Sunday) to 6 (Saturday)"}
+		}
+	}
+
+	if p.StartTime != "" {
+		start, err := time.Parse("15:04", p.StartTime)
+		if err != nil {
+			return &ValidationError{Field: "start_time", Message: "start_time must be a time like 18:00"}
+		}
+		p.StartTime = start.Format("15:04")
+	}
+	if p.EndTime != "" {
+		end, err := time.Parse("15:04", p.EndTime)
+		if err != nil {
+			return &ValidationError{Field: "end_time", Message: "end_time must be a time like 18:00"}
+		}
+		p.EndTime = end.Format("15:04")
+	}
+	if p.StartTime != "" && p.EndTime != "" && p.EndTime <= p.StartTime {
+		return &ValidationError{Field: "end_time", Message: "end_time must be after start_time"}
+	}
+
+	if p.MinLeadHours < 0 || p.MaxLeadHours < 0 {
+		return &ValidationError{Field: "min_lead_hours", Message: "Lead times cannot be negative"}
+	}
+	if p.MinLeadHours > 0 && p.MaxLeadHours > 0 && p.MaxLeadHours <= p.MinLeadHours {
+		return &ValidationError{Field: "max_lead_hours", Message: "max_lead_hours must be more than min_lead_hours"}
+	}
+	for _, id := range p.ServiceIDs {
+		if id <= 0 {
+			return &ValidationError{Field: "service_ids", Message: "Invalid service ID"}
+		}
+	}
+	return nil
+}
+
+// DeletePricingRule handles DELETE /api/admin/pricing-rules/:id
+func (s *Server) DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE method
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid pricing rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeletePricingRule(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrPricingRuleNotFound) {
+			http.Error(w, "Pricing rule not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deleting pricing rule %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Deleted pricing rule %d", id)
+}
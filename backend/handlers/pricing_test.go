@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+// createPricingRule creates a pricing rule through the admin API
+func (e *testEnv) createPricingRule(t *testing.T, rule models.PricingRule) models.PricingRule {
+	t.Helper()
+
+	rec := e.do(t, "POST", "/api/admin/pricing-rules", rule)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.PricingRule
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode pricing rule: %v", err)
+	}
+	return created
+}
+
+// slotsByTime returns the slots of the service on date keyed by start time
+func (e *testEnv) slotsByTime(t *testing.T, date string, serviceID int) map[string]models.TimeSlot {
+	t.Helper()
+
+	var slots []models.TimeSlot
+	decode(t, e.do(t, "GET", fmt.Sprintf("/api/slots?date=%s&service_id=%d", date, serviceID), nil), &slots)
+	byTime := make(map[string]models.TimeSlot, len(slots))
+	for _, slot := range slots {
+		byTime[slot.Time] = slot
+	}
+	return byTime
+}
+
+func TestSlotPricesFollowPricingRules(t *testing.T) {
+	env := newTestEnv(t)
+	today := testNow.Format("2006-01-02")
+	env.createPricingRule(t, models.PricingRule{Name: "Weekday mornings", Days: []int{1, 2, 3, 4, 5}, EndTime: "12:00", Percent: -15})
+	env.createPricingRule(t, models.PricingRule{Name: "Evenings", StartTime: "16:00", Percent: 10})
+	env.createPricingRule(t, models.PricingRule{Name: "Last minute", MaxLeadHours: 3, ServiceIDs: []int{1}, Percent: -20})
+
+	slots := env.slotsByTime(t, today, 1)
+	for clock, want := range map[string]struct {
+		amount     int64
+		adjustment int
+	}{
+		"09:00": {3250, -35}, // morning and last minute
+		"12:00": {5000, 0},
+		"16:00": {5500, 10},
+	} {
+		slot := slots[clock]
+		if slot.Price.Amount != want.amount || slot.Adjustment != want.adjustment {
+			t.Errorf("%s: expected %d (%+d%%), got %d (%+d%%)", clock, want.amount, want.adjustment, slot.Price.Amount, slot.Adjustment)
+		}
+	}
+
+	// The last-minute rule is for service 1 only
+	if slot := env.slotsByTime(t, today, 2)["09:00"]; slot.Adjustment != -15 {
+		t.Errorf("expected only the morning rule for service 2, got %+d%%", slot.Adjustment)
+	}
+
+	var rules []models.PricingRule
+	decode(t, env.do(t, "GET", "/api/admin/pricing-rules", nil), &rules)
+	if len(rules) != 3 || len(rules[0].Days) != 5 || len(rules[2].ServiceIDs) != 1 {
+		t.Errorf("unexpected pricing rules %+v", rules)
+	}
+}
+
+func TestReservationLocksSlotPrice(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	peak := env.createPricingRule(t, models.PricingRule{Name: "Peak", StartTime: slot.Time, Percent: 10})
+
+	reservation := env.reserve(t, slot.ID)
+	if reservation.Price.Amount != 5500 {
+		t.Fatalf("expected the reservation to lock 5500, got %d", reservation.Price.Amount)
+	}
+
+	// Dropping the rule does not change the price of the reserved slot
+	if rec := env.do(t, "DELETE", fmt.Sprintf("/api/admin/pricing-rules/%d", peak.ID), nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	var quote models.Quote
+	decode(t, env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, ReservationID: reservation.ReservationID}), &quote)
+	if quote.Total.Amount != 5500 {
+		t.Errorf("expected the quote to use the locked price, got %+v", quote)
+	}
+
+	// A reservation is only good for the service, date and time of its slot
+	if rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 2, ReservationID: reservation.ReservationID}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a quote of another service, got %d", rec.Code)
+	}
+	for name, change := range map[string]func(*models.BookingRequest){
+		"service": func(req *models.BookingRequest) { req.ServiceID = 2 },
+		"date":    func(req *models.BookingRequest) { req.Date = "2099-01-01" },
+		"time":    func(req *models.BookingRequest) { req.TimeSlot = "23:00" },
+	} {
+		req := bookingRequest(reservation.ReservationID, slot)
+		change(&req)
+		if rec := env.do(t, "POST", "/api/bookings", req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400 for a booking that does not match the reservation, got %d", name, rec.Code)
+		}
+	}
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot)), &booking)
+	if booking.Price.Amount != 5500 || booking.Total.Amount != 5500 {
+		t.Errorf("expected the booking at the locked price, got %+v", booking)
+	}
+
+	if rec := env.do(t, "DELETE", fmt.Sprintf("/api/admin/pricing-rules/%d", peak.ID), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted rule, got %d", rec.Code)
+	}
+}
+
+func TestPricingRuleValidation(t *testing.T) {
+	env := newTestEnv(t)
+
+	for name, rule := range map[string]models.PricingRule{
+		"no name":         {Percent: 10},
+		"no percent":      {Name: "Flat"},
+		"percent too big": {Name: "Double", Percent: 150},
+		"bad day":         {Name: "Someday", Days: []int{7}, Percent: 10},
+		"bad time":        {Name: "Evenings", StartTime: "6pm", Percent: 10},
+		"empty window":    {Name: "Never", StartTime: "18:00", EndTime: "09:00", Percent: 10},
+		"negative lead":   {Name: "Past", MinLeadHours: -1, Percent: 10},
+		"lead window":     {Name: "Never", MinLeadHours: 48, MaxLeadHours: 24, Percent: 10},
+		"bad service":     {Name: "Nothing", ServiceIDs: []int{0}, Percent: 10},
+	} {
+		if rec := env.do(t, "POST", "/api/admin/pricing-rules", rule); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	// With both times wrong the same field is always reported
+	for i := 0; i < 10; i++ {
+		rec := env.do(t, "POST", "/api/admin/pricing-rules", models.PricingRule{Name: "Evenings", StartTime: "6pm", EndTime: "9pm", Percent: 10})
+		if !strings.HasPrefix(rec.Body.String(), "start_time") {
+			t.Fatalf("expected the start_time error, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+// promoCodePattern restricts promo codes to characters that are easy to type
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// redemptionErrorMessage returns the client-facing message for a promo code
+// or gift voucher that cannot be used, and false for other errors
+func redemptionErrorMessage(err error, locale i18n.Locale) (string, bool) {
+	switch {
+	case errors.Is(err, database.ErrPromoNotFound):
+		return locale.T("promo.not_found"), true
+	case errors.Is(err, database.ErrPromoNotValidNow):
+		return locale.T("promo.not_valid_now"), true
+	case errors.Is(err, database.ErrPromoUsedUp):
+		return locale.T("promo.used_up"), true
+	case errors.Is(err, database.ErrPromoAlreadyUsed):
+		return locale.T("promo.already_used"), true
+	case errors.Is(err, database.ErrPromoNotApplicable):
+		return locale.T("promo.not_applicable"), true
+	case errors.Is(err, database.ErrVoucherNotFound):
+		return locale.T("voucher.not_found"), true
+	case errors.Is(err, database.ErrVoucherExpired):
+		return locale.T("voucher.expired"), true
+	case errors.Is(err, database.ErrVoucherUsedUp):
+		return locale.T("voucher.used_up"), true
+	case errors.Is(err, database.ErrVoucherNotApplicable):
+		return locale.T("voucher.not_applicable"), true
+	case errors.Is(err, database.ErrPackageNotFound):
+		return locale.T("package.not_found"), true
+	case errors.Is(err, database.ErrPackageExpired):
+		return locale.T("package.expired"), true
+	case errors.Is(err, database.ErrPackageUsedUp):
+		return locale.T("package.used_up"), true
+	case errors.Is(err, database.ErrPackageNotApplicable):
+		return locale.T("package.not_applicable"), true
+	}
+	return "", false
+}
+
+// GetQuote handles POST /api/quote
+func (s *Server) GetQuote(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json")
+
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	locale := i18n.Parse(req.Locale)
+	if req.Locale == "" {
+		locale = i18n.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+	if req.ServiceID <= 0 {
+		http.Error(w, locale.T("validation.service_invalid"), http.StatusBadRequest)
+		return
+	}
+
+	quote, err := s.store.Quote(r.Context(), req)
+	if err != nil {
+		if message, ok := redemptionErrorMessage(err, locale); ok {
+			http.Error(w, message, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, database.ErrServiceNotFound) {
+			http.Error(w, locale.T("validation.service_invalid"), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, database.ErrReservationMismatch) {
+			http.Error(w, locale.T("validation.reservation_mismatch"), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error quoting service %d: %v", req.ServiceID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(quote); err != nil {
+		log.Printf("Error encoding quote response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// PromoCodes handles GET and POST /api/admin/promo-codes
+func (s *Server) PromoCodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.listPromoCodes(w, r)
+	case "POST":
+		s.createPromoCode(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listPromoCodes responds with all promo codes and their uses
+func (s *Server) listPromoCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := s.store.ListPromoCodes(r.Context())
+	if err != nil {
+		log.Printf("Error listing promo codes: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(codes); err != nil {
+		log.Printf("Error encoding promo codes response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// createPromoCode validates and stores a promo code from the request body
+func (s *Server) createPromoCode(w http.ResponseWriter, r *http.Request) {
+	var req models.PromoCode
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Code = database.NormalizePromoCode(req.Code)
+	if err := validatePromoCode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	promo, err := s.store.CreatePromoCode(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, database.ErrPromoExists) {
+			http.Error(w, "Promo code already exists", http.StatusConflict)
+			return
+		}
+		log.Printf("Error creating promo code %s: %v", req.Code, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created promo code %s (%s %d)", promo.Code, promo.Kind, promo.Value)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(promo); err != nil {
+		log.Printf("Error encoding promo code response: %v", err)
+	}
+}
+
+// validatePromoCode checks a promo code before it is created and
+// normalizes its currency
+func validatePromoCode(p *models.PromoCode) error {
+	if !promoCodePattern.MatchString(p.Code) {
+		return &ValidationError{Field: "code", Message: "Code must be 3-32 letters, digits, dashes or underscores"}
+	}
+
+	switch p.Kind {
+	case models.PromoKindPercent:
+		if p.Value < 1 || p.Value > 100 {
+			return &ValidationError{Field: "value", Message: "Percentage must be from 1 to 100"}
+		}
+		p.Currency = ""
+	case models.PromoKindFixed:
+		if p.Value <= 0 {
+			return &ValidationError{Field: "value", Message: "Amount must be positive"}
+		}
+		if p.Currency != "" {
+			currency, err := models.ParseCurrency(p.Currency)
+			if err != nil {
+				return &ValidationError{Field: "currency", Message: "Invalid currency code"}
+			}
+			p.Currency = currency
+		}
+	default:
+		return &ValidationError{Field: "kind", Message: fmt.Sprintf("Kind must be %q or %q", models.PromoKindPercent, models.PromoKindFixed)}
+	}
+
+	if p.ValidFrom != nil && p.ValidUntil != nil && !p.ValidUntil.After(*p.ValidFrom) {
+		return &ValidationError{Field: "valid_until", Message: "valid_until must be after valid_from"}
+	}
+	if p.MaxUses < 0 || p.MaxUsesPerEmail < 0 {
+		return &ValidationError{Field: "max_uses", Message: "Usage limits cannot be negative"}
+	}
+	for _, id := range p.ServiceIDs {
+		if id <= 0 {
+			return &ValidationError{Field: "service_ids", Message: "Invalid service ID"}
+		}
+	}
+	return nil
+}
+
+// DeactivatePromoCode handles DELETE /api/admin/promo-codes/:id
+func (s *Server) DeactivatePromoCode(w http.ResponseWriter, r *http.Request) {
+	// Only allow DELETE method
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid promo code ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeactivatePromoCode(r.Context(), id); err != nil {
+		if errors.Is(err, database.ErrPromoNotFound) {
+			http.Error(w, "Promo code not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error deactivating promo code %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Deactivated promo code %d", id)
+}
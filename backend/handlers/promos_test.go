@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// createPromo creates a promo code through the admin API
+func (e *testEnv) createPromo(t *testing.T, promo models.PromoCode) models.PromoCode {
+	t.Helper()
+
+	rec := e.do(t, "POST", "/api/admin/promo-codes", promo)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created models.PromoCode
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode promo code: %v", err)
+	}
+	return created
+}
+
+// bookWithPromo books the first slot of the service with a promo code
+func (e *testEnv) bookWithPromo(t *testing.T, serviceID int, code, email string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	slot := e.availableSlot(t, serviceID)
+	reservation := e.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.PromoCode = code
+	req.Email = email
+	return e.do(t, "POST", "/api/bookings", req)
+}
+
+func TestQuoteAppliesPromoCode(t *testing.T) {
+	env := newTestEnv(t)
+	env.createPromo(t, models.PromoCode{Code: "spring20", Kind: models.PromoKindPercent, Value: 20})
+	env.createPromo(t, models.PromoCode{Code: "TENOFF", Kind: models.PromoKindFixed, Value: 1000})
+
+	tests := []struct {
+		code     string
+		discount int64
+	}{
+		{"", 0},
+		{" Spring20 ", 1000},
+		{"TENOFF", 1000},
+	}
+	for _, tt := range tests {
+		var quote models.Quote
+		decode(t, env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, PromoCode: tt.code}), &quote)
+		if quote.Price.Amount != 5000 || quote.Discount.Amount != tt.discount || quote.Total.Amount != 5000-tt.discount {
+			t.Errorf("%q: unexpected quote %+v", tt.code, quote)
+		}
+	}
+
+	rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, PromoCode: "NOPE", Locale: "et"})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "See sooduskood ei kehti") {
+		t.Errorf("expected localized 400 for an unknown code, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBookingWithPromoCode(t *testing.T) {
+	env := newTestEnv(t)
+	env.createPromo(t, models.PromoCode{Code: "WELCOME", Kind: models.PromoKindPercent, Value: 10, MaxUsesPerEmail: 1})
+
+	rec := env.bookWithPromo(t, 1, "welcome", "jane@example.com")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var booking models.BookingDetail
+	decode(t, rec, &booking)
+	if booking.PromoCode != "WELCOME" || booking.Discount.Amount != 500 || booking.Total.Amount != 4500 {
+		t.Errorf("expected the discount on the booking, got %+v", booking)
+	}
+
+	// The same client cannot use it twice, another client can
+	rec = env.bookWithPromo(t, 1, "WELCOME", "JANE@example.com")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "already used") {
+		t.Errorf("expected 400 for a second use, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := env.bookWithPromo(t, 1, "WELCOME", "john@example.com"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for another client, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Cancelling gives the use back
+	env.do(t, "POST", fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID), nil)
+	if rec := env.bookWithPromo(t, 1, "WELCOME", "jane@example.com"); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 after cancelling, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPromoCodeRestrictions(t *testing.T) {
+	env := newTestEnv(t)
+	later := testNow.Add(48 * time.Hour)
+	env.createPromo(t, models.PromoCode{Code: "ONCE", Kind: models.PromoKindPercent, Value: 50, MaxUses: 1})
+	env.createPromo(t, models.PromoCode{Code: "HOTSTONE", Kind: models.PromoKindPercent, Value: 50, ServiceIDs: []int{3}})
+	env.createPromo(t, models.PromoCode{Code: "LATER", Kind: models.PromoKindPercent, Value: 50, ValidFrom: &later})
+	env.createPromo(t, models.PromoCode{Code: "DOLLARS", Kind: models.PromoKindFixed, Value: 500, Currency: "usd"})
+	off := env.createPromo(t, models.PromoCode{Code: "OFF", Kind: models.PromoKindPercent, Value: 50})
+	if rec := env.do(t, "DELETE", fmt.Sprintf("/api/admin/promo-codes/%d", off.ID), nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+
+	if rec := env.bookWithPromo(t, 1, "ONCE", "jane@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		code      string
+		serviceID int
+		want      string
+	}{
+		{"ONCE", 1, "used up"},
+		{"HOTSTONE", 1, "does not apply"},
+		{"LATER", 1, "cannot be used at this time"},
+		{"DOLLARS", 1, "does not apply"},
+		{"OFF", 1, "not valid"},
+	}
+	for _, tt := range tests {
+		rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: tt.serviceID, PromoCode: tt.code})
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: expected 400 %q, got %d %q", tt.code, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 3, PromoCode: "HOTSTONE"}); rec.Code != http.StatusOK {
+		t.Errorf("expected HOTSTONE to apply to service 3, got %d", rec.Code)
+	}
+
+	var codes []models.PromoCode
+	decode(t, env.do(t, "GET", "/api/admin/promo-codes", nil), &codes)
+	for _, code := range codes {
+		if code.Code == "ONCE" && code.Uses != 1 {
+			t.Errorf("expected ONCE to have one use, got %d", code.Uses)
+		}
+		if code.Code == "OFF" && code.Active {
+			t.Error("expected OFF to be inactive")
+		}
+	}
+}
+
+func TestCreatePromoCodeValidation(t *testing.T) {
+	env := newTestEnv(t)
+	env.createPromo(t, models.PromoCode{Code: "SPRING", Kind: models.PromoKindPercent, Value: 10})
+
+	for name, promo := range map[string]models.PromoCode{
+		"short code":     {Code: "AB", Kind: models.PromoKindPercent, Value: 10},
+		"bad kind":       {Code: "ABC", Kind: "free", Value: 10},
+		"over 100%":      {Code: "ABC", Kind: models.PromoKindPercent, Value: 150},
+		"zero amount":    {Code: "ABC", Kind: models.PromoKindFixed},
+		"empty window":   {Code: "ABC", Kind: models.PromoKindPercent, Value: 10, ValidFrom: &testNow, ValidUntil: &testNow},
+		"negative limit": {Code: "ABC", Kind: models.PromoKindPercent, Value: 10, MaxUses: -1},
+	} {
+		if rec := env.do(t, "POST", "/api/admin/promo-codes", promo); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+
+	duplicate := models.PromoCode{Code: "spring", Kind: models.PromoKindPercent, Value: 10}
+	if rec := env.do(t, "POST", "/api/admin/promo-codes", duplicate); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate code, got %d", rec.Code)
+	}
+}
+
+func TestFreeBookingSkipsPayment(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	env.createPromo(t, models.PromoCode{Code: "GIFT", Kind: models.PromoKindPercent, Value: 100})
+
+	rec := env.bookWithPromo(t, 1, "GIFT", "jane@example.com")
+	var booking models.BookingDetail
+	decode(t, rec, &booking)
+	if booking.Status != models.BookingStatusConfirmed || booking.CheckoutURL != "" || booking.Total.Amount != 0 {
+		t.Errorf("expected a confirmed free booking, got %+v", booking)
+	}
+	if len(provider.Checkouts()) != 0 {
+		t.Error("no checkout should be created for a free booking")
+	}
+}
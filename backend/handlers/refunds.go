@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// lateRefundReason is recorded on refunds of payments that arrived after the booking was released
+const lateRefundReason = "Payment arrived after the booking was released"
+
+// Refunds handles GET and POST /api/admin/refunds
+func (s *Server) Refunds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.listRefunds(w, r)
+	case "POST":
+		s.issueRefund(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listRefunds responds with the refunds ledger, filtered by ?booking_id= and ?status=
+func (s *Server) listRefunds(w http.ResponseWriter, r *http.Request) {
+	var bookingID int
+	if value := r.URL.Query().Get("booking_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid booking_id parameter", http.StatusBadRequest)
+			return
+		}
+		bookingID = id
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.RefundStatusPending, models.RefundStatusSucceeded, models.RefundStatusFailed:
+	default:
+		http.Error(w, "Invalid status parameter", http.StatusBadRequest)
+		return
+	}
+
+	refunds, err := s.store.ListRefunds(r.Context(), bookingID, status)
+	if err != nil {
+		log.Printf("Error listing refunds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(refunds); err != nil {
+		log.Printf("Error encoding refunds response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// issueRefund records a refund and, unless it was paid back by hand, sends
+// it to the payment provider. A refund the provider turns down stays in the
+// ledger as failed and the response is 502.
+func (s *Server) issueRefund(w http.ResponseWriter, r *http.Request) {
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateRefundRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	refunder := s.refunder()
+	if !req.Manual && refunder == nil {
+		http.Error(w, "The payment provider cannot refund; record a manual refund instead", http.StatusConflict)
+		return
+	}
+
+	ctx := r.Context()
+	refund, err := s.store.CreateRefund(ctx, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrBookingNotFound):
+			http.Error(w, "Booking not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrRefundTooLarge):
+			http.Error(w, "Amount is more than what is left to refund", http.StatusConflict)
+		case errors.Is(err, database.ErrNoPaymentToRefund):
+			http.Error(w, "Booking has no online payment to refund", http.StatusConflict)
+		case errors.Is(err, database.ErrPaymentRefMissing):
+			http.Error(w, "The payment has no provider reference; record a manual refund instead", http.StatusConflict)
+		case errors.Is(err, database.ErrBookingNotCancelled):
+			http.Error(w, "Booking is not cancelled", http.StatusConflict)
+		default:
+			log.Printf("Error recording refund for booking %d: %v", req.BookingID, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if refund.Status == models.RefundStatusPending {
+		var providerErr error
+		refund, providerErr = s.sendRefund(ctx, refunder, refund)
+		if refund == nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if providerErr != nil {
+			status = http.StatusBadGateway
+		}
+	}
+	log.Printf("Refund %d of %s for booking %s is %s", refund.ID, refund.Amount, refund.Reference, refund.Status)
+
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(refund); err != nil {
+		log.Printf("Error encoding refund response: %v", err)
+	}
+}
+
+// validateRefundRequest checks a refund before it is recorded and trims its text fields
+func validateRefundRequest(req *models.RefundRequest) error {
+	req.Reason = strings.TrimSpace(req.Reason)
+	req.ProviderRef = strings.TrimSpace(req.ProviderRef)
+	switch {
+	case req.BookingID <= 0:
+		return &ValidationError{Field: "booking_id", Message: "Invalid booking ID"}
+	case req.Amount <= 0:
+		return &ValidationError{Field: "amount", Message: "Amount must be positive"}
+	case req.Reason == "":
+		return &ValidationError{Field: "reason", Message: "Reason is required"}
+	case !req.Manual && req.ProviderRef != "":
+		return &ValidationError{Field: "provider_ref", Message: "provider_ref is only given for manual refunds"}
+	}
+	return nil
+}
+
+// refunder returns the payment provider if it can refund, otherwise nil
+func (s *Server) refunder() payment.Refunder {
+	refunder, _ := s.config.Payments.(payment.Refunder)
+	return refunder
+}
+
+// sendRefund asks the provider to return a pending refund and records its
+// answer. The provider's error, if any, is returned with the updated refund;
+// a nil refund means the answer could not be recorded.
+func (s *Server) sendRefund(ctx context.Context, refunder payment.Refunder, refund *models.Refund) (*models.Refund, error) {
+	result, providerErr := refunder.Refund(ctx, payment.RefundRequest{
+		RefundID:  refund.ID,
+		Reference: refund.Reference,
+		PaymentID: refund.PaymentRef,
+		Amount:    refund.Amount.Amount,
+		Currency:  refund.Amount.Currency,
+		Reason:    refund.Reason,
+	})
+
+	status, providerRef, failure := models.RefundStatusFailed, "", ""
+	if providerErr != nil {
+		log.Printf("Error refunding payment %s of booking %s: %v", refund.PaymentRef, refund.Reference, providerErr)
+		failure = providerErr.Error()
+	} else {
+		providerRef = result.ID
+		switch result.Status {
+		case payment.RefundSucceeded:
+			status = models.RefundStatusSucceeded
+		case payment.RefundPending:
+			status = models.RefundStatusPending
+		default:
+			failure = "refused by the payment provider"
+		}
+	}
+
+	updated, err := s.store.RecordRefundResult(ctx, refund.ID, status, providerRef, failure)
+	if err != nil {
+		log.Printf("Error recording result of refund %d: %v", refund.ID, err)
+		return nil, providerErr
+	}
+	return updated, providerErr
+}
+
+// refundLatePayment returns a payment that was captured after its booking
+// had already been released, so the client is not charged for a slot they
+// did not get. Failures are logged for staff to refund by hand.
+func (s *Server) refundLatePayment(ctx context.Context, booking *models.BookingDetail, checkoutID string) {
+	refunder := s.refunder()
+	if refunder == nil {
+		log.Printf("Payment %s for booking %s needs a manual refund", checkoutID, booking.Reference)
+		return
+	}
+
+	payments, err := s.store.ListPayments(ctx, booking.ID)
+	if err != nil {
+		log.Printf("Error listing payments of booking %s for a refund: %v", booking.Reference, err)
+		return
+	}
+	var amount int64
+	for _, p := range payments {
+		if p.CheckoutID == checkoutID {
+			amount = p.Amount
+		}
+	}
+	if amount == 0 {
+		log.Printf("Payment %s for booking %s not found for a refund", checkoutID, booking.Reference)
+		return
+	}
+
+	refund, err := s.store.CreateRefund(ctx, models.RefundRequest{BookingID: booking.ID, Amount: amount, Reason: lateRefundReason})
+	if err != nil {
+		log.Printf("Error recording refund of late payment %s for booking %s: %v", checkoutID, booking.Reference, err)
+		return
+	}
+	if refund, err = s.sendRefund(ctx, refunder, refund); err == nil && refund != nil {
+		log.Printf("Refund %d of late payment %s for booking %s is %s", refund.ID, checkoutID, booking.Reference, refund.Status)
+	}
+}
+
+// sendCancellationRefunds sends the refunds recorded when a booking was
+// cancelled to the provider. Without a provider that can refund they stay
+// pending for staff to pay back and settle by hand.
+func (s *Server) sendCancellationRefunds(ctx context.Context, booking *models.BookingDetail) {
+	refunds, err := s.store.ListRefunds(ctx, booking.ID, models.RefundStatusPending)
+	if err != nil {
+		log.Printf("Error listing refunds of cancelled booking %s: %v", booking.Reference, err)
+		return
+	}
+	refunder := s.refunder()
+	for i := range refunds {
+		refund := &refunds[i]
+		if !refund.Cancellation || refund.ProviderRef != "" {
+			continue
+		}
+		if refunder == nil {
+			log.Printf("Refund %d of %s for cancelled booking %s needs to be paid back by hand", refund.ID, refund.Amount, booking.Reference)
+			continue
+		}
+		if refund, err = s.sendRefund(ctx, refunder, refund); err == nil && refund != nil {
+			log.Printf("Refund %d of %s for cancelled booking %s is %s", refund.ID, refund.Amount, booking.Reference, refund.Status)
+		}
+	}
+}
+
+// updateRefund records a refund status change reported through the
+// provider's webhook. Updates for refunds that are no longer pending, and
+// for ones still pending, change nothing.
+func (s *Server) updateRefund(ctx context.Context, provider string, event *payment.Event) error {
+	refund, err := s.store.GetRefundByProviderRef(ctx, provider, event.RefundID)
+	if err != nil {
+		return err
+	}
+	if refund.Status != models.RefundStatusPending {
+		return nil
+	}
+
+	var status, failure string
+	switch event.RefundStatus {
+	case payment.RefundSucceeded:
+		status = models.RefundStatusSucceeded
+	case payment.RefundFailed:
+		status, failure = models.RefundStatusFailed, event.RefundFailure
+		if failure == "" {
+			failure = "refused by the payment provider"
+		}
+	default:
+		return nil
+	}
+	if refund, err = s.store.RecordRefundResult(ctx, refund.ID, status, refund.ProviderRef, failure); err != nil {
+		return err
+	}
+	log.Printf("Refund %d of %s for booking %s is %s", refund.ID, refund.Amount, refund.Reference, refund.Status)
+	return nil
+}
+
+// SettleRefund handles POST /api/admin/refunds/:id/settle, recording the
+// outcome of a pending refund that the provider will not report, for
+// example one staff paid back by hand
+func (s *Server) SettleRefund(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid refund ID", http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Status      string `json:"status"`
+		ProviderRef string `json:"provider_ref"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Status != models.RefundStatusSucceeded && req.Status != models.RefundStatusFailed {
+		http.Error(w, "status must be succeeded or failed", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	refund, err := s.store.GetRefund(ctx, id)
+	if err != nil {
+		if errors.Is(err, database.ErrRefundNotFound) {
+			http.Error(w, "Refund not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Error getting refund %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if refund.Status != models.RefundStatusPending {
+		http.Error(w, "Refund is not pending", http.StatusConflict)
+		return
+	}
+
+	providerRef := strings.TrimSpace(req.ProviderRef)
+	if providerRef == "" {
+		providerRef = refund.ProviderRef
+	}
+	failure := ""
+	if req.Status == models.RefundStatusFailed {
+		failure = strings.TrimSpace(req.Error)
+	}
+	refund, err = s.store.RecordRefundResult(ctx, id, req.Status, providerRef, failure)
+	if err != nil {
+		log.Printf("Error settling refund %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Refund %d of %s for booking %s settled as %s", refund.ID, refund.Amount, refund.Reference, refund.Status)
+
+	if err := json.NewEncoder(w).Encode(refund); err != nil {
+		log.Printf("Error encoding refund response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// issueRefund posts a refund and checks the response code
+func (e *testEnv) issueRefund(t *testing.T, req models.RefundRequest, wantCode int) models.Refund {
+	t.Helper()
+
+	rec := e.do(t, "POST", "/api/admin/refunds", req)
+	if rec.Code != wantCode {
+		t.Fatalf("expected %d, got %d: %s", wantCode, rec.Code, rec.Body.String())
+	}
+	var refund models.Refund
+	if wantCode == http.StatusCreated || wantCode == http.StatusBadGateway {
+		if err := json.NewDecoder(rec.Body).Decode(&refund); err != nil {
+			t.Fatalf("failed to decode refund: %v", err)
+		}
+	}
+	return refund
+}
+
+func TestRefundsLedger(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Through the provider, from the captured payment
+	refund := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 2000, Reason: "Therapist ran late"}, http.StatusCreated)
+	if refund.Status != models.RefundStatusSucceeded || refund.Provider != payment.ProviderFake || refund.ProviderRef != "fake_re_1" ||
+		refund.PaymentRef != "pi_1" || refund.Reference != booking.Reference {
+		t.Errorf("unexpected refund %+v", refund)
+	}
+	sent := provider.Refunds()
+	if len(sent) != 1 || sent[0].PaymentID != "pi_1" || sent[0].Amount != 2000 || sent[0].RefundID != refund.ID {
+		t.Errorf("unexpected provider refunds %+v", sent)
+	}
+
+	// A refund the provider refuses is kept as failed and does not count
+	provider.FailRefundsWith(errors.New("card closed"))
+	failed := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 1000, Reason: "Goodwill"}, http.StatusBadGateway)
+	if failed.Status != models.RefundStatusFailed || failed.Error != "card closed" {
+		t.Errorf("expected a failed refund, got %+v", failed)
+	}
+	provider.FailRefundsWith(nil)
+
+	// Paid back by hand
+	manual := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 1000, Reason: "Goodwill", Manual: true, ProviderRef: "transfer 42"}, http.StatusCreated)
+	if manual.Status != models.RefundStatusSucceeded || manual.Provider != models.RefundProviderManual || manual.PaymentID != 0 {
+		t.Errorf("unexpected manual refund %+v", manual)
+	}
+
+	// 5000 paid, 3000 refunded
+	env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 2001, Reason: "Too much", Manual: true}, http.StatusConflict)
+	var detail models.AdminBooking
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/bookings/%d", booking.ID), nil), &detail)
+	if detail.Refunded.Amount != 3000 {
+		t.Errorf("expected 3000 refunded, got %v", detail.Refunded)
+	}
+
+	var refunds []models.Refund
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/refunds?booking_id=%d", booking.ID), nil), &refunds)
+	if len(refunds) != 3 || refunds[0].ID != manual.ID {
+		t.Errorf("expected three refunds, newest first, got %+v", refunds)
+	}
+	decode(t, env.do(t, "GET", "/api/admin/refunds?status=failed", nil), &refunds)
+	if len(refunds) != 1 || refunds[0].ID != failed.ID {
+		t.Errorf("expected the failed refund, got %+v", refunds)
+	}
+
+	rec := env.do(t, "GET", "/api/admin/exports/bookings?from="+booking.Date+"&to="+booking.Date, nil)
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][len(rows[1])-1] != "3000" {
+		t.Errorf("expected 3000 in the refunded column, got %q", rows)
+	}
+}
+
+func TestRefundRequestErrors(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+
+	for name, tc := range map[string]struct {
+		req  models.RefundRequest
+		code int
+	}{
+		"no amount":       {models.RefundRequest{BookingID: booking.ID, Reason: "x", Manual: true}, http.StatusBadRequest},
+		"no reason":       {models.RefundRequest{BookingID: booking.ID, Amount: 100, Manual: true}, http.StatusBadRequest},
+		"unknown booking": {models.RefundRequest{BookingID: 999, Amount: 100, Reason: "x", Manual: true}, http.StatusNotFound},
+		"no provider":     {models.RefundRequest{BookingID: booking.ID, Amount: 100, Reason: "x"}, http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env.issueRefund(t, tc.req, tc.code)
+		})
+	}
+
+	// With a provider, a booking paid on site has no payment to refund from
+	paid, _ := newPaymentTestEnv(t)
+	pending, _ := paid.startPaidBooking(t, 1)
+	paid.issueRefund(t, models.RefundRequest{BookingID: pending.ID, Amount: 100, Reason: "x"}, http.StatusConflict)
+
+	if rec := env.do(t, "GET", "/api/admin/refunds?status=lost", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown status, got %d", rec.Code)
+	}
+}
+
+func TestLatePaymentRefunded(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+
+	webhook := map[string]string{"type": payment.EventExpired, "checkout_id": "fake_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	webhook = map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_late"}
+	for i := 0; i < 2; i++ {
+		if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	sent := provider.Refunds()
+	if len(sent) != 1 || sent[0].PaymentID != "pi_late" || sent[0].Amount != booking.Price.Amount {
+		t.Fatalf("expected the late payment refunded once, got %+v", sent)
+	}
+	refunds, err := env.store.ListRefunds(context.Background(), booking.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || refunds[0].Status != models.RefundStatusSucceeded || refunds[0].Reason != lateRefundReason {
+		t.Errorf("unexpected refunds %+v", refunds)
+	}
+}
+
+func TestCancellingPaidBookingRefundsIt(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 1000, Reason: "Therapist ran late"}, http.StatusCreated)
+
+	// A refund for a cancellation needs the booking to be cancelled
+	env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 100, Reason: "x", Manual: true, Cancellation: true}, http.StatusConflict)
+
+	if rec := env.do(t, "POST", fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// What was not refunded yet goes back through the provider
+	sent := provider.Refunds()
+	if len(sent) != 2 || sent[1].PaymentID != "pi_1" || sent[1].Amount != 4000 {
+		t.Fatalf("expected the rest of the payment refunded, got %+v", sent)
+	}
+	var refunds []models.Refund
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/refunds?booking_id=%d", booking.ID), nil), &refunds)
+	if len(refunds) != 2 || !refunds[0].Cancellation || refunds[0].Reason != database.CancellationRefundReason ||
+		refunds[0].Status != models.RefundStatusSucceeded || refunds[1].Cancellation {
+		t.Errorf("expected a refund linked to the cancellation, got %+v", refunds)
+	}
+	var detail models.AdminBooking
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/bookings/%d", booking.ID), nil), &detail)
+	if detail.Refunded.Amount != 5000 {
+		t.Errorf("expected 5000 refunded, got %v", detail.Refunded)
+	}
+}
+
+func TestPaymentWithoutReferenceIsNotRefunded(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := env.do(t, "POST", "/api/admin/refunds", models.RefundRequest{BookingID: booking.ID, Amount: 1000, Reason: "x"})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "provider reference") {
+		t.Errorf("expected 409 for a payment without a provider reference, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The booking is still cancelled; the refund is left to staff
+	if rec := env.do(t, "POST", fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID), nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if sent := provider.Refunds(); len(sent) != 0 {
+		t.Errorf("expected no provider refunds, got %+v", sent)
+	}
+	manual := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 5000, Reason: "Refunded at the desk", Manual: true, Cancellation: true}, http.StatusCreated)
+	if !manual.Cancellation || manual.Status != models.RefundStatusSucceeded {
+		t.Errorf("unexpected manual refund %+v", manual)
+	}
+}
+
+func TestPendingRefundSettledByWebhook(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	provider.SetRefundStatus(payment.RefundPending)
+	refund := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 2000, Reason: "Therapist ran late"}, http.StatusCreated)
+	if refund.Status != models.RefundStatusPending || refund.ProviderRef != "fake_re_1" {
+		t.Fatalf("expected a pending refund, got %+v", refund)
+	}
+	var detail models.AdminBooking
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/bookings/%d", booking.ID), nil), &detail)
+	if detail.Refunded.Amount != 0 {
+		t.Errorf("a pending refund must not count as refunded, got %v", detail.Refunded)
+	}
+
+	// The provider reports the money as returned, more than once
+	update := map[string]string{"type": payment.EventRefund, "refund_id": "fake_re_1", "status": payment.RefundSucceeded}
+	for i := 0; i < 2; i++ {
+		if rec := env.do(t, "POST", "/api/payments/webhook", update); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	var refunds []models.Refund
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/refunds?booking_id=%d", booking.ID), nil), &refunds)
+	if len(refunds) != 1 || refunds[0].Status != models.RefundStatusSucceeded {
+		t.Errorf("expected the refund to have succeeded, got %+v", refunds)
+	}
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/bookings/%d", booking.ID), nil), &detail)
+	if detail.Refunded.Amount != 2000 {
+		t.Errorf("expected 2000 refunded, got %v", detail.Refunded)
+	}
+
+	// A late failure does not undo a refund that has succeeded
+	update["status"] = payment.RefundFailed
+	env.do(t, "POST", "/api/payments/webhook", update)
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/refunds?booking_id=%d", booking.ID), nil), &refunds)
+	if refunds[0].Status != models.RefundStatusSucceeded {
+		t.Errorf("expected the refund to stay succeeded, got %+v", refunds[0])
+	}
+
+	// Refunds this server does not know about are acknowledged
+	unknown := map[string]string{"type": payment.EventRefund, "refund_id": "fake_re_99", "status": payment.RefundSucceeded}
+	if rec := env.do(t, "POST", "/api/payments/webhook", unknown); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for an unknown refund, got %d", rec.Code)
+	}
+}
+
+func TestSettlePendingRefund(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	provider.SetRefundStatus(payment.RefundPending)
+	refund := env.issueRefund(t, models.RefundRequest{BookingID: booking.ID, Amount: 2000, Reason: "Therapist ran late"}, http.StatusCreated)
+
+	settle := fmt.Sprintf("/api/admin/refunds/%d/settle", refund.ID)
+	if rec := env.do(t, "POST", settle, map[string]string{"status": "pending"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a pending status, got %d", rec.Code)
+	}
+	var settled models.Refund
+	decode(t, env.do(t, "POST", settle, map[string]string{"status": "failed", "error": "card closed"}), &settled)
+	if settled.Status != models.RefundStatusFailed || settled.Error != "card closed" || settled.ProviderRef != "fake_re_1" {
+		t.Errorf("unexpected settled refund %+v", settled)
+	}
+	if rec := env.do(t, "POST", settle, map[string]string{"status": "succeeded"}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a refund that is not pending, got %d", rec.Code)
+	}
+	if rec := env.do(t, "POST", "/api/admin/refunds/99/settle", map[string]string{"status": "succeeded"}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown refund, got %d", rec.Code)
+	}
+}
+
+func TestCancellationRecordsRefundBeforeSending(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	booking, _ := env.startPaidBooking(t, 1)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The refund is part of the cancellation, even if it is never sent
+	if _, err := env.store.CancelBooking(context.Background(), booking.ID); err != nil {
+		t.Fatal(err)
+	}
+	refunds, err := env.store.ListRefunds(context.Background(), booking.ID, models.RefundStatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refunds) != 1 || !refunds[0].Cancellation || refunds[0].Amount.Amount != 5000 || refunds[0].PaymentRef != "pi_1" {
+		t.Fatalf("expected a pending refund of the cancellation, got %+v", refunds)
+	}
+	if len(provider.Refunds()) != 0 {
+		t.Error("nothing should be sent to the provider by the store")
+	}
+
+	// Staff can settle it once they have paid it back
+	var settled models.Refund
+	decode(t, env.do(t, "POST", fmt.Sprintf("/api/admin/refunds/%d/settle", refunds[0].ID), map[string]string{"status": "succeeded", "provider_ref": "transfer 42"}), &settled)
+	if settled.Status != models.RefundStatusSucceeded || settled.ProviderRef != "transfer 42" {
+		t.Errorf("unexpected settled refund %+v", settled)
+	}
+}
@@ -2,18 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"massage-booking/backend/database"
+	"massage-booking/backend/metrics"
 	"massage-booking/backend/models"
 )
 
 // CreateReservation handles POST /api/reservations
-func CreateReservation(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateReservation(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
@@ -47,14 +48,14 @@ func CreateReservation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create reservation
-	reservationID, expiresAt, err := database.CreateReservation(req.SlotID)
+	reservation, err := s.store.CreateReservation(r.Context(), req.SlotID)
 	if err != nil {
 		log.Printf("Error creating reservation for slot %d: %v", req.SlotID, err)
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, database.ErrSlotNotFound) {
 			http.Error(w, "Slot not found", http.StatusNotFound)
 			return
 		}
-		if strings.Contains(err.Error(), "not available") || strings.Contains(err.Error(), "already reserved") {
+		if errors.Is(err, database.ErrSlotUnavailable) || errors.Is(err, database.ErrSlotReserved) {
 			http.Error(w, "Slot is not available", http.StatusConflict)
 			return
 		}
@@ -62,14 +63,17 @@ func CreateReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	metrics.ReservationsCreated.Inc()
+
 	// Calculate expires in seconds
-	expiresInSeconds := int(time.Until(expiresAt).Seconds())
+	expiresInSeconds := int(reservation.ExpiresAt.Sub(s.clock.Now()).Seconds())
 
 	// Create response
 	response := models.ReservationResponse{
-		ReservationID:    reservationID,
-		ExpiresAt:        expiresAt,
+		ReservationID:    reservation.ID,
+		ExpiresAt:        reservation.ExpiresAt,
 		ExpiresInSeconds: expiresInSeconds,
+		Price:            reservation.Price,
 	}
 
 	// Send response
@@ -79,11 +83,11 @@ func CreateReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Created reservation %d for slot %d, expires at %v", reservationID, req.SlotID, expiresAt)
+	log.Printf("Created reservation %d for slot %d at %s, expires at %v", reservation.ID, req.SlotID, reservation.Price, reservation.ExpiresAt)
 }
 
 // DeleteReservation handles DELETE /api/reservations/:id
-func DeleteReservation(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteReservation(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "DELETE, OPTIONS")
@@ -115,9 +119,9 @@ func DeleteReservation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete reservation
-	if err := database.DeleteReservation(reservationID); err != nil {
+	if err := s.store.DeleteReservation(r.Context(), reservationID); err != nil {
 		log.Printf("Error deleting reservation %d: %v", reservationID, err)
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, database.ErrReservationNotFound) {
 			http.Error(w, "Reservation not found", http.StatusNotFound)
 			return
 		}
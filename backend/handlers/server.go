@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"massage-booking/backend/calendar"
+	"massage-booking/backend/clock"
+	"massage-booking/backend/email"
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// Store is the persistence layer used by the HTTP handlers
+type Store interface {
+	GetMassageTypes(ctx context.Context) ([]models.MassageType, error)
+	SetServiceTax(ctx context.Context, serviceID int, rate models.TaxRate, inclusive bool) (*models.MassageType, error)
+	GetTimeSlots(ctx context.Context, date string, serviceID int) ([]models.TimeSlot, error)
+	CreateReservation(ctx context.Context, slotID int) (*models.Reservation, error)
+	DeleteReservation(ctx context.Context, reservationID int) error
+	CreateBooking(ctx context.Context, req models.BookingRequest) (*models.Booking, error)
+	CreatePendingBooking(ctx context.Context, req models.BookingRequest, holdUntil time.Time) (*models.Booking, error)
+	AttachCheckout(ctx context.Context, p models.Payment) error
+	ConfirmPayment(ctx context.Context, checkoutID, paymentRef string) (*models.BookingDetail, error)
+	FailPayment(ctx context.Context, checkoutID, status string) error
+	ListPayments(ctx context.Context, bookingID int) ([]models.Payment, error)
+	CreateRefund(ctx context.Context, req models.RefundRequest) (*models.Refund, error)
+	RecordRefundResult(ctx context.Context, id int, status, providerRef, failure string) (*models.Refund, error)
+	GetRefund(ctx context.Context, id int) (*models.Refund, error)
+	GetRefundByProviderRef(ctx context.Context, provider, providerRef string) (*models.Refund, error)
+	ListRefunds(ctx context.Context, bookingID int, status string) ([]models.Refund, error)
+	ReleaseBooking(ctx context.Context, bookingID int) error
+	GetBookingByID(ctx context.Context, bookingID int) (*models.BookingDetail, error)
+	GetBookingByReference(ctx context.Context, reference string) (*models.BookingDetail, error)
+	IssueInvoice(ctx context.Context, bookingID int) (*models.Invoice, error)
+	CancelBooking(ctx context.Context, bookingID int) (*models.BookingDetail, error)
+	ListUpcomingBookings(ctx context.Context, from time.Time) ([]models.BookingDetail, error)
+	Quote(ctx context.Context, req models.QuoteRequest) (*models.Quote, error)
+	CreatePromoCode(ctx context.Context, p models.PromoCode) (*models.PromoCode, error)
+	ListPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	DeactivatePromoCode(ctx context.Context, id int) error
+	CreatePricingRule(ctx context.Context, r models.PricingRule) (*models.PricingRule, error)
+	ListPricingRules(ctx context.Context) ([]models.PricingRule, error)
+	DeletePricingRule(ctx context.Context, id int) error
+	IssueVoucher(ctx context.Context, req models.VoucherRequest) (*models.GiftVoucher, error)
+	ListVouchers(ctx context.Context) ([]models.GiftVoucher, error)
+	GetVoucher(ctx context.Context, code string) (*models.GiftVoucher, error)
+	CreatePackage(ctx context.Context, req models.PackageRequest) (*models.SessionPackage, error)
+	GetPackage(ctx context.Context, code string) (*models.SessionPackage, error)
+	ListPackages(ctx context.Context, email string) ([]models.SessionPackage, error)
+	RestorePackageSession(ctx context.Context, id int) (*models.SessionPackage, error)
+	ListAllBookingsOn(ctx context.Context, date string) ([]models.BookingDetail, error)
+	ListBookingsBetween(ctx context.Context, from, to string) ([]models.BookingDetail, error)
+	ListEmails(ctx context.Context, status string) ([]models.OutboxEmail, error)
+	ResendEmail(ctx context.Context, id int) error
+	Ping(ctx context.Context) error
+	MigrationsApplied(ctx context.Context) (bool, error)
+}
+
+// Mailer reports on the email transport, renders previews and invoices and sends gift
+// vouchers; booking emails themselves go through the outbox
+type Mailer interface {
+	CheckTransport(ctx context.Context) (string, error)
+	Preview(template string, booking *models.BookingDetail, locale string) (*email.Rendered, error)
+	SendPreview(ctx context.Context, template string, booking *models.BookingDetail, locale, to string) error
+	SendVoucherEmail(ctx context.Context, voucher *models.GiftVoucher) error
+	VoucherCertificate(voucher *models.GiftVoucher) (*email.Rendered, error)
+	Invoice(inv *models.Invoice) (*email.Rendered, error)
+	InvoicePDF(inv *models.Invoice) []byte
+}
+
+// Config holds handler settings that come from the environment
+type Config struct {
+	// AdminToken is the bearer token required by /api/admin endpoints; empty disables them
+	AdminToken string
+
+	// CalendarToken is the token required by the salon calendar feed; empty disables it
+	CalendarToken string
+
+	// Salon is used as the location of calendar events
+	Salon calendar.Salon
+
+	// PhoneCountryCode is assumed for phone numbers entered without one, e.g. "372"
+	PhoneCountryCode string
+
+	// Payments collects online payment before bookings are confirmed; nil confirms them immediately
+	Payments payment.Provider
+
+	// Payment holds the deposit, currency, hold timeout and return URL used with Payments
+	Payment payment.Config
+}
+
+// Server holds the dependencies shared by all HTTP handlers
+type Server struct {
+	store  Store
+	mailer Mailer
+	clock  clock.Clock
+	config Config
+}
+
+// NewServer creates a Server with the given dependencies
+func NewServer(store Store, mailer Mailer, clk clock.Clock, config Config) *Server {
+	return &Server{store: store, mailer: mailer, clock: clk, config: config}
+}
+
+// RegisterRoutes registers the API and operational endpoints on mux
+func (s *Server) RegisterRoutes(mux *http.ServeMux) {
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.Handle(pattern, metrics.Instrument(pattern, handler))
+	}
+
+	// API routes
+	handle("/api/massage-types", s.GetMassageTypesHandler)
+	handle("/api/slots", s.GetSlotsHandler)
+
+	// Story #2 routes
+	handle("/api/reservations", s.CreateReservation)
+	handle("/api/reservations/", s.DeleteReservation)
+	handle("/api/bookings", s.CreateBooking)
+
+	// Story #3 routes
+	handle("/api/bookings/", s.GetBooking)
+	handle("/api/bookings/{id}/ics", s.GetBookingICS)
+	handle("/api/calendar/feed.ics", s.CalendarFeed)
+	handle("/api/quote", s.GetQuote)
+	handle("/api/vouchers/{code}", s.GetVoucherBalance)
+	handle("/api/packages/{code}", s.GetPackageBalance)
+	handle("/api/invoices/{reference}", s.GetInvoice)
+	handle("/api/payments/webhook", s.PaymentWebhook)
+
+	// Admin routes
+	handle("/api/admin/outbox", s.requireAdmin(s.ListOutbox))
+	handle("/api/admin/outbox/{id}/resend", s.requireAdmin(s.ResendOutboxEmail))
+	handle("/api/admin/bookings", s.requireAdmin(s.ListAdminBookings))
+	handle("/api/admin/bookings/{id}", s.requireAdmin(s.GetAdminBooking))
+	handle("/api/admin/bookings/{id}/cancel", s.requireAdmin(s.CancelBooking))
+	handle("/api/admin/invoices/{reference}", s.requireAdmin(s.AdminInvoice))
+	handle("/api/admin/exports/bookings", s.requireAdmin(s.ExportBookings))
+	handle("/api/admin/refunds", s.requireAdmin(s.Refunds))
+	handle("/api/admin/refunds/{id}/settle", s.requireAdmin(s.SettleRefund))
+	handle("/api/admin/promo-codes", s.requireAdmin(s.PromoCodes))
+	handle("/api/admin/promo-codes/{id}", s.requireAdmin(s.DeactivatePromoCode))
+	handle("/api/admin/massage-types/{id}/tax", s.requireAdmin(s.SetServiceTax))
+	handle("/api/admin/pricing-rules", s.requireAdmin(s.PricingRules))
+	handle("/api/admin/pricing-rules/{id}", s.requireAdmin(s.DeletePricingRule))
+	handle("/api/admin/vouchers", s.requireAdmin(s.Vouchers))
+	handle("/api/admin/vouchers/{code}/certificate", s.requireAdmin(s.VoucherCertificate))
+	handle("/api/admin/vouchers/{code}/send", s.requireAdmin(s.SendVoucher))
+	handle("/api/admin/packages", s.requireAdmin(s.Packages))
+	handle("/api/admin/packages/{id}/restore", s.requireAdmin(s.RestorePackageSession))
+	handle("/api/admin/emails/{template}/preview", s.requireAdmin(s.PreviewEmail))
+	handle("/api/admin/emails/{template}/test-send", s.requireAdmin(s.SendTestEmail))
+
+	// Operational endpoints
+	mux.HandleFunc("/healthz", s.Healthz)
+	mux.HandleFunc("/readyz", s.Readyz)
+	mux.Handle("/metrics", metrics.Handler())
+}
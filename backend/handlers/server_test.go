@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/calendar"
+	"massage-booking/backend/clock"
+	"massage-booking/backend/database"
+	"massage-booking/backend/email"
+	"massage-booking/backend/metrics"
+	"massage-booking/backend/models"
+)
+
+// testAdminToken is the admin bearer token configured for tests
+const testAdminToken = "test-admin-token"
+
+// testCalendarToken is the calendar feed token configured for tests
+const testCalendarToken = "test-calendar-token"
+
+// testEnv bundles a Server wired to an in-memory database and fake dependencies
+type testEnv struct {
+	handler http.Handler
+	clock   *clock.Fake
+	store   *database.Store
+	mail    *email.MemoryMailer
+}
+
+// testLocation is the business timezone used by the tests
+var testLocation = mustLoadLocation("Europe/Tallinn")
+
+// testNow is the fake clock's starting time; slots are seeded from this date
+var testNow = time.Date(2025, 3, 10, 8, 0, 0, 0, testLocation)
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := clock.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	return newTestEnvAt(t, testNow)
+}
+
+// newTestEnvAt creates a test environment whose clock starts at now;
+// configure functions may adjust the handler config
+func newTestEnvAt(t *testing.T, now time.Time, configure ...func(*Config)) *testEnv {
+	t.Helper()
+
+	ctx := context.Background()
+	clk := clock.NewFake(now)
+	store, err := database.Open(ctx, ":memory:", clk, testLocation)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	if err := store.Seed(ctx); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+
+	renderer, err := email.NewRenderer(email.Branding{SalonName: "Test Salon", PrimaryColor: "#4CAF50", AccentColor: "#1976d2"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := email.NewMemoryMailer()
+	sender := email.NewSender(mail, renderer, &email.EmailConfig{FromName: "Test Salon", FromEmail: "salon@example.com", StaffLocale: "en"})
+
+	config := Config{
+		AdminToken:    testAdminToken,
+		CalendarToken: testCalendarToken,
+		Salon:         calendar.Salon{Name: "Test Salon", Address: "Narva mnt 1, Tallinn"},
+
+		PhoneCountryCode: "372",
+	}
+	for _, fn := range configure {
+		fn(&config)
+	}
+
+	mux := http.NewServeMux()
+	NewServer(store, sender, clk, config).RegisterRoutes(mux)
+
+	return &testEnv{handler: mux, clock: clk, store: store, mail: mail}
+}
+
+// do sends a request through the router and returns the recorded response
+func (e *testEnv) do(t *testing.T, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("encode request body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	if strings.HasPrefix(path, "/api/admin/") {
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	}
+	rec := httptest.NewRecorder()
+	e.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a successful JSON response into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+// availableSlot returns the first bookable slot for the service on the test date
+func (e *testEnv) availableSlot(t *testing.T, serviceID int) models.TimeSlot {
+	t.Helper()
+	return e.availableSlotOn(t, testNow.Format("2006-01-02"), serviceID)
+}
+
+// availableSlotOn returns the first bookable slot for the service on date
+func (e *testEnv) availableSlotOn(t *testing.T, date string, serviceID int) models.TimeSlot {
+	t.Helper()
+
+	var slots []models.TimeSlot
+	path := fmt.Sprintf("/api/slots?date=%s&service_id=%d", date, serviceID)
+	decode(t, e.do(t, "GET", path, nil), &slots)
+
+	for _, slot := range slots {
+		if slot.Available {
+			return slot
+		}
+	}
+	t.Fatalf("no available slot for service %d on %s", serviceID, date)
+	return models.TimeSlot{}
+}
+
+// reserve creates a reservation for the slot and returns the response
+func (e *testEnv) reserve(t *testing.T, slotID int) models.ReservationResponse {
+	t.Helper()
+
+	var res models.ReservationResponse
+	decode(t, e.do(t, "POST", "/api/reservations", models.ReservationRequest{SlotID: slotID}), &res)
+	return res
+}
+
+func bookingRequest(reservationID int, slot models.TimeSlot) models.BookingRequest {
+	return models.BookingRequest{
+		ReservationID: reservationID,
+		ClientName:    "Jane Doe",
+		Email:         "jane@example.com",
+		Phone:         "+372 5123 4567",
+		ServiceID:     slot.ServiceID,
+		Date:          slot.Date,
+		TimeSlot:      slot.Time,
+	}
+}
+
+func TestReserveBookFetchFlow(t *testing.T) {
+	env := newTestEnv(t)
+
+	var types []models.MassageType
+	decode(t, env.do(t, "GET", "/api/massage-types", nil), &types)
+	if len(types) == 0 {
+		t.Fatal("expected seeded massage types")
+	}
+	service := types[0]
+
+	slot := env.availableSlot(t, service.ID)
+	reservation := env.reserve(t, slot.ID)
+	if reservation.ExpiresInSeconds != 600 {
+		t.Errorf("expected reservation to expire in 600s, got %d", reservation.ExpiresInSeconds)
+	}
+
+	// A held slot cannot be reserved twice
+	if rec := env.do(t, "POST", "/api/reservations", models.ReservationRequest{SlotID: slot.ID}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for reserved slot, got %d", rec.Code)
+	}
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot)), &booking)
+
+	wantRef := "BK-" + testNow.Format("20060102") + "-001"
+	if !booking.StartsAt.Equal(slot.StartsAt) {
+		t.Errorf("expected booking to start at %v, got %v", slot.StartsAt, booking.StartsAt)
+	}
+	if booking.Reference != wantRef {
+		t.Errorf("expected reference %s, got %s", wantRef, booking.Reference)
+	}
+	if booking.ServiceName != service.Name || booking.Price != service.Price || booking.Duration != service.Duration {
+		t.Errorf("booking service details %+v do not match service %+v", booking, service)
+	}
+
+	var outbox []models.OutboxEmail
+	decode(t, env.do(t, "GET", "/api/admin/outbox?status=pending", nil), &outbox)
+	if len(outbox) != 1 || outbox[0].BookingID != booking.ID || outbox[0].Kind != models.EmailKindBookingConfirmation {
+		t.Errorf("expected one queued confirmation for booking %d, got %+v", booking.ID, outbox)
+	}
+
+	var fetched models.BookingDetail
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", booking.ID), nil), &fetched)
+	if fetched.Reference != booking.Reference || fetched.ClientName != "Jane Doe" || fetched.TimeSlot != slot.Time {
+		t.Errorf("fetched booking %+v does not match created booking %+v", fetched, booking)
+	}
+
+	// The booked slot is no longer available
+	if rec := env.do(t, "POST", "/api/reservations", models.ReservationRequest{SlotID: slot.ID}); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for booked slot, got %d", rec.Code)
+	}
+}
+
+func TestBookingFailsAfterReservationExpires(t *testing.T) {
+	env := newTestEnv(t)
+
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	env.clock.Advance(11 * time.Minute)
+
+	rec := env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for expired reservation, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var outbox []models.OutboxEmail
+	decode(t, env.do(t, "GET", "/api/admin/outbox", nil), &outbox)
+	if len(outbox) != 0 {
+		t.Errorf("expected no queued emails, got %d", len(outbox))
+	}
+
+	// The slot is offered again once the hold has lapsed
+	if again := env.availableSlot(t, 1); again.ID != slot.ID {
+		t.Errorf("expected slot %d to be available again, got %d", slot.ID, again.ID)
+	}
+	env.reserve(t, slot.ID)
+}
+
+func TestReservationStillValidBeforeExpiry(t *testing.T) {
+	env := newTestEnv(t)
+
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	env.clock.Advance(9 * time.Minute)
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot)), &booking)
+}
+
+func TestCreateBookingValidation(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+
+	tests := []struct {
+		name   string
+		modify func(*models.BookingRequest)
+	}{
+		{"missing name", func(r *models.BookingRequest) { r.ClientName = "" }},
+		{"name with digits", func(r *models.BookingRequest) { r.ClientName = "Jane 2" }},
+		{"invalid email", func(r *models.BookingRequest) { r.Email = "not-an-email" }},
+		{"invalid phone", func(r *models.BookingRequest) { r.Phone = "12" }},
+		{"missing reservation", func(r *models.BookingRequest) { r.ReservationID = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := bookingRequest(1, slot)
+			tt.modify(&req)
+			if rec := env.do(t, "POST", "/api/bookings", req); rec.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestDeleteReservation(t *testing.T) {
+	env := newTestEnv(t)
+
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+	path := fmt.Sprintf("/api/reservations/%d", reservation.ReservationID)
+
+	if rec := env.do(t, "DELETE", path, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := env.do(t, "DELETE", path, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for deleted reservation, got %d", rec.Code)
+	}
+}
+
+func TestGetBookingNotFound(t *testing.T) {
+	env := newTestEnv(t)
+
+	if rec := env.do(t, "GET", "/api/bookings/999", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if rec := env.do(t, "GET", "/api/bookings/abc", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	env := newTestEnv(t)
+
+	var status HealthStatus
+	decode(t, env.do(t, "GET", "/healthz", nil), &status)
+	if status.Status != "ok" {
+		t.Errorf("expected healthz ok, got %+v", status)
+	}
+
+	decode(t, env.do(t, "GET", "/readyz", nil), &status)
+	if status.Status != "ok" || status.Checks["migrations"] != "ok" {
+		t.Errorf("expected readyz ok, got %+v", status)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	env := newTestEnv(t)
+	created := metrics.BookingsCreated.Value()
+
+	decode(t, env.do(t, "GET", "/api/massage-types", nil), &[]models.MassageType{})
+	env.book(t, 1)
+
+	rec := env.do(t, "GET", "/metrics", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("expected the text exposition format, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{route="/api/massage-types",method="GET",code="200"} `,
+		"# TYPE http_request_duration_seconds histogram\n",
+		`http_request_duration_seconds_bucket{route="/api/massage-types",le="0.005"} `,
+		`http_request_duration_seconds_bucket{route="/api/massage-types",le="+Inf"} `,
+		`http_request_duration_seconds_count{route="/api/massage-types"} `,
+		fmt.Sprintf("bookings_created_total %v\n", created+1),
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %q", want)
+		}
+	}
+
+	if rec := env.do(t, "POST", "/metrics", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestSlotStartTimesAcrossDST(t *testing.T) {
+	// Europe/Tallinn moves from UTC+2 to UTC+3 on 2025-03-30
+	env := newTestEnvAt(t, time.Date(2025, 3, 29, 8, 0, 0, 0, testLocation))
+
+	for _, tt := range []struct {
+		date    string
+		wantUTC int
+	}{
+		{"2025-03-29", 2},
+		{"2025-03-30", 3},
+	} {
+		slot := env.availableSlotOn(t, tt.date, 1)
+		local := slot.StartsAt.In(testLocation)
+		if local.Format("2006-01-02 15:04") != slot.Date+" "+slot.Time {
+			t.Errorf("slot %s %s starts at %v", slot.Date, slot.Time, local)
+		}
+		if _, offset := local.Zone(); offset != tt.wantUTC*3600 {
+			t.Errorf("expected UTC+%d on %s, got offset %ds", tt.wantUTC, tt.date, offset)
+		}
+	}
+}
+
+func TestReservationExpiryIgnoresServerTimezone(t *testing.T) {
+	// The clock reports a non-UTC zone; expiry must still be exactly ten minutes
+	env := newTestEnvAt(t, time.Date(2025, 3, 10, 23, 55, 0, 0, time.FixedZone("UTC-7", -7*3600)))
+
+	slot := env.availableSlotOn(t, "2025-03-11", 1)
+	reservation := env.reserve(t, slot.ID)
+	if reservation.ExpiresAt.Location() != time.UTC {
+		t.Errorf("expected expiry in UTC, got %v", reservation.ExpiresAt)
+	}
+
+	env.clock.Advance(9 * time.Minute)
+	if rec := env.do(t, "POST", "/api/reservations", models.ReservationRequest{SlotID: slot.ID}); rec.Code != http.StatusConflict {
+		t.Errorf("expected slot to still be held, got %d", rec.Code)
+	}
+
+	env.clock.Advance(2 * time.Minute)
+	env.reserve(t, slot.ID)
+}
+
+func TestAdminEndpointsRequireToken(t *testing.T) {
+	env := newTestEnv(t)
+
+	req := httptest.NewRequest("GET", "/api/admin/outbox", nil)
+	rec := httptest.NewRecorder()
+	env.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+
+	if rec := env.do(t, "POST", "/api/admin/outbox/999/resend", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown email, got %d", rec.Code)
+	}
+}
+
+func TestAdminCancelBooking(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+	path := fmt.Sprintf("/api/admin/bookings/%d/cancel", booking.ID)
+
+	rec := env.do(t, "POST", path, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var cancelled models.BookingDetail
+	decode(t, rec, &cancelled)
+	if cancelled.Status != models.BookingStatusCancelled || cancelled.CancelledAt == nil {
+		t.Errorf("expected a cancelled booking, got %+v", cancelled)
+	}
+
+	if rec := env.do(t, "POST", path, nil); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when cancelling twice, got %d", rec.Code)
+	}
+	if rec := env.do(t, "POST", "/api/admin/bookings/999/cancel", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown booking, got %d", rec.Code)
+	}
+}
+
+func TestValidationMessagesAreLocalized(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+
+	req := bookingRequest(1, slot)
+	req.Email = "not-an-email"
+	req.Locale = "ru"
+	rec := env.do(t, "POST", "/api/bookings", req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Введите корректный адрес электронной почты") {
+		t.Errorf("expected Russian validation message, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// So are errors from creating the booking
+	req.Email = "john@example.com"
+	req.ReservationID = 9999
+	rec = env.do(t, "POST", "/api/bookings", req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Резерв не найден или истёк") {
+		t.Errorf("expected Russian reservation message, got %d %q", rec.Code, rec.Body.String())
+	}
+	req.Email = "not-an-email"
+
+	// Without a locale in the body the Accept-Language header is used
+	req.Locale = ""
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest("POST", "/api/bookings", bytes.NewReader(body))
+	httpReq.Header.Set("Accept-Language", "et-EE,et;q=0.9")
+	rec = httptest.NewRecorder()
+	env.handler.ServeHTTP(rec, httpReq)
+	if !strings.Contains(rec.Body.String(), "Palun sisestage kehtiv e-posti aadress") {
+		t.Errorf("expected Estonian validation message, got %q", rec.Body.String())
+	}
+}
+
+func TestBookingStoresLocaleAndAcceptsNonLatinNames(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.ClientName = "Анна Õunapuu"
+	req.Locale = "ru-RU"
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", req), &booking)
+	if booking.Locale != "ru" || booking.ClientName != "Анна Õunapuu" {
+		t.Errorf("unexpected booking %+v", booking)
+	}
+}
+
+func TestAdminBookingsShowBookedAndCurrentPrice(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 1)
+
+	var list []models.AdminBooking
+	decode(t, env.do(t, "GET", "/api/admin/bookings?date="+booking.Date, nil), &list)
+	if len(list) != 1 || list[0].ID != booking.ID {
+		t.Fatalf("expected the booking in the list, got %+v", list)
+	}
+
+	var single models.AdminBooking
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/admin/bookings/%d", booking.ID), nil), &single)
+	if single.Price != booking.Price || single.CurrentPrice == nil || *single.CurrentPrice != booking.Price {
+		t.Errorf("expected booked and current price %v, got %v and %v", booking.Price, single.Price, single.CurrentPrice)
+	}
+
+	if rec := env.do(t, "GET", "/api/admin/bookings", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a date, got %d", rec.Code)
+	}
+	if rec := env.do(t, "GET", "/api/admin/bookings/999", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown booking, got %d", rec.Code)
+	}
+}
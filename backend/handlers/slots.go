@@ -5,12 +5,10 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-
-	"massage-booking/backend/database"
 )
 
 // GetSlotsHandler handles GET /api/slots?date=YYYY-MM-DD&service_id=1
-func GetSlotsHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetSlotsHandler(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
@@ -52,7 +50,7 @@ func GetSlotsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get time slots from database
-	timeSlots, err := database.GetTimeSlots(date, serviceID)
+	timeSlots, err := s.store.GetTimeSlots(r.Context(), date, serviceID)
 	if err != nil {
 		log.Printf("Error getting time slots for date %s and service %d: %v", date, serviceID, err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+func TestBookingWithSMSOptIn(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.Phone = "5123 4567"
+	req.SMSOptIn = true
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", req), &booking)
+	if booking.Phone != "+37251234567" || !booking.SMSOptIn {
+		t.Errorf("expected normalised phone and opt-in, got %q %v", booking.Phone, booking.SMSOptIn)
+	}
+
+	var outbox []models.OutboxEmail
+	decode(t, env.do(t, "GET", "/api/admin/outbox", nil), &outbox)
+	channels := map[string]string{}
+	for _, e := range outbox {
+		channels[e.Channel] = e.Recipient
+	}
+	if channels[models.ChannelEmail] != "jane@example.com" || channels[models.ChannelSMS] != "+37251234567" {
+		t.Errorf("expected an email and an SMS confirmation, got %+v", outbox)
+	}
+}
+
+func TestBookingWithoutSMSOptInQueuesEmailOnly(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot)), &booking)
+
+	var outbox []models.OutboxEmail
+	decode(t, env.do(t, "GET", "/api/admin/outbox", nil), &outbox)
+	if len(outbox) != 1 || outbox[0].Channel != models.ChannelEmail {
+		t.Errorf("expected only an email confirmation, got %+v", outbox)
+	}
+}
+
+func TestSMSOptInRequiresUsablePhone(t *testing.T) {
+	env := newTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.Phone = "+1234567890123456"
+	req.SMSOptIn = true
+	if rec := env.do(t, "POST", "/api/bookings", req); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a phone that cannot receive SMS, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+)
+
+// pageRun describes a static page loaded by testdata/page.js
+type pageRun struct {
+	Scripts   []string                  `json:"scripts"`
+	Search    string                    `json:"search"`
+	Responses map[string][]pageResponse `json:"responses"`
+	Run       string                    `json:"run,omitempty"`
+}
+
+// pageResponse is a canned API response; Body is raw JSON or text
+type pageResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// pageResult is what the page showed
+type pageResult struct {
+	Elements map[string]struct {
+		Text    string `json:"text"`
+		HTML    string `json:"html"`
+		Display string `json:"display"`
+	} `json:"elements"`
+	Requests []struct {
+		Key  string         `json:"key"`
+		Body map[string]any `json:"body"`
+	} `json:"requests"`
+	Alerts   []string `json:"alerts"`
+	Location string   `json:"location"`
+}
+
+// loadPage runs the page's scripts in node against the given responses,
+// skipping the test when node is not installed
+func loadPage(t *testing.T, run pageRun) pageResult {
+	t.Helper()
+
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	for i, script := range run.Scripts {
+		run.Scripts[i] = "../static/" + script
+	}
+	input, err := json.Marshal(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(node, "testdata/page.js")
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("page failed: %v\n%s", err, stderr.String())
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("page logged errors:\n%s", stderr.String())
+	}
+
+	var result pageResult
+	if err := json.Unmarshal(out, &result); err != nil {
+		t.Fatalf("failed to decode page result: %v\n%s", err, out)
+	}
+	return result
+}
+
+// apiResponse returns the body of a successful API response for a page to load
+func (e *testEnv) apiResponse(t *testing.T, path string) pageResponse {
+	t.Helper()
+
+	rec := e.do(t, "GET", path, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+	}
+	return pageResponse{Status: http.StatusOK, Body: bytes.TrimSpace(rec.Body.Bytes())}
+}
+
+func TestBookingPageShowsPrices(t *testing.T) {
+	env := newTestEnv(t)
+	page := loadPage(t, pageRun{
+		Scripts:   []string{"money.js", "app.js"},
+		Responses: map[string][]pageResponse{"GET /api/massage-types": {env.apiResponse(t, "/api/massage-types")}},
+		Run: `
+			event = {currentTarget: element('card')};
+			selectService(massageTypes[0]);
+			selectedDate = '2025-03-10';
+			selectedTime = {id: 1, time: '10:00'};
+			updateSelectionSummary();
+			updateBookingSummary();
+		`,
+	})
+
+	if services := page.Elements["services-container"].HTML; !strings.Contains(services, "€50.00") || !strings.Contains(services, "€70.00") {
+		t.Errorf("expected service prices in the list, got %q", services)
+	}
+	if info := page.Elements["selected-service-info"].HTML; !strings.Contains(info, "60 minutes, €50.00") {
+		t.Errorf("expected the selected service price, got %q", info)
+	}
+	for _, id := range []string{"summary-price", "booking-summary-price"} {
+		if got := page.Elements[id].Text; got != "€50.00" {
+			t.Errorf("%s = %q, want €50.00", id, got)
+		}
+	}
+}
+
+func TestConfirmationPageShowsBooking(t *testing.T) {
+	env := newTestEnv(t)
+	booking := env.book(t, 2)
+
+	path := fmt.Sprintf("/api/bookings/%d", booking.ID)
+	page := loadPage(t, pageRun{
+		Scripts:   []string{"money.js", "confirmation.js"},
+		Search:    fmt.Sprintf("?id=%d", booking.ID),
+		Responses: map[string][]pageResponse{"GET " + path: {env.apiResponse(t, path)}},
+	})
+
+	if page.Elements["success-section"].Display != "block" {
+		t.Fatalf("expected the booking shown, got %+v", page.Elements)
+	}
+	for id, want := range map[string]string{
+		"booking-reference": booking.Reference,
+		"service-name":      "Deep Tissue",
+		"service-price":     "€70.00",
+	} {
+		if got := page.Elements[id].Text; got != want {
+			t.Errorf("%s = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestBookingPageRedirectsToCheckout(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+	slot := env.availableSlot(t, 1)
+	reservation := env.reserve(t, slot.ID)
+	rec := env.do(t, "POST", "/api/bookings", bookingRequest(reservation.ReservationID, slot))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var booking models.BookingDetail
+	if err := json.Unmarshal(rec.Body.Bytes(), &booking); err != nil || booking.CheckoutURL == "" {
+		t.Fatalf("expected a checkout url, got %s", rec.Body.String())
+	}
+
+	page := loadPage(t, pageRun{
+		Scripts: []string{"money.js", "app.js"},
+		Responses: map[string][]pageResponse{
+			"GET /api/massage-types": {env.apiResponse(t, "/api/massage-types")},
+			"POST /api/bookings":     {{Status: http.StatusOK, Body: bytes.TrimSpace(rec.Body.Bytes())}},
+		},
+		Run: fmt.Sprintf(`
+			selectedService = massageTypes[0];
+			selectedDate = %q;
+			selectedTime = {id: %d, time: %q};
+			currentReservation = {reservation_id: %d};
+			element('client-name').value = 'Jane Doe';
+			element('client-email').value = 'jane@example.com';
+			element('client-phone').value = '+372 5123 4567';
+			await handleFormSubmit({preventDefault() {}});
+		`, slot.Date, slot.ID, slot.Time, reservation.ReservationID),
+	})
+
+	if page.Location != booking.CheckoutURL {
+		t.Errorf("expected to be sent to checkout at %q, went to %q", booking.CheckoutURL, page.Location)
+	}
+}
+
+func TestBookingPageShowsCancelledPayment(t *testing.T) {
+	env := newTestEnv(t)
+	page := loadPage(t, pageRun{
+		Scripts:   []string{"money.js", "app.js"},
+		Search:    "?payment=cancelled",
+		Responses: map[string][]pageResponse{"GET /api/massage-types": {env.apiResponse(t, "/api/massage-types")}},
+	})
+	if got := page.Elements["error-text"].Text; !strings.Contains(got, "Payment was cancelled") {
+		t.Errorf("expected a cancelled payment message, got %q", got)
+	}
+}
+
+func TestConfirmationPageFollowsPayment(t *testing.T) {
+	env, _ := newPaymentTestEnv(t)
+	paid, _ := env.startPaidBooking(t, 1)
+	paidPath := fmt.Sprintf("/api/bookings/%d", paid.ID)
+	pending := env.apiResponse(t, paidPath)
+	webhook := map[string]string{"type": payment.EventPaid, "checkout_id": "fake_1", "payment_id": "pi_1"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	failed, _ := env.startPaidBooking(t, 2)
+	failedPath := fmt.Sprintf("/api/bookings/%d", failed.ID)
+	webhook = map[string]string{"type": payment.EventFailed, "checkout_id": "fake_2"}
+	if rec := env.do(t, "POST", "/api/payments/webhook", webhook); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	for name, tc := range map[string]struct {
+		path      string
+		responses []pageResponse
+		shown     string
+	}{
+		// The client is back before the webhook, which arrives while the page waits
+		"paid":    {paidPath, []pageResponse{pending, pending, env.apiResponse(t, paidPath)}, "success-section"},
+		"waiting": {paidPath, []pageResponse{pending}, "pending-section"},
+		"failed":  {failedPath, []pageResponse{env.apiResponse(t, failedPath)}, "cancelled-section"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			page := loadPage(t, pageRun{
+				Scripts:   []string{"money.js", "confirmation.js"},
+				Search:    "?id=" + strings.TrimPrefix(tc.path, "/api/bookings/"),
+				Responses: map[string][]pageResponse{"GET " + tc.path: tc.responses},
+			})
+			for _, id := range []string{"success-section", "pending-section", "cancelled-section", "error-section"} {
+				if shown := page.Elements[id].Display == "block"; shown != (id == tc.shown) {
+					t.Errorf("%s shown = %v", id, shown)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+// setServiceTax sets the tax of a service through the admin API
+func (e *testEnv) setServiceTax(t *testing.T, serviceID int, body map[string]any) models.MassageType {
+	t.Helper()
+
+	var service models.MassageType
+	decode(t, e.do(t, "PUT", fmt.Sprintf("/api/admin/massage-types/%d/tax", serviceID), body), &service)
+	return service
+}
+
+func TestServiceTaxIsStoredOnBookings(t *testing.T) {
+	env := newTestEnv(t)
+	service := env.setServiceTax(t, 1, map[string]any{"tax_rate": 22})
+	if service.TaxRate != 2200 || !service.TaxInclusive || service.Price.Amount != 5000 {
+		t.Fatalf("unexpected service %+v", service)
+	}
+	env.setServiceTax(t, 2, map[string]any{"tax_rate": 9.5, "tax_inclusive": false})
+
+	var services []models.MassageType
+	decode(t, env.do(t, "GET", "/api/massage-types", nil), &services)
+	if services[1].TaxRate != 950 || services[1].TaxInclusive {
+		t.Errorf("expected 9.5%% added to Deep Tissue, got %+v", services[1])
+	}
+
+	// 50.00 including 22% is 40.98 net and 9.02 tax
+	inclusive := env.book(t, 1)
+	want := models.TaxAmounts{
+		TaxRate: 2200, TaxInclusive: true,
+		Net: models.NewMoney(4098, "EUR"), Tax: models.NewMoney(902, "EUR"), Gross: models.NewMoney(5000, "EUR"),
+	}
+	if inclusive.TaxAmounts != want || inclusive.Total.Amount != 5000 {
+		t.Errorf("expected %+v, got %+v with total %d", want, inclusive.TaxAmounts, inclusive.Total.Amount)
+	}
+
+	// 70.00 plus 9.5% is 76.65 to pay
+	exclusive := env.book(t, 2)
+	want = models.TaxAmounts{
+		TaxRate: 950,
+		Net:     models.NewMoney(7000, "EUR"), Tax: models.NewMoney(665, "EUR"), Gross: models.NewMoney(7665, "EUR"),
+	}
+	if exclusive.TaxAmounts != want || exclusive.Price.Amount != 7000 || exclusive.Total.Amount != 7665 {
+		t.Errorf("expected %+v, got %+v with total %d", want, exclusive.TaxAmounts, exclusive.Total.Amount)
+	}
+
+	// Bookings keep their tax when the rate changes
+	env.setServiceTax(t, 2, map[string]any{"tax_rate": 0})
+	var fetched models.BookingDetail
+	decode(t, env.do(t, "GET", fmt.Sprintf("/api/bookings/%d", exclusive.ID), nil), &fetched)
+	if fetched.TaxAmounts != want {
+		t.Errorf("booking tax changed to %+v", fetched.TaxAmounts)
+	}
+}
+
+func TestQuoteAddsTaxAfterDiscount(t *testing.T) {
+	env := newTestEnv(t)
+	env.setServiceTax(t, 1, map[string]any{"tax_rate": 20, "tax_inclusive": false})
+	env.createPromo(t, models.PromoCode{Code: "TEN", Kind: models.PromoKindFixed, Value: 1000})
+
+	var quote models.Quote
+	decode(t, env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, PromoCode: "TEN"}), &quote)
+	if quote.Net.Amount != 4000 || quote.Tax.Amount != 800 || quote.Gross.Amount != 4800 || quote.Total.Amount != 4800 {
+		t.Errorf("expected 40.00 + 8.00 tax, got %+v", quote)
+	}
+}
+
+func TestSetServiceTaxValidation(t *testing.T) {
+	env := newTestEnv(t)
+
+	tests := []struct {
+		name string
+		path string
+		body any
+		want int
+	}{
+		{"missing rate", "/api/admin/massage-types/1/tax", map[string]any{"tax_inclusive": true}, http.StatusBadRequest},
+		{"over 100%", "/api/admin/massage-types/1/tax", map[string]any{"tax_rate": 120}, http.StatusBadRequest},
+		{"negative", "/api/admin/massage-types/1/tax", map[string]any{"tax_rate": -5}, http.StatusBadRequest},
+		{"unknown service", "/api/admin/massage-types/99/tax", map[string]any{"tax_rate": 22}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := env.do(t, "PUT", tt.path, tt.body); rec.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
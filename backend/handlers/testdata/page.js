@@ -0,0 +1,127 @@
+// Runs the browser scripts of a static page in node with a minimal DOM and
+// canned API responses, then prints what the page showed as JSON.
+//
+// Usage: node page.js < run.json, where run.json has
+//   scripts:   paths of the scripts to load, in order
+//   search:    the page's query string, e.g. "?id=1"
+//   responses: "METHOD /path" to a list of {status, body}; the last one repeats
+//   run:       optional code to run in the page after it has loaded
+'use strict';
+
+const fs = require('fs');
+const vm = require('vm');
+
+const input = JSON.parse(fs.readFileSync(0, 'utf8'));
+
+const elements = {};
+const requests = [];
+const alerts = [];
+const listeners = [];
+let timers = 0;
+
+function makeElement(id) {
+    return {
+        id: id,
+        textContent: '',
+        innerHTML: '',
+        value: '',
+        checked: false,
+        disabled: false,
+        className: '',
+        style: {},
+        children: [],
+        classList: { add() {}, remove() {}, toggle() {}, contains() { return false; } },
+        addEventListener() {},
+        appendChild(child) { this.children.push(child); },
+        remove() {},
+        reset() {},
+        scrollIntoView() {},
+        querySelector() { return null; }
+    };
+}
+
+function element(id) {
+    if (!elements[id]) {
+        elements[id] = makeElement(id);
+    }
+    return elements[id];
+}
+
+const responses = input.responses || {};
+async function fetch(path, options) {
+    const key = ((options && options.method) || 'GET') + ' ' + path;
+    requests.push({ key: key, body: options && options.body ? JSON.parse(options.body) : null });
+    const queue = responses[key] || [{ status: 404, body: 'not found' }];
+    const response = queue.length > 1 ? queue.shift() : queue[0];
+    const body = typeof response.body === 'string' ? response.body : JSON.stringify(response.body);
+    return {
+        ok: response.status >= 200 && response.status < 300,
+        status: response.status,
+        json: async () => JSON.parse(body),
+        text: async () => body
+    };
+}
+
+const location = { search: input.search || '', href: '' };
+const context = {
+    console: console,
+    Intl: Intl,
+    URLSearchParams: URLSearchParams,
+    navigator: { language: 'en-US' },
+    location: location,
+    fetch: fetch,
+    alert: (message) => alerts.push(message),
+    // Timers fire straight away, a bounded number of times, so polling ends
+    setTimeout: (fn) => { if (++timers <= 20) setImmediate(fn); return timers; },
+    clearTimeout() {},
+    setInterval: () => 0,
+    clearInterval() {},
+    MutationObserver: class { observe() {} },
+    document: {
+        getElementById: element,
+        createElement: () => makeElement(''),
+        querySelector: () => null,
+        querySelectorAll: () => [],
+        addEventListener: (type, fn) => { if (type === 'DOMContentLoaded') listeners.push(fn); },
+        head: makeElement('head'),
+        body: makeElement('body')
+    },
+    element: element
+};
+context.window = context;
+context.window.addEventListener = () => {};
+context.window.scrollTo = () => {};
+vm.createContext(context);
+
+function settle() {
+    let rounds = 0;
+    return new Promise((resolve) => {
+        const next = () => (++rounds >= 200 ? resolve() : setImmediate(next));
+        next();
+    });
+}
+
+(async () => {
+    for (const script of input.scripts) {
+        vm.runInContext(fs.readFileSync(script, 'utf8'), context, { filename: script });
+    }
+    listeners.forEach((fn) => fn());
+    await settle();
+    if (input.run) {
+        await vm.runInContext(`(async () => { ${input.run} })()`, context);
+        await settle();
+    }
+
+    const shown = {};
+    for (const [id, el] of Object.entries(elements)) {
+        shown[id] = {
+            text: String(el.textContent),
+            html: el.innerHTML + el.children.map((child) => child.innerHTML).join(''),
+            display: el.style.display || ''
+        };
+    }
+    process.stdout.write(JSON.stringify({ elements: shown, requests: requests, alerts: alerts, location: location.href }));
+})().catch((error) => {
+    console.error(error);
+    process.exit(1);
+});
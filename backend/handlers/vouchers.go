@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"massage-booking/backend/database"
+	"massage-booking/backend/models"
+)
+
+// maxVoucherMessage bounds the personal message printed on a gift voucher
+const maxVoucherMessage = 500
+
+// GetVoucherBalance handles GET /api/vouchers/:code so clients can check
+// what is left on a gift voucher
+func (s *Server) GetVoucherBalance(w http.ResponseWriter, r *http.Request) {
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Content-Type", "application/json")
+
+	// Handle preflight OPTIONS request
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	voucher, ok := s.lookupVoucher(w, r)
+	if !ok {
+		return
+	}
+
+	// Who bought it and for whom is only shown to staff
+	voucher.PurchaserName, voucher.RecipientName, voucher.RecipientEmail, voucher.Message = "", "", "", ""
+	if err := json.NewEncoder(w).Encode(voucher); err != nil {
+		log.Printf("Error encoding voucher response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// Vouchers handles GET and POST /api/admin/vouchers
+func (s *Server) Vouchers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		s.listVouchers(w, r)
+	case "POST":
+		s.issueVoucher(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// listVouchers responds with all gift vouchers and their balances
+func (s *Server) listVouchers(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := s.store.ListVouchers(r.Context())
+	if err != nil {
+		log.Printf("Error listing gift vouchers: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(vouchers); err != nil {
+		log.Printf("Error encoding gift vouchers response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// issueVoucher creates a gift voucher and emails the certificate to the
+// recipient if an address is given. The voucher is kept even if the email
+// fails; it can be sent again with SendVoucher.
+func (s *Server) issueVoucher(w http.ResponseWriter, r *http.Request) {
+	var req models.VoucherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateVoucherRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	voucher, err := s.store.IssueVoucher(ctx, req)
+	if err != nil {
+		if errors.Is(err, database.ErrServiceNotFound) {
+			http.Error(w, "Service not found", http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error issuing gift voucher: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Issued gift voucher %s worth %s", voucher.Code, voucher.Value)
+
+	if voucher.RecipientEmail != "" {
+		if err := s.mailer.SendVoucherEmail(ctx, voucher); err != nil {
+			log.Printf("Error sending gift voucher %s to %s: %v", voucher.Code, voucher.RecipientEmail, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(voucher); err != nil {
+		log.Printf("Error encoding gift voucher response: %v", err)
+	}
+}
+
+// validateVoucherRequest checks a gift voucher before it is issued and
+// normalizes its currency and text fields
+func validateVoucherRequest(req *models.VoucherRequest) error {
+	switch {
+	case req.ServiceID < 0:
+		return &ValidationError{Field: "service_id", Message: "Invalid service ID"}
+	case req.ServiceID > 0 && req.Amount != 0:
+		return &ValidationError{Field: "amount", Message: "Give either an amount or a service, not both"}
+	case req.ServiceID == 0 && req.Amount <= 0:
+		return &ValidationError{Field: "amount", Message: "Amount must be positive"}
+	}
+
+	if req.Currency != "" {
+		currency, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			return &ValidationError{Field: "currency", Message: "Invalid currency code"}
+		}
+		req.Currency = currency
+	}
+
+	req.PurchaserName = strings.TrimSpace(req.PurchaserName)
+	req.RecipientName = strings.TrimSpace(req.RecipientName)
+	req.RecipientEmail = strings.TrimSpace(req.RecipientEmail)
+	req.Message = strings.TrimSpace(req.Message)
+	if req.RecipientEmail != "" && !emailPattern.MatchString(req.RecipientEmail) {
+		return &ValidationError{Field: "recipient_email", Message: "Invalid recipient address"}
+	}
+	if utf8.RuneCountInString(req.Message) > maxVoucherMessage {
+		return &ValidationError{Field: "message", Message: fmt.Sprintf("Message must be at most %d characters", maxVoucherMessage)}
+	}
+	if req.ExpiresOn != "" {
+		if _, err := time.Parse("2006-01-02", req.ExpiresOn); err != nil {
+			return &ValidationError{Field: "expires_on", Message: "expires_on must be a date like 2026-12-31"}
+		}
+	}
+	return nil
+}
+
+// VoucherCertificate handles GET /api/admin/vouchers/:code/certificate,
+// responding with the printable HTML certificate
+func (s *Server) VoucherCertificate(w http.ResponseWriter, r *http.Request) {
+	// Only allow GET method
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	voucher, ok := s.lookupVoucher(w, r)
+	if !ok {
+		return
+	}
+
+	rendered, err := s.mailer.VoucherCertificate(voucher)
+	if err != nil {
+		log.Printf("Error rendering gift voucher %s: %v", voucher.Code, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, rendered.HTML)
+}
+
+// SendVoucher handles POST /api/admin/vouchers/:code/send, emailing the
+// certificate to the voucher's recipient again
+func (s *Server) SendVoucher(w http.ResponseWriter, r *http.Request) {
+	// Only allow POST method
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	voucher, ok := s.lookupVoucher(w, r)
+	if !ok {
+		return
+	}
+	if voucher.RecipientEmail == "" {
+		http.Error(w, "Voucher has no recipient address", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.mailer.SendVoucherEmail(r.Context(), voucher); err != nil {
+		log.Printf("Error sending gift voucher %s to %s: %v", voucher.Code, voucher.RecipientEmail, err)
+		http.Error(w, "Failed to send email: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	log.Printf("Sent gift voucher %s to %s", voucher.Code, voucher.RecipientEmail)
+}
+
+// lookupVoucher loads the gift voucher named in the path. It writes an
+// error response and returns false if it cannot be loaded.
+func (s *Server) lookupVoucher(w http.ResponseWriter, r *http.Request) (*models.GiftVoucher, bool) {
+	voucher, err := s.store.GetVoucher(r.Context(), r.PathValue("code"))
+	if err != nil {
+		if errors.Is(err, database.ErrVoucherNotFound) {
+			http.Error(w, "Voucher not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("Error getting gift voucher: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return voucher, true
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"massage-booking/backend/models"
+)
+
+// issueVoucher issues a gift voucher through the admin API
+func (e *testEnv) issueVoucher(t *testing.T, req models.VoucherRequest) models.GiftVoucher {
+	t.Helper()
+
+	rec := e.do(t, "POST", "/api/admin/vouchers", req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var voucher models.GiftVoucher
+	if err := json.Unmarshal(rec.Body.Bytes(), &voucher); err != nil {
+		t.Fatalf("decode gift voucher: %v", err)
+	}
+	return voucher
+}
+
+// bookWithVoucher books the first slot of the service paying with a gift voucher
+func (e *testEnv) bookWithVoucher(t *testing.T, serviceID int, code string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	slot := e.availableSlot(t, serviceID)
+	reservation := e.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.VoucherCode = code
+	return e.do(t, "POST", "/api/bookings", req)
+}
+
+// voucherBalance returns the remaining balance from the public lookup
+func (e *testEnv) voucherBalance(t *testing.T, code string) int64 {
+	t.Helper()
+
+	var voucher models.GiftVoucher
+	decode(t, e.do(t, "GET", "/api/vouchers/"+code, nil), &voucher)
+	return voucher.Balance.Amount
+}
+
+func TestGiftVoucherPartialRedemption(t *testing.T) {
+	env := newTestEnv(t)
+	voucher := env.issueVoucher(t, models.VoucherRequest{
+		Amount:         8000,
+		PurchaserName:  "John Doe",
+		RecipientName:  "Jane Doe",
+		RecipientEmail: "jane@example.com",
+		Message:        "Happy birthday!",
+	})
+	if !strings.HasPrefix(voucher.Code, "GIFT-") || voucher.Balance != models.NewMoney(8000, "EUR") {
+		t.Fatalf("unexpected voucher %+v", voucher)
+	}
+
+	msgs := env.mail.Messages()
+	if len(msgs) != 1 || msgs[0].To != "jane@example.com" || !strings.Contains(msgs[0].HTMLBody, voucher.Code) {
+		t.Fatalf("expected the certificate to be emailed, got %+v", msgs)
+	}
+
+	// The first booking is paid in full, leaving 30.00
+	rec := env.bookWithVoucher(t, 1, strings.ToLower(voucher.Code))
+	var first models.BookingDetail
+	decode(t, rec, &first)
+	if first.VoucherCode != voucher.Code || first.VoucherAmount.Amount != 5000 || first.Total.Amount != 0 {
+		t.Errorf("expected the voucher to pay for the booking, got %+v", first)
+	}
+
+	var balance models.GiftVoucher
+	decode(t, env.do(t, "GET", "/api/vouchers/"+voucher.Code, nil), &balance)
+	if balance.Balance.Amount != 3000 || balance.RecipientEmail != "" || balance.Message != "" {
+		t.Errorf("expected a balance of 3000 without personal details, got %+v", balance)
+	}
+
+	// The rest goes towards a dearer service
+	var quote models.Quote
+	decode(t, env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 2, VoucherCode: voucher.Code}), &quote)
+	if quote.VoucherAmount.Amount != 3000 || quote.Total.Amount != 4000 {
+		t.Errorf("unexpected quote %+v", quote)
+	}
+	if rec := env.bookWithVoucher(t, 2, voucher.Code); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := env.voucherBalance(t, voucher.Code); got != 0 {
+		t.Errorf("expected the voucher to be used up, got %d", got)
+	}
+	if rec := env.bookWithVoucher(t, 1, voucher.Code); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "no balance left") {
+		t.Errorf("expected 400 for a used up voucher, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Cancelling gives the amount back
+	env.do(t, "POST", fmt.Sprintf("/api/admin/bookings/%d/cancel", first.ID), nil)
+	if got := env.voucherBalance(t, voucher.Code); got != 5000 {
+		t.Errorf("expected 5000 back after cancelling, got %d", got)
+	}
+}
+
+func TestServiceGiftVoucher(t *testing.T) {
+	env := newTestEnv(t)
+	env.createPromo(t, models.PromoCode{Code: "HALF", Kind: models.PromoKindPercent, Value: 50})
+	voucher := env.issueVoucher(t, models.VoucherRequest{ServiceID: 3})
+	if voucher.ServiceName == "" || voucher.Value.Amount != 6500 {
+		t.Fatalf("expected the voucher to be worth the service, got %+v", voucher)
+	}
+
+	rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, VoucherCode: voucher.Code, Locale: "et"})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "valitud teenuse") {
+		t.Errorf("expected a localized 400 for another service, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// The voucher pays whatever the discount leaves and is then used up
+	slot := env.availableSlot(t, 3)
+	reservation := env.reserve(t, slot.ID)
+	req := bookingRequest(reservation.ReservationID, slot)
+	req.PromoCode, req.VoucherCode = "HALF", voucher.Code
+	var booking models.BookingDetail
+	decode(t, env.do(t, "POST", "/api/bookings", req), &booking)
+	if booking.Discount.Amount != 3250 || booking.VoucherAmount.Amount != 3250 || booking.Total.Amount != 0 {
+		t.Errorf("unexpected booking %+v", booking)
+	}
+	if got := env.voucherBalance(t, voucher.Code); got != 0 {
+		t.Errorf("expected the service voucher to be used up, got %d", got)
+	}
+}
+
+func TestGiftVoucherExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	voucher := env.issueVoucher(t, models.VoucherRequest{Amount: 2000, ExpiresOn: testNow.Format("2006-01-02")})
+
+	if rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, VoucherCode: voucher.Code}); rec.Code != http.StatusOK {
+		t.Errorf("expected the voucher to be valid on its last day, got %d", rec.Code)
+	}
+	env.clock.Advance(24 * time.Hour)
+	rec := env.do(t, "POST", "/api/quote", models.QuoteRequest{ServiceID: 1, VoucherCode: voucher.Code})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "expired") {
+		t.Errorf("expected 400 for an expired voucher, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := env.do(t, "GET", "/api/vouchers/GIFT-NONE-NONE", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown voucher, got %d", rec.Code)
+	}
+}
+
+func TestIssueGiftVoucherValidation(t *testing.T) {
+	env := newTestEnv(t)
+
+	for name, req := range map[string]models.VoucherRequest{
+		"no value":           {},
+		"negative amount":    {Amount: -100},
+		"amount and service": {Amount: 1000, ServiceID: 1},
+		"unknown service":    {ServiceID: 99},
+		"bad currency":       {Amount: 1000, Currency: "euro"},
+		"bad email":          {Amount: 1000, RecipientEmail: "jane"},
+		"bad expiry":         {Amount: 1000, ExpiresOn: "31.12.2026"},
+		"long message":       {Amount: 1000, Message: strings.Repeat("x", maxVoucherMessage+1)},
+	} {
+		if rec := env.do(t, "POST", "/api/admin/vouchers", req); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestGiftVoucherCertificate(t *testing.T) {
+	env := newTestEnv(t)
+	voucher := env.issueVoucher(t, models.VoucherRequest{Amount: 5000, RecipientName: "Jane", Locale: "et"})
+
+	rec := env.do(t, "GET", "/api/admin/vouchers/"+voucher.Code+"/certificate", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), voucher.Code) || !strings.Contains(rec.Body.String(), "Kinkekaart") {
+		t.Errorf("expected the certificate in Estonian, got %d", rec.Code)
+	}
+
+	if rec := env.do(t, "POST", "/api/admin/vouchers/"+voucher.Code+"/send", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a recipient address, got %d", rec.Code)
+	}
+	if len(env.mail.Messages()) != 0 {
+		t.Error("no email should be sent without a recipient address")
+	}
+
+	var vouchers []models.GiftVoucher
+	decode(t, env.do(t, "GET", "/api/admin/vouchers", nil), &vouchers)
+	if len(vouchers) != 1 || vouchers[0].RecipientName != "Jane" {
+		t.Errorf("unexpected vouchers %+v", vouchers)
+	}
+}
+
+func TestGiftVoucherWithPayment(t *testing.T) {
+	env, provider := newPaymentTestEnv(t)
+	voucher := env.issueVoucher(t, models.VoucherRequest{Amount: 2000})
+
+	rec := env.bookWithVoucher(t, 1, voucher.Code)
+	var booking models.BookingDetail
+	decode(t, rec, &booking)
+	checkouts := provider.Checkouts()
+	if booking.Status != models.BookingStatusPendingPayment || len(checkouts) != 1 || checkouts[0].Amount != 3000 {
+		t.Fatalf("expected a checkout for the rest, got %+v, %+v", booking, checkouts)
+	}
+
+	// An unpaid booking gives the voucher amount back when its hold expires
+	env.clock.Advance(31 * time.Minute)
+	if _, err := env.store.ReleaseExpiredHolds(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.voucherBalance(t, voucher.Code); got != 2000 {
+		t.Errorf("expected the full balance back, got %d", got)
+	}
+}
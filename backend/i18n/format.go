@@ -0,0 +1,152 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateNames holds the calendar words for one locale
+type dateNames struct {
+	weekdays      [7]string  // Sunday first, as time.Weekday
+	shortWeekdays [7]string  // Sunday first
+	months        [12]string // in the form used after a day number
+	shortMonths   [12]string
+}
+
+var names = map[Locale]dateNames{
+	English: {
+		weekdays:      [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortWeekdays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		months:        [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		shortMonths:   [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	},
+	Estonian: {
+		weekdays:      [7]string{"pühapäev", "esmaspäev", "teisipäev", "kolmapäev", "neljapäev", "reede", "laupäev"},
+		shortWeekdays: [7]string{"P", "E", "T", "K", "N", "R", "L"},
+		months:        [12]string{"jaanuar", "veebruar", "märts", "aprill", "mai", "juuni", "juuli", "august", "september", "oktoober", "november", "detsember"},
+		shortMonths:   [12]string{"jaan", "veebr", "märts", "apr", "mai", "juuni", "juuli", "aug", "sept", "okt", "nov", "dets"},
+	},
+	Russian: {
+		weekdays:      [7]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"},
+		shortWeekdays: [7]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"},
+		months:        [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"},
+		shortMonths:   [12]string{"янв", "фев", "мар", "апр", "мая", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"},
+	},
+}
+
+func (l Locale) names() dateNames {
+	if n, ok := names[l]; ok {
+		return n
+	}
+	return names[Default]
+}
+
+// FormatDate formats a date in full, e.g. "Monday, March 10, 2025",
+// "esmaspäev, 10. märts 2025" or "понедельник, 10 марта 2025 г."
+func (l Locale) FormatDate(t time.Time) string {
+	n := l.names()
+	weekday, month := n.weekdays[t.Weekday()], n.months[t.Month()-1]
+	switch l {
+	case Estonian:
+		return fmt.Sprintf("%s, %d. %s %d", weekday, t.Day(), month, t.Year())
+	case Russian:
+		return fmt.Sprintf("%s, %d %s %d г.", weekday, t.Day(), month, t.Year())
+	default:
+		return fmt.Sprintf("%s, %s %d, %d", weekday, month, t.Day(), t.Year())
+	}
+}
+
+// FormatShortDate formats a date compactly, e.g. "Mon 10 Mar", "E 10. märts" or "пн 10 мар"
+func (l Locale) FormatShortDate(t time.Time) string {
+	n := l.names()
+	weekday, month := n.shortWeekdays[t.Weekday()], n.shortMonths[t.Month()-1]
+	if l == Estonian {
+		return fmt.Sprintf("%s %d. %s", weekday, t.Day(), month)
+	}
+	return fmt.Sprintf("%s %d %s", weekday, t.Day(), month)
+}
+
+// FormatDateString formats a YYYY-MM-DD date with FormatDate, returning
+// the value unchanged if it cannot be parsed
+func (l Locale) FormatDateString(value string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return value
+	}
+	return l.FormatDate(date)
+}
+
+// FormatShortDateString formats a YYYY-MM-DD date with FormatShortDate
+func (l Locale) FormatShortDateString(value string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return value
+	}
+	return l.FormatShortDate(date)
+}
+
+// currencies lists the symbol and number of minor unit digits of
+// currencies that differ from the default of the code and two digits
+var currencies = map[string]struct {
+	symbol string
+	digits int
+}{
+	"EUR": {"€", 2},
+	"USD": {"$", 2},
+	"GBP": {"£", 2},
+	"RUB": {"₽", 2},
+	"JPY": {"¥", 0},
+	"ISK": {"kr", 0},
+}
+
+// FormatMoney formats an amount given in minor units of an ISO 4217
+// currency, e.g. "€1,234.50" in English and "1 234,50 €" (with
+// non-breaking spaces) in Estonian and Russian
+func (l Locale) FormatMoney(amount int64, currency string) string {
+	symbol, digits := currency, 2
+	if c, ok := currencies[currency]; ok {
+		symbol, digits = c.symbol, c.digits
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	unit := int64(1)
+	for i := 0; i < digits; i++ {
+		unit *= 10
+	}
+
+	thousands, decimal := ",", "."
+	if l == Estonian || l == Russian {
+		thousands, decimal = "\u00a0", ","
+	}
+	number := groupThousands(amount/unit, thousands)
+	if digits > 0 {
+		number += fmt.Sprintf("%s%0*d", decimal, digits, amount%unit)
+	}
+
+	switch {
+	case l == Estonian || l == Russian:
+		return sign + number + "\u00a0" + symbol
+	case symbol == currency:
+		return sign + symbol + "\u00a0" + number
+	default:
+		return sign + symbol + number
+	}
+}
+
+// groupThousands writes n with sep between groups of three digits
+func groupThousands(n int64, sep string) string {
+	digits := fmt.Sprint(n)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
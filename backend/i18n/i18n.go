@@ -0,0 +1,123 @@
+// Package i18n provides message catalogs and locale-aware formatting
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Locale is a supported language, identified by its ISO 639-1 code
+type Locale string
+
+// Supported locales
+const (
+	English  Locale = "en"
+	Estonian Locale = "et"
+	Russian  Locale = "ru"
+)
+
+// Default is used when no supported locale is requested
+const Default = English
+
+// Supported lists every locale with a message catalog
+var Supported = []Locale{English, Estonian, Russian}
+
+//go:embed locales/*.json
+var catalogFiles embed.FS
+
+// catalogs maps each locale to its messages, loaded from locales/<code>.json
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[Locale]map[string]string {
+	result := map[Locale]map[string]string{}
+	for _, l := range Supported {
+		data, err := catalogFiles.ReadFile(path.Join("locales", string(l)+".json"))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", l, err))
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %s: %v", l, err))
+		}
+		result[l] = messages
+	}
+	return result
+}
+
+// Parse returns the supported locale for a language tag such as "et",
+// "ru-RU" or "en_GB", or Default if the language is not supported
+func Parse(tag string) Locale {
+	if l, ok := lookup(tag); ok {
+		return l
+	}
+	return Default
+}
+
+// lookup matches the primary language subtag of tag against the supported locales
+func lookup(tag string) (Locale, bool) {
+	lang := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	for _, l := range Supported {
+		if string(l) == lang {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// FromAcceptLanguage picks the preferred supported locale from an
+// Accept-Language header, or Default if none is acceptable
+func FromAcceptLanguage(header string) Locale {
+	type candidate struct {
+		locale Locale
+		q      float64
+		order  int
+	}
+
+	var candidates []candidate
+	for i, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		l, ok := lookup(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{l, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].locale
+}
+
+// T returns the message for key, formatted with args. Missing translations
+// fall back to English, and unknown keys are returned as is.
+func (l Locale) T(key string, args ...any) string {
+	msg, ok := catalogs[l][key]
+	if !ok {
+		msg, ok = catalogs[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
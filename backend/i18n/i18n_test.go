@@ -0,0 +1,101 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for key := range catalogs[English] {
+		for _, l := range Supported {
+			if _, ok := catalogs[l][key]; !ok {
+				t.Errorf("%s catalog is missing %q", l, key)
+			}
+		}
+	}
+	for _, l := range Supported {
+		for key := range catalogs[l] {
+			if _, ok := catalogs[English][key]; !ok {
+				t.Errorf("%s catalog has unknown key %q", l, key)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for tag, want := range map[string]Locale{
+		"et": Estonian, "ET-ee": Estonian, "ru_RU": Russian, "en-GB": English, "": English, "de": English,
+	} {
+		if got := Parse(tag); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", tag, got, want)
+		}
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	for header, want := range map[string]Locale{
+		"ru-RU,ru;q=0.9,en;q=0.8": Russian,
+		"de-DE,de;q=0.9,et;q=0.5": Estonian,
+		"en;q=0.3, et;q=0.7":      Estonian,
+		"fr":                      English,
+		"":                        English,
+	} {
+		if got := FromAcceptLanguage(header); got != want {
+			t.Errorf("FromAcceptLanguage(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := Russian.T("confirmation.subject", "BK-1"); got != "Подтверждение бронирования - BK-1" {
+		t.Errorf("unexpected translation %q", got)
+	}
+	if got := Locale("de").T("email.service"); got != "Service:" {
+		t.Errorf("expected English fallback, got %q", got)
+	}
+	if got := English.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("expected key for unknown message, got %q", got)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	for l, want := range map[Locale]string{
+		English:  "Monday, March 10, 2025",
+		Estonian: "esmaspäev, 10. märts 2025",
+		Russian:  "понедельник, 10 марта 2025 г.",
+	} {
+		if got := l.FormatDate(date); got != want {
+			t.Errorf("%s FormatDate = %q, want %q", l, got, want)
+		}
+	}
+	if got := Estonian.FormatShortDate(date); got != "E 10. märts" {
+		t.Errorf("Estonian FormatShortDate = %q", got)
+	}
+	if got := English.FormatDateString("not a date"); got != "not a date" {
+		t.Errorf("expected unparseable date unchanged, got %q", got)
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	for _, tt := range []struct {
+		locale   Locale
+		amount   int64
+		currency string
+		want     string
+	}{
+		{English, 5000, "EUR", "€50.00"},
+		{English, 123450, "EUR", "€1,234.50"},
+		{Estonian, 6550, "EUR", "65,50\u00a0€"},
+		{Russian, 123450, "EUR", "1\u00a0234,50\u00a0€"},
+		{English, -500, "EUR", "-€5.00"},
+		{English, 1205, "USD", "$12.05"},
+		{English, 5000, "JPY", "¥5,000"},
+		{English, 49900, "SEK", "SEK\u00a0499.00"},
+		{Estonian, 49900, "SEK", "499,00\u00a0SEK"},
+	} {
+		if got := tt.locale.FormatMoney(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("%s FormatMoney(%d, %s) = %q, want %q", tt.locale, tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -1,71 +1,232 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"net/mail"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
+	_ "time/tzdata" // embedded zone database for containers without tzdata
 
+	"massage-booking/backend/calendar"
+	"massage-booking/backend/clock"
 	"massage-booking/backend/database"
+	"massage-booking/backend/email"
 	"massage-booking/backend/handlers"
+	"massage-booking/backend/models"
+	"massage-booking/backend/payment"
+	"massage-booking/backend/sms"
+)
+
+const (
+	// shutdownTimeout bounds how long in-flight requests may take to finish
+	shutdownTimeout = 30 * time.Second
+
+	// requestTimeout bounds how long a handler may spend on a request, including database work
+	requestTimeout = 15 * time.Second
 )
 
 func main() {
+	// Cancelled on SIGINT/SIGTERM to start graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	clk := clock.Real{}
+
+	// Slot dates and times are interpreted in the business timezone
+	loc, err := clock.LoadLocation(os.Getenv("BUSINESS_TIMEZONE"))
+	if err != nil {
+		log.Fatalf("Invalid business timezone: %v", err)
+	}
+	log.Printf("Using business timezone %s", loc)
+
+	currency, err := models.ParseCurrency(os.Getenv("CURRENCY"))
+	if err != nil {
+		log.Fatalf("Invalid CURRENCY: %v", err)
+	}
+	vatRate, err := models.ParseTaxRate(os.Getenv("VAT_RATE"))
+	if err != nil {
+		log.Fatalf("Invalid VAT_RATE: %v", err)
+	}
+
 	// Initialize database
-	if err := database.InitDB(); err != nil {
+	store, err := database.Open(ctx, "./massage_booking.db", clk, loc)
+	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	store.SetCurrency(currency)
+	store.SetTaxRate(vatRate)
+	if err := store.Seed(ctx); err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
 
-	// Start cleanup job for expired reservations
-	database.StartCleanupJob()
+	emailConfig := email.GetEmailConfig()
+	transport, err := email.NewMailer(emailConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure email transport: %v", err)
+	}
+	log.Printf("Using %s email transport", transport.Name())
+	branding, err := email.GetBranding()
+	if err != nil {
+		log.Fatalf("Invalid email branding: %v", err)
+	}
+	renderer, err := email.NewRenderer(branding, emailConfig.TemplateDir)
+	if err != nil {
+		log.Fatalf("Failed to load email templates: %v", err)
+	}
+	mailer := email.NewSender(transport, renderer, emailConfig)
 
-	// Set up graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-c
-		log.Println("Shutting down gracefully...")
-		if err := database.CloseDB(); err != nil {
-			log.Printf("Error closing database: %v", err)
+	// Invoices name the salon as the seller unless set otherwise
+	store.SetInvoiceSettings(database.InvoiceSettings{
+		Seller: models.Seller{
+			Name:         getEnvOrDefault("INVOICE_SELLER_NAME", branding.SalonName),
+			Address:      getEnvOrDefault("INVOICE_SELLER_ADDRESS", branding.Address),
+			RegistryCode: os.Getenv("INVOICE_REGISTRY_CODE"),
+			VATNumber:    os.Getenv("INVOICE_VAT_NUMBER"),
+			Email:        os.Getenv("INVOICE_SELLER_EMAIL"),
+			BankAccount:  os.Getenv("INVOICE_BANK_ACCOUNT"),
+		},
+		Prefix: os.Getenv("INVOICE_PREFIX"),
+	})
+	if emailConfig.AttachInvoices {
+		mailer.AttachInvoices(store)
+		log.Println("Attaching invoices to confirmation emails")
+	}
+
+	// Staff are notified of bookings and cancellations and sent a daily digest
+	digestTime, err := email.ParseDigestTime(emailConfig.DigestTime)
+	if err != nil {
+		log.Fatalf("Invalid DIGEST_TIME: %v", err)
+	}
+	if emailConfig.StaffEmail != "" {
+		if _, err := mail.ParseAddress(emailConfig.StaffEmail); err != nil {
+			log.Fatalf("Invalid STAFF_EMAIL: %v", err)
 		}
-		os.Exit(0)
-	}()
+		store.SetStaffEmail(emailConfig.StaffEmail)
+		log.Printf("Sending staff notifications to %s", emailConfig.StaffEmail)
+	}
+
+	smsConfig := sms.GetConfig()
+	smsProvider, err := sms.NewProvider(smsConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure SMS provider: %v", err)
+	}
+	log.Printf("Using %s SMS provider", smsProvider.Name())
+	smsSender := sms.NewSender(smsProvider, branding.SalonName)
+
+	paymentConfig, err := payment.GetConfig()
+	if err != nil {
+		log.Fatalf("Invalid payment configuration: %v", err)
+	}
+	payments, err := payment.NewProvider(paymentConfig)
+	if err != nil {
+		log.Fatalf("Failed to configure payment provider: %v", err)
+	}
+	if payments != nil {
+		log.Printf("Collecting %d%% of the price online with the %s payment provider", paymentConfig.DepositPercent, payments.Name())
+	}
+
+	srv := handlers.NewServer(store, mailer, clk, handlers.Config{
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		CalendarToken: os.Getenv("CALENDAR_FEED_TOKEN"),
+		Salon:         calendar.Salon{Name: branding.SalonName, Address: branding.Address},
+
+		PhoneCountryCode: smsConfig.DefaultCountryCode,
+
+		Payments: payments,
+		Payment:  *paymentConfig,
+	})
+
+	reminderOffsets, err := database.ParseReminderOffsets(os.Getenv("REMINDER_OFFSETS"))
+	if err != nil {
+		log.Fatalf("Invalid REMINDER_OFFSETS: %v", err)
+	}
+
+	// Start background jobs: reservation and payment hold cleanup, reminders, the staff digest and the email outbox worker
+	var jobs sync.WaitGroup
+	store.StartCleanupJob(ctx, &jobs)
+	store.StartReminderJob(ctx, &jobs, reminderOffsets)
+	if emailConfig.StaffEmail != "" && digestTime != "" {
+		email.NewDigest(store, mailer, clk, loc, emailConfig.StaffEmail, digestTime).Start(ctx, &jobs)
+	}
+	email.NewWorker(store, mailer, smsSender, clk, email.DefaultWorkerConfig()).Start(ctx, &jobs)
 
 	// Set up routes
-	setupRoutes()
+	server := &http.Server{
+		Addr:              ":8080",
+		Handler:           withRequestTimeout(setupRoutes(srv), requestTimeout),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      requestTimeout + 5*time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
 
 	// Start server
-	port := ":8080"
-	log.Printf("Server starting on port %s", port)
+	log.Printf("Server starting on port %s", server.Addr)
 	log.Printf("Static files served from: /static")
 	log.Printf("API endpoints available at: /api")
 
-	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down gracefully...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and wait for in-flight requests
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+
+	// Wait for background jobs; unsent emails stay queued in the outbox
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-shutdownCtx.Done():
+		log.Println("Timed out waiting for background jobs")
+	}
+
+	if err := store.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
 	}
+	log.Println("Shutdown complete")
 }
 
 // setupRoutes configures all HTTP routes
-func setupRoutes() {
-	// API routes
-	http.HandleFunc("/api/massage-types", handlers.GetMassageTypesHandler)
-	http.HandleFunc("/api/slots", handlers.GetSlotsHandler)
+func setupRoutes(srv *handlers.Server) *http.ServeMux {
+	mux := http.NewServeMux()
 
-	// Story #2 routes
-	http.HandleFunc("/api/reservations", handlers.CreateReservation)
-	http.HandleFunc("/api/reservations/", handlers.DeleteReservation)
-	http.HandleFunc("/api/bookings", handlers.CreateBooking)
-
-	// Story #3 routes
-	http.HandleFunc("/api/bookings/", handlers.GetBooking)
+	// API and operational routes
+	srv.RegisterRoutes(mux)
 
 	// Static file server for frontend
 	fs := http.FileServer(http.Dir("./backend/static/"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+	mux.Handle("/static/", http.StripPrefix("/static/", fs))
 
 	// Serve specific pages
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case "/":
 			http.ServeFile(w, r, "./backend/static/index.html")
@@ -77,4 +238,23 @@ func setupRoutes() {
 	})
 
 	log.Println("Routes configured successfully")
+	return mux
+}
+
+// getEnvOrDefault returns the environment variable key, or defaultValue if it is empty
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// withRequestTimeout attaches a deadline to every request context so that
+// database calls made with r.Context() are cancelled when it expires
+func withRequestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpositionFormat(t *testing.T) {
+	counter := &CounterVec{name: "test_total", help: "Test counter.", labels: []string{"path"}, values: make(map[string]float64)}
+	counter.Inc(`a"b\c` + "\n")
+	counter.Add(2, "ü")
+
+	histogram := &HistogramVec{name: "test_seconds", help: "Test histogram.", buckets: []float64{0.1, 1}, labels: []string{"route"}, series: make(map[string]*histogram)}
+	histogram.Observe(0.05, "/x")
+	histogram.Observe(0.5, "/x")
+	histogram.Observe(5, "/x")
+
+	var out bytes.Buffer
+	counter.write(&out)
+	histogram.write(&out)
+
+	want := strings.Join([]string{
+		"# HELP test_total Test counter.",
+		"# TYPE test_total counter",
+		`test_total{path="a\"b\\c\n"} 1`,
+		`test_total{path="ü"} 2`,
+		"# HELP test_seconds Test histogram.",
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{route="/x",le="0.1"} 1`,
+		`test_seconds_bucket{route="/x",le="1"} 2`,
+		`test_seconds_bucket{route="/x",le="+Inf"} 3`,
+		`test_seconds_sum{route="/x"} 5.55`,
+		`test_seconds_count{route="/x"} 3`,
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
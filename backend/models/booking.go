@@ -2,6 +2,13 @@ package models
 
 import "time"
 
+// Booking statuses
+const (
+	BookingStatusPendingPayment = "pending_payment" // slot held while the client pays online
+	BookingStatusConfirmed      = "confirmed"
+	BookingStatusCancelled      = "cancelled"
+)
+
 // Booking represents a confirmed booking
 type Booking struct {
 	ID         int       `json:"id" db:"id"`
@@ -12,23 +19,55 @@ type Booking struct {
 	ServiceID  int       `json:"service_id" db:"service_id"`
 	Date       string    `json:"date" db:"date"`
 	TimeSlot   string    `json:"time_slot" db:"time_slot"`
+	StartsAt   time.Time `json:"starts_at" db:"starts_at"`
+	Status     string    `json:"status" db:"status"`
+	SMSOptIn   bool      `json:"sms_opt_in" db:"sms_opt_in"`
+	Locale     string    `json:"locale" db:"locale"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	HoldExpiresAt *time.Time `json:"hold_expires_at,omitempty" db:"hold_expires_at"` // set while pending payment
 }
 
 // BookingDetail represents a booking with service details for confirmation page
 type BookingDetail struct {
-	ID          int       `json:"id" db:"id"`
-	Reference   string    `json:"reference" db:"reference"`
-	ClientName  string    `json:"client_name" db:"client_name"`
-	Email       string    `json:"email" db:"email"`
-	Phone       string    `json:"phone" db:"phone"`
-	ServiceID   int       `json:"service_id" db:"service_id"`
-	ServiceName string    `json:"service_name" db:"service_name"`
-	Duration    int       `json:"duration" db:"duration"`
-	Price       float64   `json:"price" db:"price"`
-	Date        string    `json:"date" db:"date"`
-	TimeSlot    string    `json:"time_slot" db:"time_slot"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID            int        `json:"id" db:"id"`
+	Reference     string     `json:"reference" db:"reference"`
+	ClientName    string     `json:"client_name" db:"client_name"`
+	Email         string     `json:"email" db:"email"`
+	Phone         string     `json:"phone" db:"phone"`
+	ServiceID     int        `json:"service_id" db:"service_id"`
+	ServiceName   string     `json:"service_name" db:"service_name"`
+	Duration      int        `json:"duration" db:"duration"`
+	Price         Money      `json:"price" db:"price_cents"`
+	PromoCode     string     `json:"promo_code,omitempty" db:"promo_code"`
+	Discount      Money      `json:"discount" db:"discount_cents"`
+	VoucherCode   string     `json:"voucher_code,omitempty" db:"voucher_code"`
+	VoucherAmount Money      `json:"voucher_amount" db:"voucher_cents"`    // paid from a gift voucher
+	PackageID     int        `json:"package_id,omitempty" db:"package_id"` // session package that paid for the booking
+	Total         Money      `json:"total" db:"-"`                         // gross less voucher, zero with a package
+	Refunded      Money      `json:"refunded" db:"-"`                      // succeeded refunds
+	Date          string     `json:"date" db:"date"`
+	TimeSlot      string     `json:"time_slot" db:"time_slot"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	Status        string     `json:"status" db:"status"`
+	SMSOptIn      bool       `json:"sms_opt_in" db:"sms_opt_in"`
+	Locale        string     `json:"locale" db:"locale"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+
+	// Tax on the price less discount, as it was when booked
+	TaxAmounts
+
+	// Set while the booking is pending payment
+	HoldExpiresAt *time.Time `json:"hold_expires_at,omitempty" db:"hold_expires_at"`
+	CheckoutURL   string     `json:"checkout_url,omitempty" db:"-"`
+}
+
+// AdminBooking is a booking as shown to staff, with the service's current
+// catalog price next to the price it was booked at
+type AdminBooking struct {
+	BookingDetail
+	CurrentPrice *Money `json:"current_price"` // nil if the service no longer exists
 }
 
 // BookingRequest represents the request to create a booking
@@ -40,4 +79,9 @@ type BookingRequest struct {
 	ServiceID     int    `json:"service_id"`
 	Date          string `json:"date"`
 	TimeSlot      string `json:"time_slot"`
+	SMSOptIn      bool   `json:"sms_opt_in"`   // also send confirmations and reminders by SMS
+	Locale        string `json:"locale"`       // language for messages, e.g. "et"; defaults to Accept-Language
+	PromoCode     string `json:"promo_code"`   // optional discount code
+	VoucherCode   string `json:"voucher_code"` // optional gift voucher to pay with
+	PackageCode   string `json:"package_code"` // optional session package to pay with
 }
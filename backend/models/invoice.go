@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// Invoice line kinds
+const (
+	InvoiceLineService  = "service"
+	InvoiceLineDiscount = "discount" // promo code discount, description is the code
+	InvoiceLinePackage  = "package"  // session from a prepaid package
+)
+
+// Seller is the business named on invoices
+type Seller struct {
+	Name         string `json:"name"`
+	Address      string `json:"address,omitempty"`
+	RegistryCode string `json:"registry_code,omitempty"` // company registration number
+	VATNumber    string `json:"vat_number,omitempty"`
+	Email        string `json:"email,omitempty"`
+	BankAccount  string `json:"bank_account,omitempty"` // IBAN shown for paying the amount due
+}
+
+// InvoiceLine is one item on an invoice; amounts include tax
+type InvoiceLine struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   Money  `json:"unit_price"`
+	Amount      Money  `json:"amount"`
+}
+
+// VATLine is the tax at one rate in an invoice's VAT breakdown
+type VATLine struct {
+	Rate  TaxRate `json:"rate"`
+	Net   Money   `json:"net"`
+	Tax   Money   `json:"tax"`
+	Gross Money   `json:"gross"`
+}
+
+// Invoice is the invoice or receipt for a booking. Its number and issue
+// date are fixed when it is first issued; the lines come from the booking.
+type Invoice struct {
+	ID        int       `json:"id" db:"id"`
+	Number    string    `json:"number" db:"number"`
+	BookingID int       `json:"booking_id" db:"booking_id"`
+	Reference string    `json:"reference" db:"-"`
+	IssuedOn  string    `json:"issued_on" db:"issued_on"` // YYYY-MM-DD in the business timezone
+	Seller    Seller    `json:"seller" db:"seller"`
+	VATRate   TaxRate   `json:"vat_rate" db:"vat_rate"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	BuyerName   string `json:"buyer_name" db:"-"`
+	BuyerEmail  string `json:"buyer_email" db:"-"`
+	ServiceDate string `json:"service_date" db:"-"`
+	TimeSlot    string `json:"time_slot" db:"-"`
+	Locale      string `json:"locale" db:"-"`
+
+	Lines            []InvoiceLine `json:"lines" db:"-"`
+	PricesIncludeTax bool          `json:"prices_include_tax" db:"-"` // line amounts are gross rather than net
+	VAT              []VATLine     `json:"vat" db:"-"`
+	Net              Money         `json:"net" db:"-"`
+	Tax              Money         `json:"tax" db:"-"`
+	Gross            Money         `json:"gross" db:"-"`
+	VoucherCode      string        `json:"voucher_code,omitempty" db:"-"`
+	PaidVoucher      Money         `json:"paid_voucher" db:"-"` // paid from a gift voucher
+	PaidOnline       Money         `json:"paid_online" db:"-"`  // captured online payments
+	Due              Money         `json:"due" db:"-"`
+}
+
+// IsReceipt reports whether nothing is left to pay, so the document is a receipt
+func (inv *Invoice) IsReceipt() bool {
+	return inv.Due.Amount == 0
+}
+
+// Itemize fills in the buyer, lines, VAT breakdown and payments from the
+// booking the invoice is for. The lines are priced as the service was, with
+// or without VAT, and the breakdown is the tax stored on the booking; a gift
+// voucher is a means of payment, not a discount.
+func (inv *Invoice) Itemize(b *BookingDetail, paidOnline int64) {
+	currency := b.Price.Currency
+	inv.BookingID = b.ID
+	inv.Reference = b.Reference
+	inv.BuyerName, inv.BuyerEmail = b.ClientName, b.Email
+	inv.ServiceDate, inv.TimeSlot = b.Date, b.TimeSlot
+	inv.Locale = b.Locale
+
+	inv.Lines = []InvoiceLine{{Kind: InvoiceLineService, Description: b.ServiceName, Quantity: 1, UnitPrice: b.Price, Amount: b.Price}}
+	switch {
+	case b.PackageID != 0:
+		credit := NewMoney(-b.Price.Amount, currency)
+		inv.Lines = append(inv.Lines, InvoiceLine{Kind: InvoiceLinePackage, Quantity: 1, UnitPrice: credit, Amount: credit})
+	case b.Discount.Amount > 0:
+		discount := NewMoney(-b.Discount.Amount, currency)
+		inv.Lines = append(inv.Lines, InvoiceLine{Kind: InvoiceLineDiscount, Description: b.PromoCode, Quantity: 1, UnitPrice: discount, Amount: discount})
+	}
+
+	inv.PricesIncludeTax = b.TaxInclusive
+	inv.Net, inv.Tax, inv.Gross = b.Net, b.Tax, b.Gross
+	inv.VAT = []VATLine{{Rate: b.TaxRate, Net: b.Net, Tax: b.Tax, Gross: b.Gross}}
+
+	inv.VoucherCode = b.VoucherCode
+	inv.PaidVoucher = b.VoucherAmount
+	inv.PaidOnline = NewMoney(paidOnline, currency)
+	inv.Due = NewMoney(max(b.Gross.Amount-b.VoucherAmount.Amount-paidOnline, 0), currency)
+}
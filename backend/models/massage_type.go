@@ -2,8 +2,16 @@ package models
 
 // MassageType represents a massage service offered
 type MassageType struct {
-	ID       int     `json:"id" db:"id"`
-	Name     string  `json:"name" db:"name"`
-	Duration int     `json:"duration" db:"duration"` // minutes
-	Price    float64 `json:"price" db:"price"`       // euros
+	ID           int     `json:"id" db:"id"`
+	Name         string  `json:"name" db:"name"`
+	Duration     int     `json:"duration" db:"duration"` // minutes
+	Price        Money   `json:"price" db:"price_cents"`
+	TaxRate      TaxRate `json:"tax_rate" db:"tax_rate"`
+	TaxInclusive bool    `json:"tax_inclusive" db:"tax_inclusive"` // price includes the tax; otherwise it is added on top
+}
+
+// ServiceTaxRequest sets how a service is taxed
+type ServiceTaxRequest struct {
+	TaxRate      *TaxRate `json:"tax_rate"`
+	TaxInclusive *bool    `json:"tax_inclusive"` // defaults to true
 }
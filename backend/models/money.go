@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency is used when no currency is configured
+const DefaultCurrency = "EUR"
+
+// Money is an amount in the minor unit of its currency, e.g. cents for EUR
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"` // ISO 4217 code, upper case
+}
+
+// NewMoney creates an amount of minor units in currency
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// String returns the amount in minor units with its currency, e.g. "5000 EUR";
+// use i18n.Locale.FormatMoney for display
+func (m Money) String() string {
+	return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+}
+
+// ParseCurrency validates an ISO 4217 currency code and returns it in
+// upper case; an empty value gives DefaultCurrency
+func ParseCurrency(value string) (string, error) {
+	if value == "" {
+		return DefaultCurrency, nil
+	}
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("invalid currency code %q", value)
+	}
+	return code, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Outbox email kinds
+const (
+	EmailKindBookingConfirmation = "booking_confirmation"
+	EmailKindBookingReminder     = "booking_reminder"
+	EmailKindBookingCancellation = "booking_cancellation"
+
+	// Sent to the salon staff address rather than the client
+	EmailKindStaffNewBooking   = "staff_new_booking"
+	EmailKindStaffCancellation = "staff_cancellation"
+)
+
+// Outbox delivery channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// Outbox email statuses
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"    // gave up after the maximum number of attempts
+	OutboxStatusSkipped = "skipped" // no longer relevant, e.g. the booking was cancelled
+)
+
+// OutboxEmail is a notification queued for delivery by the outbox worker.
+// Despite the name it also carries SMS messages, distinguished by Channel.
+type OutboxEmail struct {
+	ID            int        `json:"id" db:"id"`
+	Channel       string     `json:"channel" db:"channel"`
+	Kind          string     `json:"kind" db:"kind"`
+	BookingID     int        `json:"booking_id" db:"booking_id"`
+	Recipient     string     `json:"recipient" db:"recipient"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// EmailTestRequest asks for a rendered email to be sent to an arbitrary address
+type EmailTestRequest struct {
+	To        string `json:"to"`
+	BookingID int    `json:"booking_id,omitempty"` // sample data when omitted
+	Locale    string `json:"locale,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// SessionPackage is a prepaid bundle of sessions of one service, such as
+// "5 x Swedish Massage", tied to the client's email. Bookings of the service
+// made with that email and the package's secret code use a session instead
+// of being charged.
+type SessionPackage struct {
+	ID          int       `json:"id" db:"id"`
+	Code        string    `json:"code" db:"code"`
+	Email       string    `json:"email,omitempty" db:"email"`
+	ClientName  string    `json:"client_name,omitempty" db:"client_name"`
+	ServiceID   int       `json:"service_id" db:"service_id"`
+	ServiceName string    `json:"service_name" db:"service_name"`
+	Sessions    int       `json:"sessions" db:"sessions"`
+	Remaining   int       `json:"remaining" db:"remaining"`
+	ExpiresOn   string    `json:"expires_on,omitempty" db:"expires_on"` // last business date it can be used, empty if it never expires
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// PackageRequest asks for a session package to be created
+type PackageRequest struct {
+	Email      string `json:"email"`
+	ClientName string `json:"client_name"`
+	ServiceID  int    `json:"service_id"`
+	Sessions   int    `json:"sessions"`
+	ExpiresOn  string `json:"expires_on"` // YYYY-MM-DD, optional
+}
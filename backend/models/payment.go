@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Payment statuses
+const (
+	PaymentStatusPending = "pending"
+	PaymentStatusPaid    = "paid"
+	PaymentStatusFailed  = "failed"
+	PaymentStatusExpired = "expired" // the client did not pay before the hold ran out
+)
+
+// Payment is an online checkout for a booking
+type Payment struct {
+	ID         int       `json:"id" db:"id"`
+	BookingID  int       `json:"booking_id" db:"booking_id"`
+	Provider   string    `json:"provider" db:"provider"`
+	CheckoutID string    `json:"checkout_id" db:"checkout_id"`
+	PaymentRef string    `json:"payment_ref,omitempty" db:"payment_ref"` // provider reference of the captured payment
+	Amount     int64     `json:"amount" db:"amount"`                     // in minor units
+	Currency   string    `json:"currency" db:"currency"`
+	Status     string    `json:"status" db:"status"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// PricingRule raises or lowers the price of slots by a percentage depending
+// on when they start and how soon. Unset conditions match every slot.
+type PricingRule struct {
+	ID           int       `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Days         []int     `json:"days" db:"days"`                     // weekdays, 0 for Sunday; empty for every day
+	StartTime    string    `json:"start_time" db:"start_time"`         // HH:MM, slots starting at or after
+	EndTime      string    `json:"end_time" db:"end_time"`             // HH:MM, slots starting before
+	MinLeadHours int       `json:"min_lead_hours" db:"min_lead_hours"` // slots starting at least this far ahead
+	MaxLeadHours int       `json:"max_lead_hours" db:"max_lead_hours"` // slots starting within this many hours
+	ServiceIDs   []int     `json:"service_ids" db:"-"`                 // empty for all services
+	Percent      int       `json:"percent" db:"percent"`               // e.g. 10 for +10%, -15 for -15%
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Applies reports whether the rule adjusts a slot of the service starting
+// at start, given in the business timezone, when lead is the time until it starts
+func (r *PricingRule) Applies(serviceID int, start time.Time, lead time.Duration) bool {
+	if len(r.ServiceIDs) > 0 && !slices.Contains(r.ServiceIDs, serviceID) {
+		return false
+	}
+	if len(r.Days) > 0 && !slices.Contains(r.Days, int(start.Weekday())) {
+		return false
+	}
+	clock := start.Format("15:04")
+	if (r.StartTime != "" && clock < r.StartTime) || (r.EndTime != "" && clock >= r.EndTime) {
+		return false
+	}
+	if r.MinLeadHours > 0 && lead < time.Duration(r.MinLeadHours)*time.Hour {
+		return false
+	}
+	if r.MaxLeadHours > 0 && lead >= time.Duration(r.MaxLeadHours)*time.Hour {
+		return false
+	}
+	return true
+}
+
+// SlotPrice applies every matching rule to the base price. The percentages
+// of matching rules are added up, the price never goes below zero, and the
+// result is rounded half up to a whole minor unit. It returns the price and
+// the total adjustment in percent.
+func SlotPrice(base Money, rules []PricingRule, serviceID int, start time.Time, lead time.Duration) (Money, int) {
+	adjustment := 0
+	for i := range rules {
+		if rules[i].Applies(serviceID, start, lead) {
+			adjustment += rules[i].Percent
+		}
+	}
+	if adjustment < -100 {
+		adjustment = -100
+	}
+	return NewMoney((base.Amount*int64(100+adjustment)+50)/100, base.Currency), adjustment
+}
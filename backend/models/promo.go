@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Promo code kinds
+const (
+	PromoKindPercent = "percent" // Value is a percentage of the price
+	PromoKindFixed   = "fixed"   // Value is an amount in minor units of Currency
+)
+
+// PromoCode is a discount clients can enter when booking
+type PromoCode struct {
+	ID              int        `json:"id" db:"id"`
+	Code            string     `json:"code" db:"code"`
+	Kind            string     `json:"kind" db:"kind"`
+	Value           int64      `json:"value" db:"value"`
+	Currency        string     `json:"currency,omitempty" db:"currency"` // fixed discounts only
+	ValidFrom       *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ValidUntil      *time.Time `json:"valid_until,omitempty" db:"valid_until"`
+	MaxUses         int        `json:"max_uses" db:"max_uses"`                     // 0 for unlimited
+	MaxUsesPerEmail int        `json:"max_uses_per_email" db:"max_uses_per_email"` // 0 for unlimited
+	ServiceIDs      []int      `json:"service_ids" db:"-"`                         // empty for all services
+	Active          bool       `json:"active" db:"active"`
+	Uses            int        `json:"uses" db:"-"` // bookings using the code that are not cancelled
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Discount returns the reduction the code gives on price, never more than
+// the price itself. Percentages are rounded half up to a whole minor unit.
+func (p *PromoCode) Discount(price Money) Money {
+	amount := p.Value
+	if p.Kind == PromoKindPercent {
+		amount = (price.Amount*p.Value + 50) / 100
+	}
+	if amount > price.Amount {
+		amount = price.Amount
+	}
+	return NewMoney(amount, price.Currency)
+}
+
+// QuoteRequest asks for the price of a service with an optional promo code
+// and gift voucher
+type QuoteRequest struct {
+	ServiceID     int    `json:"service_id"`
+	ReservationID int    `json:"reservation_id"` // quotes the price locked into the reservation when given
+	PromoCode     string `json:"promo_code"`
+	VoucherCode   string `json:"voucher_code"`
+	PackageCode   string `json:"package_code"` // needs the email the package was sold to
+	Email         string `json:"email"`        // checked against per-client limits when given
+	Locale        string `json:"locale"`       // language for error messages
+}
+
+// Quote is the price a booking would have
+type Quote struct {
+	ServiceID     int    `json:"service_id"`
+	Price         Money  `json:"price"`
+	PromoCode     string `json:"promo_code,omitempty"`
+	Discount      Money  `json:"discount"`
+	VoucherCode   string `json:"voucher_code,omitempty"`
+	VoucherAmount Money  `json:"voucher_amount"`       // paid from the gift voucher
+	PackageID     int    `json:"package_id,omitempty"` // session package that would pay instead
+	Total         Money  `json:"total"`                // left to pay
+
+	// Tax on the price less discount
+	TaxAmounts
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Refund statuses
+const (
+	RefundStatusPending   = "pending" // sent to the provider, the money has not gone back yet
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// RefundProviderManual marks refunds paid back outside the system, e.g. in cash or by bank transfer
+const RefundProviderManual = "manual"
+
+// Refund is an entry in the refunds ledger: money returned to a client for
+// a booking, either through the payment provider or by hand
+type Refund struct {
+	ID           int       `json:"id" db:"id"`
+	BookingID    int       `json:"booking_id" db:"booking_id"`
+	Reference    string    `json:"reference" db:"reference"`               // booking reference
+	PaymentID    int       `json:"payment_id,omitempty" db:"payment_id"`   // online payment refunded, 0 for manual refunds
+	PaymentRef   string    `json:"payment_ref,omitempty" db:"payment_ref"` // provider reference of that payment
+	Amount       Money     `json:"amount" db:"amount_cents"`
+	Reason       string    `json:"reason" db:"reason"`
+	Provider     string    `json:"provider" db:"provider"`                   // payment provider name or RefundProviderManual
+	ProviderRef  string    `json:"provider_ref,omitempty" db:"provider_ref"` // provider refund ID, or a note such as a transfer number
+	Status       string    `json:"status" db:"status"`
+	Error        string    `json:"error,omitempty" db:"error"`     // why the provider refused it
+	Cancellation bool      `json:"cancellation" db:"cancellation"` // made because the booking was cancelled
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RefundRequest asks for part or all of what was paid for a booking to be
+// returned. Without Manual the money goes back through the payment provider.
+type RefundRequest struct {
+	BookingID    int    `json:"booking_id"`
+	Amount       int64  `json:"amount"` // in minor units
+	Reason       string `json:"reason"`
+	Manual       bool   `json:"manual"`       // already paid back outside the system
+	ProviderRef  string `json:"provider_ref"` // for manual refunds, e.g. a bank transfer number
+	Cancellation bool   `json:"cancellation"` // refunds the booking's cancellation, which it must have
+}
@@ -4,10 +4,11 @@ import "time"
 
 // Reservation represents a temporary slot reservation
 type Reservation struct {
-	ID        int       `json:"id" db:"id"`
-	SlotID    int       `json:"slot_id" db:"slot_id"`
+	ID         int       `json:"id" db:"id"`
+	SlotID     int       `json:"slot_id" db:"slot_id"`
 	ReservedAt time.Time `json:"reserved_at" db:"reserved_at"`
 	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Price      Money     `json:"price" db:"price_cents"` // locked when the slot is reserved
 }
 
 // ReservationRequest represents the request to create a reservation
@@ -17,7 +18,8 @@ type ReservationRequest struct {
 
 // ReservationResponse represents the response when creating a reservation
 type ReservationResponse struct {
-	ReservationID    int   `json:"reservation_id"`
+	ReservationID    int       `json:"reservation_id"`
 	ExpiresAt        time.Time `json:"expires_at"`
-	ExpiresInSeconds int   `json:"expires_in_seconds"`
+	ExpiresInSeconds int       `json:"expires_in_seconds"`
+	Price            Money     `json:"price"` // slot price, kept until the reservation expires
 }
@@ -1,10 +1,18 @@
 package models
 
+import "time"
+
 // TimeSlot represents an available time slot for booking
 type TimeSlot struct {
-	ID        int    `json:"id" db:"id"`
-	Date      string `json:"date" db:"date"`           // YYYY-MM-DD
-	Time      string `json:"time" db:"time"`           // HH:MM
-	ServiceID int    `json:"service_id" db:"service_id"`
-	Available bool   `json:"available" db:"available"`
+	ID        int       `json:"id" db:"id"`
+	Date      string    `json:"date" db:"date"`           // YYYY-MM-DD
+	Time      string    `json:"time" db:"time"`           // HH:MM
+	StartsAt  time.Time `json:"starts_at" db:"starts_at"` // UTC instant of Date and Time in the business timezone
+	ServiceID int       `json:"service_id" db:"service_id"`
+	Available bool      `json:"available" db:"available"`
+
+	// Price is the service price with the pricing rules for this slot
+	// applied; Adjustment is their total in percent
+	Price      Money `json:"price" db:"-"`
+	Adjustment int   `json:"adjustment" db:"-"`
 }
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TaxRate is a VAT or sales tax rate in hundredths of a percent, so 2400
+// is 24% and 2550 is 25.5%. In JSON it is the percentage as a number.
+type TaxRate int
+
+// MaxTaxRate is the highest rate ParseTaxRate accepts, 100%
+const MaxTaxRate TaxRate = 10000
+
+// taxRatePattern matches a whole number of percent with up to two decimals
+var taxRatePattern = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]{1,2}))?$`)
+
+// ParseTaxRate parses a percentage with up to two decimals, e.g. "24",
+// "25.5" or "9%". An empty value is a rate of zero.
+func ParseTaxRate(value string) (TaxRate, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "%")
+	if value == "" {
+		return 0, nil
+	}
+	match := taxRatePattern.FindStringSubmatch(value)
+	if match == nil {
+		if _, fraction, _ := strings.Cut(value, "."); len(fraction) > 2 {
+			return 0, fmt.Errorf("invalid tax rate %q: at most two decimals", value)
+		}
+		return 0, fmt.Errorf("invalid tax rate %q", value)
+	}
+	whole, fraction := match[1], match[2]
+	// More than three digits is out of range, and too long for Atoi
+	if len(strings.TrimLeft(whole, "0")) > 3 {
+		return 0, fmt.Errorf("invalid tax rate %q: must be from 0 to 100", value)
+	}
+	percent, _ := strconv.Atoi(whole)
+	hundredths := 0
+	if fraction != "" {
+		hundredths, _ = strconv.Atoi(fraction + strings.Repeat("0", 2-len(fraction)))
+	}
+	rate := TaxRate(percent*100 + hundredths)
+	if rate > MaxTaxRate {
+		return 0, fmt.Errorf("invalid tax rate %q: must be from 0 to 100", value)
+	}
+	return rate, nil
+}
+
+// String returns the rate as a percentage without trailing zeros, e.g. "24%" or "25.5%"
+func (r TaxRate) String() string {
+	return r.number() + "%"
+}
+
+// number formats the rate as a decimal number of percent
+func (r TaxRate) number() string {
+	s := fmt.Sprintf("%d.%02d", r/100, r%100)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON encodes the rate as a number of percent
+func (r TaxRate) MarshalJSON() ([]byte, error) {
+	return []byte(r.number()), nil
+}
+
+// UnmarshalJSON decodes a number of percent
+func (r *TaxRate) UnmarshalJSON(data []byte) error {
+	rate, err := ParseTaxRate(string(data))
+	if err != nil {
+		return err
+	}
+	*r = rate
+	return nil
+}
+
+// IncludedTax returns the tax contained in a gross amount, rounded half up
+// to a whole minor unit
+func (r TaxRate) IncludedTax(gross int64) int64 {
+	return roundDiv(gross*int64(r), 10000+int64(r))
+}
+
+// ExcludedTax returns the tax to add to a net amount, rounded half up to a
+// whole minor unit
+func (r TaxRate) ExcludedTax(net int64) int64 {
+	return roundDiv(net*int64(r), 10000)
+}
+
+// TaxAmounts splits what is charged for a booking into net and tax at one rate
+type TaxAmounts struct {
+	TaxRate      TaxRate `json:"tax_rate"`
+	TaxInclusive bool    `json:"tax_inclusive"` // the price includes the tax rather than having it added
+	Net          Money   `json:"net"`
+	Tax          Money   `json:"tax"`
+	Gross        Money   `json:"gross"` // net plus tax, what the client is charged
+}
+
+// ApplyTax works out the tax on an amount priced at rate. With inclusive
+// pricing the amount is the gross and the tax is contained in it; otherwise
+// it is the net and the tax is added on top.
+func ApplyTax(amount Money, rate TaxRate, inclusive bool) TaxAmounts {
+	t := TaxAmounts{TaxRate: rate, TaxInclusive: inclusive}
+	if inclusive {
+		tax := rate.IncludedTax(amount.Amount)
+		t.Net = NewMoney(amount.Amount-tax, amount.Currency)
+		t.Tax = NewMoney(tax, amount.Currency)
+		t.Gross = amount
+	} else {
+		tax := rate.ExcludedTax(amount.Amount)
+		t.Net = amount
+		t.Tax = NewMoney(tax, amount.Currency)
+		t.Gross = NewMoney(amount.Amount+tax, amount.Currency)
+	}
+	return t
+}
+
+// roundDiv divides rounding half away from zero
+func roundDiv(a, b int64) int64 {
+	if a < 0 {
+		return -roundDiv(-a, b)
+	}
+	return (2*a + b) / (2 * b)
+}
@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestParseTaxRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  TaxRate
+	}{
+		{"", 0},
+		{"0", 0},
+		{"24", 2400},
+		{"25.5", 2550},
+		{"9.05", 905},
+		{" 9% ", 900},
+		{"007", 700},
+		{"100", 10000},
+		{"100.00", 10000},
+	}
+	for _, tt := range tests {
+		got, err := ParseTaxRate(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTaxRate(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseTaxRateRejectsInvalid(t *testing.T) {
+	for _, value := range []string{
+		"1.+5",
+		"1.-5",
+		"+5",
+		"-5",
+		"1.",
+		".5",
+		"1.5.5",
+		"24.555",
+		"1e2",
+		"2 4",
+		"24%%",
+		"abc",
+		"100.01",
+		"101",
+		"99999999999999999999",
+	} {
+		if got, err := ParseTaxRate(value); err == nil {
+			t.Errorf("ParseTaxRate(%q) = %d, want an error", value, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// GiftVoucher is a prepaid voucher clients can give as a present. A value
+// voucher can be spent over several bookings until its balance runs out; a
+// service voucher pays for one booking of its service in full.
+type GiftVoucher struct {
+	ID             int       `json:"id" db:"id"`
+	Code           string    `json:"code" db:"code"`
+	ServiceID      int       `json:"service_id,omitempty" db:"service_id"` // 0 for value vouchers
+	ServiceName    string    `json:"service_name,omitempty" db:"service_name"`
+	Value          Money     `json:"value" db:"amount_cents"` // service price at issue for service vouchers
+	Balance        Money     `json:"balance" db:"balance_cents"`
+	PurchaserName  string    `json:"purchaser_name,omitempty" db:"purchaser_name"`
+	RecipientName  string    `json:"recipient_name,omitempty" db:"recipient_name"`
+	RecipientEmail string    `json:"recipient_email,omitempty" db:"recipient_email"`
+	Message        string    `json:"message,omitempty" db:"message"`
+	Locale         string    `json:"locale,omitempty" db:"locale"`         // language of the certificate
+	ExpiresOn      string    `json:"expires_on,omitempty" db:"expires_on"` // last business date it can be used, empty if it never expires
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// Covers returns how much of due the voucher pays: all of it for a service
+// voucher, otherwise as much as the balance allows
+func (v *GiftVoucher) Covers(due Money) Money {
+	amount := due.Amount
+	if v.ServiceID == 0 && v.Balance.Amount < amount {
+		amount = v.Balance.Amount
+	}
+	return NewMoney(amount, due.Currency)
+}
+
+// VoucherRequest asks for a gift voucher to be issued. Exactly one of
+// Amount and ServiceID is set.
+type VoucherRequest struct {
+	Amount         int64  `json:"amount"`   // value in minor units
+	Currency       string `json:"currency"` // defaults to the salon currency
+	ServiceID      int    `json:"service_id"`
+	PurchaserName  string `json:"purchaser_name"`
+	RecipientName  string `json:"recipient_name"`
+	RecipientEmail string `json:"recipient_email"` // emailed the certificate when set
+	Message        string `json:"message"`
+	Locale         string `json:"locale"`
+	ExpiresOn      string `json:"expires_on"` // YYYY-MM-DD, optional
+}
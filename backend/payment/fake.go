@@ -0,0 +1,124 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// FakeProvider creates checkouts locally without taking real payments;
+// intended for tests and development. Its checkout URL leads straight to
+// the success page, and payments are completed by posting an unsigned
+// webhook such as {"type": "paid", "checkout_id": "fake_1"}. Refunds
+// succeed at once without moving any money, unless SetRefundStatus says
+// otherwise, and a later change is reported the same way:
+// {"type": "refund", "refund_id": "fake_re_1", "status": "succeeded"}.
+type FakeProvider struct {
+	mu           sync.Mutex
+	publicURL    string
+	checkouts    []CheckoutRequest
+	refunds      []RefundRequest
+	err          error
+	refundErr    error
+	refundStatus string
+}
+
+// NewFakeProvider creates a fake provider
+func NewFakeProvider(publicURL string) *FakeProvider {
+	return &FakeProvider{publicURL: publicURL}
+}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+// CreateCheckout records the request and returns a checkout for it
+func (p *FakeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.checkouts = append(p.checkouts, req)
+	return &Checkout{ID: fmt.Sprintf("fake_%d", len(p.checkouts)), URL: req.SuccessURL}, nil
+}
+
+// ParseWebhook decodes an unsigned JSON event
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	var payload struct {
+		Type       string `json:"type"`
+		CheckoutID string `json:"checkout_id"`
+		PaymentID  string `json:"payment_id"`
+		RefundID   string `json:"refund_id"`
+		Status     string `json:"status"`
+		Failure    string `json:"failure"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %v", err)
+	}
+	switch payload.Type {
+	case EventPaid, EventFailed, EventExpired:
+	case EventRefund:
+		return &Event{Type: EventRefund, RefundID: payload.RefundID, RefundStatus: payload.Status, RefundFailure: payload.Failure}, nil
+	default:
+		return nil, nil
+	}
+	return &Event{Type: payload.Type, CheckoutID: payload.CheckoutID, PaymentID: payload.PaymentID}, nil
+}
+
+// FailWith makes subsequent checkouts fail with err; nil restores success
+func (p *FakeProvider) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Checkouts returns the recorded checkout requests in creation order
+func (p *FakeProvider) Checkouts() []CheckoutRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CheckoutRequest(nil), p.checkouts...)
+}
+
+// Refund records the request and reports the refund as done straight away,
+// or in the status set with SetRefundStatus
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.refundErr != nil {
+		return nil, p.refundErr
+	}
+	p.refunds = append(p.refunds, req)
+	status := RefundSucceeded
+	if p.refundStatus != "" {
+		status = p.refundStatus
+	}
+	return &Refund{ID: fmt.Sprintf("fake_re_%d", len(p.refunds)), Status: status}, nil
+}
+
+// SetRefundStatus makes subsequent refunds report status, e.g. RefundPending;
+// an empty status restores RefundSucceeded
+func (p *FakeProvider) SetRefundStatus(status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refundStatus = status
+}
+
+// FailRefundsWith makes subsequent refunds fail with err; nil restores success
+func (p *FakeProvider) FailRefundsWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refundErr = err
+}
+
+// Refunds returns the recorded refund requests in order
+func (p *FakeProvider) Refunds() []RefundRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]RefundRequest(nil), p.refunds...)
+}
@@ -0,0 +1,170 @@
+// Package payment collects online payment for bookings through a pluggable provider
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider names accepted in PAYMENT_PROVIDER
+const (
+	ProviderNone   = "none"
+	ProviderFake   = "fake"
+	ProviderStripe = "stripe"
+)
+
+// Event types reported by providers through webhooks
+const (
+	EventPaid    = "paid"
+	EventFailed  = "failed"
+	EventExpired = "expired"
+	EventRefund  = "refund" // a refund the provider accepted has changed status
+)
+
+// ErrInvalidSignature is returned when a webhook does not come from the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// CheckoutRequest describes the payment collected for one booking
+type CheckoutRequest struct {
+	BookingID   int
+	Reference   string
+	Description string
+	Email       string
+	Amount      int64  // in minor units, e.g. cents
+	Currency    string // ISO 4217 code, upper case
+	SuccessURL  string
+	CancelURL   string
+	ExpiresAt   time.Time
+}
+
+// Checkout is a hosted payment page created by the provider
+type Checkout struct {
+	ID  string
+	URL string
+}
+
+// Event is a payment outcome reported by the provider
+type Event struct {
+	Type       string // EventPaid, EventFailed, EventExpired or EventRefund
+	CheckoutID string
+	PaymentID  string // provider reference of the captured payment, if any
+
+	// For EventRefund, the provider's refund ID, its new status and, for
+	// failed refunds, why
+	RefundID      string
+	RefundStatus  string // RefundPending, RefundSucceeded or RefundFailed
+	RefundFailure string
+}
+
+// Provider creates checkouts and interprets the provider's webhooks
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+
+	// ParseWebhook verifies and decodes a webhook request body. It returns
+	// a nil event for notifications that do not affect a booking.
+	ParseWebhook(header http.Header, body []byte) (*Event, error)
+}
+
+// Refund statuses reported by providers
+const (
+	RefundPending   = "pending" // accepted, the money has not gone back yet
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// RefundRequest describes money returned from a captured payment
+type RefundRequest struct {
+	RefundID  int    // local ledger ID, which makes retries safe
+	Reference string // booking reference
+	PaymentID string // provider reference of the captured payment
+	Amount    int64  // in minor units, at most what was captured
+	Currency  string
+	Reason    string
+}
+
+// Refund is a refund accepted by the provider
+type Refund struct {
+	ID     string
+	Status string // RefundPending, RefundSucceeded or RefundFailed
+}
+
+// Refunder returns money from captured payments through the provider
+type Refunder interface {
+	Refund(ctx context.Context, req RefundRequest) (*Refund, error)
+}
+
+// Config holds payment configuration
+type Config struct {
+	Provider            string // none, fake or stripe
+	StripeSecretKey     string
+	StripeWebhookSecret string
+	StripeAPIURL        string        // base URL of the Stripe-compatible API
+	DepositPercent      int           // share of the price collected online, 1-100
+	Timeout             time.Duration // how long a slot is held while the client pays
+	PublicURL           string        // base URL the client returns to after checkout
+}
+
+// GetConfig loads payment configuration from environment variables.
+// Online payment is disabled unless PAYMENT_PROVIDER is set.
+func GetConfig() (*Config, error) {
+	config := &Config{
+		Provider:            getEnvOrDefault("PAYMENT_PROVIDER", ProviderNone),
+		StripeSecretKey:     getEnvOrDefault("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret: getEnvOrDefault("STRIPE_WEBHOOK_SECRET", ""),
+		StripeAPIURL:        getEnvOrDefault("STRIPE_API_URL", "https://api.stripe.com"),
+		PublicURL:           strings.TrimRight(getEnvOrDefault("PUBLIC_URL", "http://localhost:8080"), "/"),
+	}
+
+	percent, err := strconv.Atoi(getEnvOrDefault("PAYMENT_DEPOSIT_PERCENT", "100"))
+	if err != nil || percent < 1 || percent > 100 {
+		return nil, fmt.Errorf("PAYMENT_DEPOSIT_PERCENT must be a whole number from 1 to 100")
+	}
+	config.DepositPercent = percent
+
+	timeout, err := time.ParseDuration(getEnvOrDefault("PAYMENT_TIMEOUT", "30m"))
+	if err != nil || timeout < time.Minute {
+		return nil, fmt.Errorf("PAYMENT_TIMEOUT must be a duration of at least 1m")
+	}
+	config.Timeout = timeout
+
+	return config, nil
+}
+
+// NewProvider creates the provider selected by config.Provider, or nil when
+// online payment is disabled
+func NewProvider(config *Config) (Provider, error) {
+	switch config.Provider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderFake:
+		return NewFakeProvider(config.PublicURL), nil
+	case ProviderStripe:
+		if config.StripeSecretKey == "" || config.StripeWebhookSecret == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET are required for the stripe provider")
+		}
+		return NewStripeProvider(config.StripeAPIURL, config.StripeSecretKey, config.StripeWebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", config.Provider)
+	}
+}
+
+// DepositAmount returns the share of a price in minor units collected
+// online, rounded half up to a whole minor unit
+func (c *Config) DepositAmount(price int64) int64 {
+	return (price*int64(c.DepositPercent) + 50) / 100
+}
+
+// getEnvOrDefault gets environment variable or returns default value
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
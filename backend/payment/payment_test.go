@@ -0,0 +1,21 @@
+package payment
+
+import "testing"
+
+func TestDepositAmount(t *testing.T) {
+	for _, tt := range []struct {
+		percent int
+		price   int64
+		want    int64
+	}{
+		{100, 5000, 5000},
+		{30, 5000, 1500},
+		{33, 4550, 1502}, // 1501.5 rounds up
+		{1, 49, 0},
+	} {
+		config := Config{DepositPercent: tt.percent}
+		if got := config.DepositAmount(tt.price); got != tt.want {
+			t.Errorf("%d%% of %d = %d, want %d", tt.percent, tt.price, got, tt.want)
+		}
+	}
+}
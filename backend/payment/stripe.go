@@ -0,0 +1,239 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stripeMinExpiry is the shortest checkout lifetime the Stripe API accepts
+const stripeMinExpiry = 30 * time.Minute
+
+// stripeSignatureTolerance bounds the age of an accepted webhook signature
+const stripeSignatureTolerance = 5 * time.Minute
+
+// StripeProvider uses Checkout Sessions of the Stripe API, or any service
+// implementing the same endpoints and webhook signatures
+type StripeProvider struct {
+	baseURL       string
+	secretKey     string
+	webhookSecret string
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewStripeProvider creates a provider for the API at baseURL
+func NewStripeProvider(baseURL, secretKey, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+		now:           time.Now,
+	}
+}
+
+// Name returns the provider name
+func (p *StripeProvider) Name() string {
+	return ProviderStripe
+}
+
+// stripeSession is the part of a Checkout Session this package reads
+type stripeSession struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	PaymentStatus string `json:"payment_status"`
+	PaymentIntent string `json:"payment_intent"`
+}
+
+// CreateCheckout creates a Checkout Session for the booking
+func (p *StripeProvider) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("client_reference_id", req.Reference)
+	form.Set("metadata[booking_id]", strconv.Itoa(req.BookingID))
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(req.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(req.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+	if req.Email != "" {
+		form.Set("customer_email", req.Email)
+	}
+	// Shorter holds are enforced by the booking store instead
+	if !req.ExpiresAt.IsZero() && req.ExpiresAt.Sub(p.now()) >= stripeMinExpiry {
+		form.Set("expires_at", strconv.FormatInt(req.ExpiresAt.Unix(), 10))
+	}
+
+	var session stripeSession
+	if err := p.post(ctx, "/v1/checkout/sessions", form, "checkout-"+req.Reference, &session); err != nil {
+		return nil, err
+	}
+	if session.ID == "" || session.URL == "" {
+		return nil, fmt.Errorf("payment provider response has no checkout id or url")
+	}
+	return &Checkout{ID: session.ID, URL: session.URL}, nil
+}
+
+// Refund refunds part or all of a captured PaymentIntent
+func (p *StripeProvider) Refund(ctx context.Context, req RefundRequest) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", req.PaymentID)
+	form.Set("amount", strconv.FormatInt(req.Amount, 10))
+	form.Set("metadata[booking_reference]", req.Reference)
+	form.Set("metadata[reason]", req.Reason)
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := p.post(ctx, "/v1/refunds", form, "refund-"+strconv.Itoa(req.RefundID), &refund); err != nil {
+		return nil, err
+	}
+	if refund.ID == "" {
+		return nil, fmt.Errorf("payment provider response has no refund id")
+	}
+
+	return &Refund{ID: refund.ID, Status: refundStatus(refund.Status)}, nil
+}
+
+// refundStatus maps a Stripe refund status to a Refund status. Refunds
+// needing action by the customer are still under way.
+func refundStatus(status string) string {
+	switch status {
+	case "succeeded":
+		return RefundSucceeded
+	case "failed", "canceled":
+		return RefundFailed
+	}
+	return RefundPending
+}
+
+// post sends a form to an API endpoint and decodes the JSON response into v.
+// The idempotency key makes a retried request return the first result.
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, v any) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Authorization", "Bearer "+p.secretKey)
+	httpReq.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call payment provider: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read payment provider response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("payment provider returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid payment provider response: %v", err)
+	}
+	return nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header and maps checkout
+// session events to payment outcomes and refund events to refund updates
+func (p *StripeProvider) ParseWebhook(header http.Header, body []byte) (*Event, error) {
+	if err := p.verifySignature(header.Get("Stripe-Signature"), body); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Type string `json:"type"`
+		Data struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %v", err)
+	}
+
+	switch payload.Type {
+	case "refund.updated", "refund.failed", "charge.refund.updated":
+		var refund struct {
+			ID            string `json:"id"`
+			Status        string `json:"status"`
+			FailureReason string `json:"failure_reason"`
+		}
+		if err := json.Unmarshal(payload.Data.Object, &refund); err != nil {
+			return nil, fmt.Errorf("invalid webhook body: %v", err)
+		}
+		return &Event{Type: EventRefund, RefundID: refund.ID, RefundStatus: refundStatus(refund.Status), RefundFailure: refund.FailureReason}, nil
+	}
+
+	var session stripeSession
+	if err := json.Unmarshal(payload.Data.Object, &session); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %v", err)
+	}
+	event := &Event{CheckoutID: session.ID, PaymentID: session.PaymentIntent}
+	switch payload.Type {
+	case "checkout.session.completed":
+		// Delayed payment methods complete the session before the money arrives
+		if session.PaymentStatus != "paid" {
+			return nil, nil
+		}
+		event.Type = EventPaid
+	case "checkout.session.async_payment_succeeded":
+		event.Type = EventPaid
+	case "checkout.session.async_payment_failed":
+		event.Type = EventFailed
+	case "checkout.session.expired":
+		event.Type = EventExpired
+	default:
+		return nil, nil
+	}
+	return event, nil
+}
+
+// verifySignature checks a header of the form t=<unix>,v1=<hex hmac>
+func (p *StripeProvider) verifySignature(header string, body []byte) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := p.now().Sub(time.Unix(seconds, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(p.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
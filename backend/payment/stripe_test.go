@@ -0,0 +1,217 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func sign(secret string, timestamp int64, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", timestamp, body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestStripeCreateCheckout(t *testing.T) {
+	var form map[string]string
+	var auth, idempotency string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/checkout/sessions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		idempotency = r.Header.Get("Idempotency-Key")
+		r.ParseForm()
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		fmt.Fprint(w, `{"id": "cs_test_1", "url": "https://checkout.example.com/cs_test_1"}`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	provider := NewStripeProvider(server.URL+"/", "sk_test", "whsec_test")
+	provider.now = func() time.Time { return now }
+
+	checkout, err := provider.CreateCheckout(context.Background(), CheckoutRequest{
+		BookingID:   7,
+		Reference:   "MB-ABC123",
+		Description: "Classic massage",
+		Email:       "jane@example.com",
+		Amount:      4550,
+		Currency:    "EUR",
+		SuccessURL:  "http://localhost/ok",
+		CancelURL:   "http://localhost/cancel",
+		ExpiresAt:   now.Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkout.ID != "cs_test_1" || checkout.URL != "https://checkout.example.com/cs_test_1" {
+		t.Errorf("unexpected checkout %+v", checkout)
+	}
+	if auth != "Bearer sk_test" || idempotency != "checkout-MB-ABC123" {
+		t.Errorf("unexpected headers %q, %q", auth, idempotency)
+	}
+	want := map[string]string{
+		"mode":                                          "payment",
+		"client_reference_id":                           "MB-ABC123",
+		"metadata[booking_id]":                          "7",
+		"line_items[0][price_data][currency]":           "eur",
+		"line_items[0][price_data][unit_amount]":        "4550",
+		"line_items[0][price_data][product_data][name]": "Classic massage",
+		"customer_email":                                "jane@example.com",
+	}
+	for key, value := range want {
+		if form[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, form[key])
+		}
+	}
+	// Holds shorter than the API minimum are not passed on
+	if _, ok := form["expires_at"]; ok {
+		t.Error("expires_at should be omitted for short holds")
+	}
+}
+
+func TestStripeCreateCheckoutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "Invalid API Key"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	provider := NewStripeProvider(server.URL, "sk_bad", "whsec_test")
+	if _, err := provider.CreateCheckout(context.Background(), CheckoutRequest{Reference: "MB-1"}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
+
+func TestStripeRefund(t *testing.T) {
+	var form map[string]string
+	var idempotency string
+	status := "succeeded"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/refunds" {
+			http.NotFound(w, r)
+			return
+		}
+		idempotency = r.Header.Get("Idempotency-Key")
+		r.ParseForm()
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		fmt.Fprintf(w, `{"id": "re_1", "status": %q}`, status)
+	}))
+	defer server.Close()
+
+	provider := NewStripeProvider(server.URL, "sk_test", "whsec_test")
+	req := RefundRequest{RefundID: 3, Reference: "MB-ABC123", PaymentID: "pi_1", Amount: 2500, Currency: "EUR", Reason: "Therapist ill"}
+	refund, err := provider.Refund(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *refund != (Refund{ID: "re_1", Status: RefundSucceeded}) || idempotency != "refund-3" {
+		t.Errorf("unexpected refund %+v with idempotency key %q", refund, idempotency)
+	}
+	if form["payment_intent"] != "pi_1" || form["amount"] != "2500" || form["metadata[reason]"] != "Therapist ill" {
+		t.Errorf("unexpected form %v", form)
+	}
+
+	for stripeStatus, want := range map[string]string{"pending": RefundPending, "requires_action": RefundPending, "failed": RefundFailed} {
+		status = stripeStatus
+		if refund, err := provider.Refund(context.Background(), req); err != nil || refund.Status != want {
+			t.Errorf("%s: expected %s, got %+v (%v)", stripeStatus, want, refund, err)
+		}
+	}
+}
+
+func TestStripeParseWebhook(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	provider := NewStripeProvider("https://api.stripe.com", "sk_test", "whsec_test")
+	provider.now = func() time.Time { return now }
+
+	tests := []struct {
+		name string
+		body string
+		want *Event
+	}{
+		{
+			"paid",
+			`{"type": "checkout.session.completed", "data": {"object": {"id": "cs_1", "payment_status": "paid", "payment_intent": "pi_1"}}}`,
+			&Event{Type: EventPaid, CheckoutID: "cs_1", PaymentID: "pi_1"},
+		},
+		{
+			"completed but unpaid",
+			`{"type": "checkout.session.completed", "data": {"object": {"id": "cs_1", "payment_status": "unpaid"}}}`,
+			nil,
+		},
+		{
+			"async failure",
+			`{"type": "checkout.session.async_payment_failed", "data": {"object": {"id": "cs_1"}}}`,
+			&Event{Type: EventFailed, CheckoutID: "cs_1"},
+		},
+		{
+			"expired",
+			`{"type": "checkout.session.expired", "data": {"object": {"id": "cs_1"}}}`,
+			&Event{Type: EventExpired, CheckoutID: "cs_1"},
+		},
+		{
+			"refund succeeded",
+			`{"type": "refund.updated", "data": {"object": {"id": "re_1", "object": "refund", "status": "succeeded"}}}`,
+			&Event{Type: EventRefund, RefundID: "re_1", RefundStatus: RefundSucceeded},
+		},
+		{
+			"charge refund failed",
+			`{"type": "charge.refund.updated", "data": {"object": {"id": "re_1", "object": "refund", "status": "failed", "failure_reason": "expired_or_canceled_card"}}}`,
+			&Event{Type: EventRefund, RefundID: "re_1", RefundStatus: RefundFailed, RefundFailure: "expired_or_canceled_card"},
+		},
+		{
+			"unrelated",
+			`{"type": "customer.created", "data": {"object": {"id": "cus_1"}}}`,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Stripe-Signature", sign("whsec_test", now.Unix(), tt.body))
+			event, err := provider.ParseWebhook(header, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (event == nil) != (tt.want == nil) || (event != nil && *event != *tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, event)
+			}
+		})
+	}
+}
+
+func TestStripeRejectsBadSignatures(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	provider := NewStripeProvider("https://api.stripe.com", "sk_test", "whsec_test")
+	provider.now = func() time.Time { return now }
+	body := `{"type": "checkout.session.expired", "data": {"object": {"id": "cs_1"}}}`
+
+	signatures := map[string]string{
+		"missing":      "",
+		"wrong secret": sign("whsec_other", now.Unix(), body),
+		"stale":        sign("whsec_test", now.Add(-10*time.Minute).Unix(), body),
+		"tampered":     sign("whsec_test", now.Unix(), body+" "),
+	}
+	for name, signature := range signatures {
+		header := http.Header{}
+		header.Set("Stripe-Signature", signature)
+		if _, err := provider.ParseWebhook(header, []byte(body)); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
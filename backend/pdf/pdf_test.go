@@ -0,0 +1,62 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	doc := New("Arve (1)")
+	doc.Text(50, 60, 12, Bold, "Tasuda: 42,50 €")
+	doc.Line(50, 70, 545, 70, 0.5)
+	doc.AddPage()
+	doc.Text(50, 60, 10, Regular, `Привет (a\b)`)
+	got := doc.Bytes()
+
+	for _, want := range []string{
+		"%PDF-1.4\n",
+		"/Type /Pages /Kids [5 0 R 7 0 R] /Count 2",
+		"/BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding",
+		"BT /F2 12 Tf 50 781.89 Td (Tasuda: 42,50 \x80) Tj ET\n",
+		"0.5 w 50 771.89 m 545 771.89 l S\n",
+		`(?????? \(a\\b\)) Tj`,
+		"/Title (Arve \\(1\\))",
+		"%%EOF\n",
+	} {
+		if !bytes.Contains(got, []byte(want)) {
+			t.Errorf("PDF missing %q:\n%s", want, got)
+		}
+	}
+
+	// Every cross-reference entry must point at the start of its object
+	xref := bytes.LastIndex(got, []byte("\nxref\n"))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(got[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 objects, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(string(got[offset:]), want) {
+			t.Errorf("xref entry %d points at %q", i+1, got[offset:offset+10])
+		}
+	}
+	if !bytes.HasSuffix(got, []byte(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xref+1))) {
+		t.Error("startxref does not point at the cross-reference table")
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if got := Width("100,00", Regular, 10); got != 30.58 {
+		t.Errorf("expected 30.58, got %v", got)
+	}
+	if Width("Total", Bold, 10) <= Width("Total", Regular, 10) {
+		t.Error("bold text should be wider")
+	}
+	if !Encodable("Käibemaks õigus € – šokk") || Encodable("Итого") {
+		t.Error("unexpected Encodable result")
+	}
+}
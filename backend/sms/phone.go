@@ -0,0 +1,49 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizePhone converts a phone number to E.164 (e.g. +37251234567).
+// Spaces, dashes, dots and parentheses are ignored, as is a "(0)" trunk
+// prefix after the country code; a leading 00 is treated as +; numbers
+// without a country prefix get defaultCountryCode, after dropping a single
+// national trunk 0.
+func NormalizePhone(raw, defaultCountryCode string) (string, error) {
+	var digits strings.Builder
+	international := false
+	for i, r := range strings.Replace(strings.TrimSpace(raw), "(0)", "", 1) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			international = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("phone number contains %q", r)
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		if defaultCountryCode == "" {
+			return "", fmt.Errorf("phone number has no country code")
+		}
+		national := strings.TrimPrefix(number, "0")
+		if len(national) < 6 {
+			return "", fmt.Errorf("phone number is too short")
+		}
+		number = defaultCountryCode + national
+	}
+
+	// E.164 allows at most 15 digits; country code plus subscriber number is at least 8 in practice
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", fmt.Errorf("phone number must have 8 to 15 digits including the country code")
+	}
+	return "+" + number, nil
+}
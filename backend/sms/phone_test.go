@@ -0,0 +1,34 @@
+package sms
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"+372 5123 4567":      "+37251234567",
+		"5123 4567":           "+37251234567",
+		"00372-5123-4567":     "+37251234567",
+		"+44 (0)20 7946 0958": "+442079460958",
+		"+1 (415) 555-0100":   "+14155550100",
+		"07946 095 812":       "+3727946095812",
+		"12345":               "",
+		"+372 5123 abc":       "",
+		"+1234567890123456":   "",
+	}
+
+	for raw, want := range tests {
+		got, err := NormalizePhone(raw, "372")
+		if want == "" {
+			if err == nil {
+				t.Errorf("NormalizePhone(%q) = %q, expected an error", raw, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("NormalizePhone(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	if _, err := NormalizePhone("5123 4567", ""); err == nil {
+		t.Error("expected an error for a national number without a default country code")
+	}
+}
@@ -0,0 +1,193 @@
+// Package sms sends text message notifications through a pluggable provider
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Provider names accepted in SMS_PROVIDER
+const (
+	ProviderWebhook = "webhook"
+	ProviderLog     = "log"
+	ProviderMemory  = "memory"
+)
+
+// Provider delivers a text message to an E.164 phone number
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, to, body string) error
+}
+
+// Config holds SMS provider configuration
+type Config struct {
+	Provider           string // webhook, log or memory
+	WebhookURL         string
+	WebhookToken       string // optional bearer token sent to the webhook
+	From               string // sender ID or number passed to the webhook
+	DefaultCountryCode string // used to normalise national numbers, without "+"
+}
+
+// GetConfig loads SMS configuration from environment variables.
+// Without SMS_PROVIDER, the webhook is used when SMS_WEBHOOK_URL is set and
+// messages are only logged otherwise.
+func GetConfig() *Config {
+	config := &Config{
+		Provider:           getEnvOrDefault("SMS_PROVIDER", ""),
+		WebhookURL:         getEnvOrDefault("SMS_WEBHOOK_URL", ""),
+		WebhookToken:       getEnvOrDefault("SMS_WEBHOOK_TOKEN", ""),
+		From:               getEnvOrDefault("SMS_FROM", ""),
+		DefaultCountryCode: getEnvOrDefault("SMS_DEFAULT_COUNTRY_CODE", "372"),
+	}
+
+	if config.Provider == "" {
+		config.Provider = ProviderLog
+		if config.WebhookURL != "" {
+			config.Provider = ProviderWebhook
+		}
+	}
+
+	return config
+}
+
+// NewProvider creates the provider selected by config.Provider
+func NewProvider(config *Config) (Provider, error) {
+	switch config.Provider {
+	case ProviderWebhook:
+		if config.WebhookURL == "" {
+			return nil, fmt.Errorf("SMS_WEBHOOK_URL is required for the webhook provider")
+		}
+		return NewWebhookProvider(config.WebhookURL, config.WebhookToken, config.From), nil
+	case ProviderLog:
+		return LogProvider{}, nil
+	case ProviderMemory:
+		return NewMemoryProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", config.Provider)
+	}
+}
+
+// WebhookProvider posts each message as JSON to an HTTP endpoint, which
+// forwards it to an SMS gateway
+type WebhookProvider struct {
+	url    string
+	token  string
+	from   string
+	client *http.Client
+}
+
+// NewWebhookProvider creates a provider that posts to url
+func NewWebhookProvider(url, token, from string) *WebhookProvider {
+	return &WebhookProvider{url: url, token: token, from: from, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name returns the provider name
+func (p *WebhookProvider) Name() string {
+	return ProviderWebhook
+}
+
+// webhookPayload is the JSON body sent to the webhook
+type webhookPayload struct {
+	To   string `json:"to"`
+	From string `json:"from,omitempty"`
+	Body string `json:"body"`
+}
+
+// Send posts the message and fails unless the webhook answers with a 2xx status
+func (p *WebhookProvider) Send(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(webhookPayload{To: to, From: p.from, Body: body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call SMS webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS webhook returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// Message is a text message recorded by MemoryProvider
+type Message struct {
+	To   string
+	Body string
+}
+
+// MemoryProvider records messages instead of sending them; intended for tests
+type MemoryProvider struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryProvider creates an empty in-memory recorder
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{}
+}
+
+// Name returns the provider name
+func (p *MemoryProvider) Name() string {
+	return ProviderMemory
+}
+
+// Send records the message
+func (p *MemoryProvider) Send(ctx context.Context, to, body string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, Message{To: to, Body: body})
+	return nil
+}
+
+// Messages returns the recorded messages in send order
+func (p *MemoryProvider) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+// LogProvider writes each message to the log; the fallback when no provider is configured
+type LogProvider struct{}
+
+// Name returns the provider name
+func (LogProvider) Name() string {
+	return ProviderLog
+}
+
+// Send logs the recipient and message
+func (LogProvider) Send(ctx context.Context, to, body string) error {
+	log.Printf("=== SMS NOTIFICATION ===")
+	log.Printf("To: %s", to)
+	log.Printf("Body: %s", body)
+	log.Printf("=== END SMS ===")
+	return nil
+}
+
+// getEnvOrDefault gets environment variable or returns default value
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
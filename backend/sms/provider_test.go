@@ -0,0 +1,74 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"massage-booking/backend/models"
+)
+
+func TestWebhookProviderPostsMessage(t *testing.T) {
+	var got webhookPayload
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p := NewWebhookProvider(srv.URL, "secret", "Salon")
+	if err := p.Send(context.Background(), "+37251234567", "Hello"); err != nil {
+		t.Fatal(err)
+	}
+	if got.To != "+37251234567" || got.From != "Salon" || got.Body != "Hello" || auth != "Bearer secret" {
+		t.Errorf("unexpected request %+v with auth %q", got, auth)
+	}
+}
+
+func TestWebhookProviderReportsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid number", http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	err := NewWebhookProvider(srv.URL, "", "").Send(context.Background(), "+37251234567", "Hello")
+	if err == nil || !strings.Contains(err.Error(), "422") || !strings.Contains(err.Error(), "invalid number") {
+		t.Errorf("expected webhook error, got %v", err)
+	}
+}
+
+func TestNewProviderSelection(t *testing.T) {
+	for name, want := range map[string]string{ProviderLog: ProviderLog, ProviderMemory: ProviderMemory} {
+		p, err := NewProvider(&Config{Provider: name})
+		if err != nil || p.Name() != want {
+			t.Errorf("NewProvider(%s) = %v, %v", name, p, err)
+		}
+	}
+	if _, err := NewProvider(&Config{Provider: ProviderWebhook}); err == nil {
+		t.Error("expected error for webhook provider without URL")
+	}
+	if _, err := NewProvider(&Config{Provider: "pigeon"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestSenderWritesReminder(t *testing.T) {
+	provider := NewMemoryProvider()
+	booking := &models.BookingDetail{Reference: "BK-20250310-001", ServiceName: "Swedish Massage", Date: "2025-03-10", TimeSlot: "10:00"}
+
+	if err := NewSender(provider, "Serenity").SendReminderSMS(context.Background(), booking, "+37251234567"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := provider.Messages()
+	want := "Serenity: reminder of your Swedish Massage on Mon 10 Mar at 10:00. Ref BK-20250310-001"
+	if len(msgs) != 1 || msgs[0].To != "+37251234567" || msgs[0].Body != want {
+		t.Errorf("unexpected messages %+v", msgs)
+	}
+}
@@ -0,0 +1,36 @@
+package sms
+
+import (
+	"context"
+
+	"massage-booking/backend/i18n"
+	"massage-booking/backend/models"
+)
+
+// Sender writes booking notifications as text messages and delivers them through a Provider
+type Sender struct {
+	provider  Provider
+	salonName string
+}
+
+// NewSender creates a sender that signs messages with salonName
+func NewSender(provider Provider, salonName string) *Sender {
+	return &Sender{provider: provider, salonName: salonName}
+}
+
+// SendConfirmationSMS sends a booking confirmation to the E.164 number to, in the booking's locale
+func (s *Sender) SendConfirmationSMS(ctx context.Context, booking *models.BookingDetail, to string) error {
+	return s.provider.Send(ctx, to, s.message("sms.confirmation", booking))
+}
+
+// SendReminderSMS sends an appointment reminder to the E.164 number to, in the booking's locale
+func (s *Sender) SendReminderSMS(ctx context.Context, booking *models.BookingDetail, to string) error {
+	return s.provider.Send(ctx, to, s.message("sms.reminder", booking))
+}
+
+// message formats a catalog message with the booking details
+func (s *Sender) message(key string, booking *models.BookingDetail) string {
+	locale := i18n.Parse(booking.Locale)
+	return locale.T(key, s.salonName, booking.ServiceName,
+		locale.FormatShortDateString(booking.Date), booking.TimeSlot, booking.Reference)
+}
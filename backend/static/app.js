@@ -39,6 +39,7 @@ const confirmBookingBtn = document.getElementById('confirm-booking-btn');
 const clientNameInput = document.getElementById('client-name');
 const clientEmailInput = document.getElementById('client-email');
 const clientPhoneInput = document.getElementById('client-phone');
+const smsOptInInput = document.getElementById('sms-opt-in');
 const nameError = document.getElementById('name-error');
 const emailError = document.getElementById('email-error');
 const phoneError = document.getElementById('phone-error');
@@ -48,6 +49,11 @@ document.addEventListener('DOMContentLoaded', function() {
     loadMassageTypes();
     setupEventListeners();
     setupFormValidation();
+
+    // The payment provider sends clients back here when they cancel checkout
+    if (new URLSearchParams(window.location.search).get('payment') === 'cancelled') {
+        showError('Payment was cancelled, so your booking was not confirmed. Please choose a time and book again.');
+    }
 });
 
 // Setup event listeners
@@ -115,7 +121,7 @@ function renderServices() {
             <h3>${service.name}</h3>
             <div class="service-details">
                 <span class="duration">${service.duration} minutes</span>
-                <span class="price">€${service.price}</span>
+                <span class="price">${formatMoney(service.price)}</span>
             </div>
         `;
         
@@ -141,7 +147,7 @@ function selectService(service) {
     // Update selected service info
     selectedServiceInfo.innerHTML = `
         <strong>Selected Service:</strong> ${service.name} 
-        (${service.duration} minutes, €${service.price})
+        (${service.duration} minutes, ${formatMoney(service.price)})
     `;
     
     // Hide time slots and summary
@@ -312,7 +318,7 @@ function updateSelectionSummary() {
     document.getElementById('summary-date').textContent = formattedDate;
     document.getElementById('summary-time').textContent = selectedTime.time;
     document.getElementById('summary-duration').textContent = selectedService.duration;
-    document.getElementById('summary-price').textContent = selectedService.price;
+    document.getElementById('summary-price').textContent = formatMoney(selectedService.price);
 }
 
 // Utility functions
@@ -342,7 +348,7 @@ function bookAppointment() {
     }
 
     // For now, just show a success message
-    alert(`Appointment booked successfully!\n\nService: ${selectedService.name}\nDate: ${selectedDate}\nTime: ${selectedTime.time}\nPrice: €${selectedService.price}`);
+    alert(`Appointment booked successfully!\n\nService: ${selectedService.name}\nDate: ${selectedDate}\nTime: ${selectedTime.time}\nPrice: ${formatMoney(selectedService.price)}`);
 
     // Reset the form
     resetBooking();
@@ -439,7 +445,7 @@ function updateBookingSummary() {
     document.getElementById('booking-summary-service').textContent = selectedService.name;
     document.getElementById('booking-summary-date').textContent = formattedDate;
     document.getElementById('booking-summary-time').textContent = selectedTime.time;
-    document.getElementById('booking-summary-price').textContent = selectedService.price;
+    document.getElementById('booking-summary-price').textContent = formatMoney(selectedService.price);
 }
 
 // Start reservation countdown timer
@@ -514,7 +520,7 @@ function validateName() {
     } else if (name.length < 2) {
         isValid = false;
         errorMessage = 'Name must be at least 2 characters';
-    } else if (!/^[a-zA-Z\s]+$/.test(name)) {
+    } else if (!/^[\p{L}\p{M}\s]+$/u.test(name)) {
         isValid = false;
         errorMessage = 'Name should contain only letters and spaces';
     }
@@ -607,7 +613,9 @@ async function handleFormSubmit(event) {
             phone: clientPhoneInput.value.trim(),
             service_id: selectedService.id,
             date: selectedDate,
-            time_slot: selectedTime.time
+            time_slot: selectedTime.time,
+            sms_opt_in: smsOptInInput.checked,
+            locale: navigator.language
         };
 
         const response = await fetch('/api/bookings', {
@@ -630,8 +638,9 @@ async function handleFormSubmit(event) {
             clearInterval(reservationTimer);
         }
 
-        // Redirect to confirmation page with booking ID
-        window.location.href = `/confirmation.html?id=${booking.id}`;
+        // Send the client to pay when the booking awaits online payment,
+        // otherwise straight to the confirmation page
+        window.location.href = booking.checkout_url || `/confirmation.html?id=${booking.id}`;
 
         // Note: No need to reset state as we're navigating away
 
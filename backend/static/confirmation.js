@@ -4,6 +4,14 @@
 const loadingSection = document.getElementById('loading-section');
 const errorSection = document.getElementById('error-section');
 const successSection = document.getElementById('success-section');
+const pendingSection = document.getElementById('pending-section');
+const pendingNote = document.getElementById('pending-note');
+const cancelledSection = document.getElementById('cancelled-section');
+
+// How often and how many times to check a booking that is awaiting payment
+const paymentPollInterval = 3000;
+const paymentPollAttempts = 40;
+let paymentPolls = 0;
 
 // Booking detail elements
 const bookingReference = document.getElementById('booking-reference');
@@ -45,7 +53,7 @@ async function loadBookingDetails() {
         }
 
         const booking = await response.json();
-        displayBookingDetails(booking);
+        showBooking(booking);
         
     } catch (error) {
         console.error('Error loading booking details:', error);
@@ -53,6 +61,31 @@ async function loadBookingDetails() {
     }
 }
 
+// Show the booking according to its status. After an online payment the
+// provider may send the client back before it has told us the outcome, so a
+// booking awaiting payment is checked again until it is confirmed or released.
+function showBooking(booking) {
+    loadingSection.style.display = 'none';
+    pendingSection.style.display = 'none';
+
+    switch (booking.status) {
+        case 'pending_payment':
+            pendingSection.style.display = 'block';
+            if (paymentPolls < paymentPollAttempts) {
+                paymentPolls++;
+                setTimeout(loadBookingDetails, paymentPollInterval);
+            } else {
+                pendingNote.textContent = 'Your payment is still being processed. We will email you as soon as the booking is confirmed.';
+            }
+            break;
+        case 'cancelled':
+            cancelledSection.style.display = 'block';
+            break;
+        default:
+            displayBookingDetails(booking);
+    }
+}
+
 // Display booking details in the UI
 function displayBookingDetails(booking) {
     // Hide loading, show success
@@ -63,7 +96,7 @@ function displayBookingDetails(booking) {
     bookingReference.textContent = booking.reference;
     serviceName.textContent = booking.service_name;
     serviceDuration.textContent = `${booking.duration} minutes`;
-    servicePrice.textContent = `€${booking.price.toFixed(2)}`;
+    servicePrice.textContent = formatMoney(booking.total);
     
     // Format and display date
     const formattedDate = formatDate(booking.date);
@@ -81,6 +114,7 @@ function displayBookingDetails(booking) {
 // Show error state
 function showError() {
     loadingSection.style.display = 'none';
+    pendingSection.style.display = 'none';
     errorSection.style.display = 'block';
 }
 
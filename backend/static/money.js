@@ -0,0 +1,12 @@
+// Money formatting shared by the booking pages
+
+// Format a money value from the API, an amount in minor units with its
+// currency, e.g. {amount: 5000, currency: "EUR"} becomes "€50.00"
+function formatMoney(money) {
+    const format = new Intl.NumberFormat(navigator.language, {
+        style: 'currency',
+        currency: money.currency
+    });
+    const digits = format.resolvedOptions().maximumFractionDigits;
+    return format.format(money.amount / Math.pow(10, digits));
+}
@@ -1,6 +1,8 @@
 module massage-booking
 
-go 1.23
+go 1.23.0
+
+require modernc.org/sqlite v1.39.0
 
 require (
 	github.com/dustin/go-humanize v1.0.1 // indirect
@@ -13,5 +15,4 @@ require (
 	modernc.org/libc v1.66.3 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.39.0 // indirect
 )